@@ -6,12 +6,19 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"task-management/internal/config"
+	"task-management/internal/events"
 	"task-management/internal/handlers"
+	"task-management/internal/importer"
+	"task-management/internal/jobs"
 	"task-management/internal/middleware"
+	"task-management/internal/notify"
 	"task-management/internal/repository"
+	"task-management/internal/webhooks"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -35,12 +42,88 @@ func main() {
 	userRepo := repository.NewUserRepository(db)
 	taskRepo := repository.NewTaskRepository(db)
 	projectRepo := repository.NewProjectRepository(db)
+	fieldRepo := repository.NewCustomFieldRepository(db)
+	recurrenceRepo := repository.NewRecurrenceRepository(db)
+	jobRepo := repository.NewJobRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	activityRepo := repository.NewActivityRepository(db)
+	gitIntegrationRepo := repository.NewGitIntegrationRepository(db)
+	importRepo := repository.NewImportRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+	sprintRepo := repository.NewSprintRepository(db)
+	milestoneRepo := repository.NewMilestoneRepository(db)
+	workLogRepo := repository.NewWorkLogRepository(db)
+	analyticsRepo := repository.NewAnalyticsRepository(db)
+	watcherRepo := repository.NewWatcherRepository(db)
+	calendarFeedRepo := repository.NewCalendarFeedRepository(db)
+
+	// Domain events; webhooks turn them into queued deliveries
+	bus := events.NewBus()
+	dispatcher := webhooks.NewDispatcher(webhookRepo, watcherRepo)
+	bus.Subscribe(dispatcher.Handle)
+	bus.Subscribe(notify.NewWatcherNotifier(watcherRepo).Handle)
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(userRepo)
-	taskHandler := handlers.NewTaskHandler(taskRepo)
-	projectHandler := handlers.NewProjectHandler(projectRepo, userRepo)
-	adminHandler := handlers.NewAdminHandler(userRepo)
+	taskHandler := handlers.NewTaskHandler(taskRepo, projectRepo, userRepo, fieldRepo, recurrenceRepo, milestoneRepo, bus)
+	fieldHandler := handlers.NewCustomFieldHandler(fieldRepo, projectRepo, userRepo)
+	projectHandler := handlers.NewProjectHandler(projectRepo, userRepo, taskRepo, templateRepo)
+	templateHandler := handlers.NewTemplateHandler(templateRepo, projectRepo, userRepo)
+	sprintHandler := handlers.NewSprintHandler(sprintRepo, taskRepo, fieldRepo, projectRepo, userRepo)
+	milestoneHandler := handlers.NewMilestoneHandler(milestoneRepo, projectRepo, userRepo)
+	workLogHandler := handlers.NewWorkLogHandler(workLogRepo, taskRepo, projectRepo, userRepo)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsRepo, projectRepo, userRepo)
+	watcherHandler := handlers.NewWatcherHandler(watcherRepo, taskRepo, projectRepo, userRepo)
+	calendarHandler := handlers.NewCalendarHandler(calendarFeedRepo, taskRepo, projectRepo, userRepo)
+	adminHandler := handlers.NewAdminHandler(userRepo, projectRepo, jobRepo)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, dispatcher, projectRepo, userRepo)
+	gitHandler := handlers.NewGitHandler(gitIntegrationRepo, taskRepo, activityRepo, recurrenceRepo, bus, projectRepo, userRepo)
+	activityHandler := handlers.NewActivityHandler(activityRepo, taskRepo, projectRepo, userRepo)
+	exportHandler := handlers.NewExportHandler(taskRepo, fieldRepo, projectRepo, userRepo)
+	importHandler := handlers.NewImportHandler(importRepo, fieldRepo, projectRepo, userRepo)
+
+	// Background jobs
+	retentionDays, err := strconv.Atoi(config.GetEnv("TRASH_RETENTION_DAYS", "30"))
+	if err != nil || retentionDays < 1 {
+		log.Fatal("TRASH_RETENTION_DAYS must be a positive number of days")
+	}
+	recurrenceWindowHours, err := strconv.Atoi(config.GetEnv("RECURRENCE_WINDOW_HOURS", "24"))
+	if err != nil || recurrenceWindowHours < 0 {
+		log.Fatal("RECURRENCE_WINDOW_HOURS must be a non-negative number of hours")
+	}
+	jobWorkers, err := strconv.Atoi(config.GetEnv("JOB_WORKERS", "2"))
+	if err != nil || jobWorkers < 1 {
+		log.Fatal("JOB_WORKERS must be a positive number")
+	}
+	digestHour, err := strconv.Atoi(config.GetEnv("DIGEST_HOUR", "8"))
+	if err != nil || digestHour < 0 || digestHour > 23 {
+		log.Fatal("DIGEST_HOUR must be an hour from 0 to 23")
+	}
+	scheduler := jobs.NewScheduler(jobRepo, jobWorkers)
+	periodicJobs := []struct {
+		jobType string
+		spec    string
+		run     jobs.JobFunc
+	}{
+		{"purge-trash", "0 * * * *", jobs.NewPurgeJob(userRepo, projectRepo, taskRepo, time.Duration(retentionDays)*24*time.Hour)},
+		{"recurring-tasks", "*/15 * * * *", jobs.NewRecurrenceJob(recurrenceRepo, time.Duration(recurrenceWindowHours)*time.Hour)},
+		{"rebalance-ranks", "20 * * * *", jobs.NewRankRebalanceJob(taskRepo)},
+		{"due-reminders", "*/15 * * * *", jobs.NewDueSoonJob(notificationRepo, 24*time.Hour)},
+		{"overdue-alerts", "*/15 * * * *", jobs.NewOverdueJob(notificationRepo)},
+		{"daily-digest", "0 * * * *", jobs.NewDigestJob(notificationRepo, scheduler, digestHour)},
+		{"cleanup-jobs", "30 3 * * *", jobs.NewJobCleanupJob(jobRepo, 7*24*time.Hour)},
+		{"cleanup-webhook-deliveries", "45 3 * * *", webhooks.NewCleanupJob(webhookRepo, 30*24*time.Hour)},
+	}
+	for _, job := range periodicJobs {
+		if err := scheduler.Cron(job.jobType, job.spec, job.run); err != nil {
+			log.Fatalf("Invalid schedule for job %s: %v", job.jobType, err)
+		}
+	}
+	scheduler.Handle(jobs.JobTypeSendDigest, jobs.NewSendDigestJob(notificationRepo))
+	scheduler.Handle(webhooks.JobTypeDeliver, webhooks.NewDeliveryJob(webhookRepo))
+	scheduler.Handle(importer.JobTypeImport, importer.NewImportJob(importRepo))
 
 	// Create router
 	r := mux.NewRouter()
@@ -53,34 +136,123 @@ func main() {
 	api.HandleFunc("/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
 	api.HandleFunc("/auth/refresh", authHandler.RefreshToken).Methods("POST", "OPTIONS")
 
+	// Git push hooks are verified with the integration's secret instead of a JWT
+	api.HandleFunc("/hooks/git/{projectId}", gitHandler.ReceivePush).Methods("POST")
+
+	// Calendar apps can't send a JWT, so feeds are opened by the secret token in the URL
+	api.HandleFunc("/calendar/{token:[0-9a-f]+}.ics", calendarHandler.GetFeed).Methods("GET")
+
 	// Protected routes (authentication required)
 	protected := api.PathPrefix("").Subrouter()
 	protected.Use(middleware.AuthMiddleware)
 
 	// Auth routes
 	protected.HandleFunc("/auth/me", authHandler.GetMe).Methods("GET")
+	protected.HandleFunc("/auth/me/timezone", authHandler.UpdateTimezone).Methods("PUT", "OPTIONS")
 
 	// Project routes
 	protected.HandleFunc("/projects", projectHandler.GetProjects).Methods("GET")
 	protected.HandleFunc("/projects", projectHandler.CreateProject).Methods("POST", "OPTIONS")
 	protected.HandleFunc("/projects/{id}", projectHandler.GetProject).Methods("GET")
 	protected.HandleFunc("/projects/{id}", projectHandler.UpdateProject).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/projects/{id}", projectHandler.PatchProject).Methods("PATCH", "OPTIONS")
 	protected.HandleFunc("/projects/{id}", projectHandler.DeleteProject).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/restore", projectHandler.RestoreProject).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/archive", projectHandler.ArchiveProject).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/unarchive", projectHandler.UnarchiveProject).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/trash", projectHandler.GetTrash).Methods("GET", "OPTIONS")
 	protected.HandleFunc("/projects/{id}/members", projectHandler.GetMembers).Methods("GET", "OPTIONS")
 	protected.HandleFunc("/projects/{id}/invite", projectHandler.InviteMember).Methods("POST", "OPTIONS")
 	protected.HandleFunc("/projects/{id}/members/{userId}", projectHandler.UpdateMemberRole).Methods("PUT", "OPTIONS")
 	protected.HandleFunc("/projects/{id}/members/{userId}", projectHandler.RemoveMember).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/transfer-ownership", projectHandler.TransferOwnership).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/leave", projectHandler.LeaveProject).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/clone", templateHandler.CloneProject).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/template", templateHandler.SaveTemplate).Methods("POST", "OPTIONS")
+
+	protected.HandleFunc("/projects/{id}/export", exportHandler.ExportTasks).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/import", importHandler.ImportTasks).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/imports/{importId}", importHandler.GetImport).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/custom-fields", fieldHandler.GetFields).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/custom-fields", fieldHandler.CreateField).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/custom-fields/{fieldId}", fieldHandler.UpdateField).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/custom-fields/{fieldId}", fieldHandler.DeleteField).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/webhooks", webhookHandler.GetWebhooks).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/webhooks", webhookHandler.CreateWebhook).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/webhooks/{webhookId}", webhookHandler.UpdateWebhook).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/webhooks/{webhookId}", webhookHandler.DeleteWebhook).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/webhooks/{webhookId}/deliveries", webhookHandler.GetDeliveries).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/webhooks/{webhookId}/test", webhookHandler.TestWebhook).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/git-integration", gitHandler.GetIntegration).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/git-integration", gitHandler.SaveIntegration).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/git-integration", gitHandler.DeleteIntegration).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints", sprintHandler.GetSprints).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints", sprintHandler.CreateSprint).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints/{sprintId}", sprintHandler.UpdateSprint).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints/{sprintId}", sprintHandler.DeleteSprint).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints/{sprintId}/start", sprintHandler.StartSprint).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints/{sprintId}/close", sprintHandler.CloseSprint).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints/{sprintId}/tasks", sprintHandler.AddTasks).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/sprints/{sprintId}/tasks/{taskId}", sprintHandler.RemoveTask).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/velocity", sprintHandler.GetVelocity).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/milestones", milestoneHandler.GetMilestones).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/milestones", milestoneHandler.CreateMilestone).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/milestones/{milestoneId}", milestoneHandler.GetMilestone).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/milestones/{milestoneId}", milestoneHandler.UpdateMilestone).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/milestones/{milestoneId}", milestoneHandler.DeleteMilestone).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/time-report", workLogHandler.GetProjectTimeReport).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/projects/{id}/analytics", analyticsHandler.GetProjectAnalytics).Methods("GET", "OPTIONS")
+
+	// Project templates
+	protected.HandleFunc("/templates", templateHandler.GetTemplates).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/templates/{templateId}", templateHandler.GetTemplate).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/templates/{templateId}", templateHandler.DeleteTemplate).Methods("DELETE", "OPTIONS")
 
 	// Task routes
 	protected.HandleFunc("/tasks", taskHandler.GetTasks).Methods("GET")
 	protected.HandleFunc("/tasks", taskHandler.CreateTask).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tasks/bulk", taskHandler.BulkTasks).Methods("POST", "OPTIONS")
 	protected.HandleFunc("/tasks/{id}", taskHandler.GetTask).Methods("GET")
 	protected.HandleFunc("/tasks/{id}", taskHandler.UpdateTask).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}", taskHandler.PatchTask).Methods("PATCH", "OPTIONS")
 	protected.HandleFunc("/tasks/{id}", taskHandler.DeleteTask).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/restore", taskHandler.RestoreTask).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/move", taskHandler.MoveTask).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/copy", taskHandler.CopyTask).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/position", taskHandler.PositionTask).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/activity", activityHandler.GetTaskActivity).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/worklogs", workLogHandler.GetWorkLogs).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/worklogs", workLogHandler.CreateWorkLog).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/timer/start", workLogHandler.StartTimer).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/watchers", watcherHandler.GetWatchers).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/watch", watcherHandler.WatchTask).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/tasks/{id}/watch", watcherHandler.UnwatchTask).Methods("DELETE", "OPTIONS")
+
+	// Personal views
+	protected.HandleFunc("/me/tasks", taskHandler.GetMyTasks).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/me/calendar-feeds", calendarHandler.GetFeeds).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/me/calendar-feeds", calendarHandler.CreateFeed).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/me/calendar-feeds/{id}", calendarHandler.DeleteFeed).Methods("DELETE", "OPTIONS")
+
+	// Time tracking
+	protected.HandleFunc("/worklogs/{id}", workLogHandler.UpdateWorkLog).Methods("PUT", "OPTIONS")
+	protected.HandleFunc("/worklogs/{id}", workLogHandler.DeleteWorkLog).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/timer", workLogHandler.GetTimer).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/timer/stop", workLogHandler.StopTimer).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/time-report", workLogHandler.GetTimeReport).Methods("GET", "OPTIONS")
 
 	// Admin routes
 	protected.HandleFunc("/admin/users", adminHandler.GetAllUsers).Methods("GET", "OPTIONS")
 	protected.HandleFunc("/admin/users/{id}", adminHandler.DeleteUser).Methods("DELETE", "OPTIONS")
+	protected.HandleFunc("/admin/users/{id}/restore", adminHandler.RestoreUser).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/admin/trash", adminHandler.GetTrash).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/admin/jobs", adminHandler.GetJobs).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/admin/jobs/{id}/retry", adminHandler.RetryJob).Methods("POST", "OPTIONS")
+
+	// Notification routes
+	protected.HandleFunc("/notifications", notificationHandler.GetNotifications).Methods("GET", "OPTIONS")
+	protected.HandleFunc("/notifications/read-all", notificationHandler.MarkAllRead).Methods("POST", "OPTIONS")
+	protected.HandleFunc("/notifications/{id}/read", notificationHandler.MarkRead).Methods("POST", "OPTIONS")
 
 	// Apply CORS middleware to all routes
 	r.Use(middleware.CORSMiddleware)
@@ -115,6 +287,9 @@ func main() {
 		}
 	}()
 
+	// Start background jobs
+	scheduler.Start()
+
 	// Wait for interrupt signal for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -130,5 +305,9 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	if err := scheduler.Stop(ctx); err != nil {
+		log.Println("Background jobs did not stop in time:", err)
+	}
+
 	log.Println("Server exited")
 }
@@ -0,0 +1,122 @@
+// Package due interprets task due dates.
+//
+// A due date is either a moment (RFC 3339, with a time of day) or, when it
+// is all-day, a calendar day. All-day dates are stored as midnight UTC of
+// their day and mean that day wherever the user is: a task due all day on
+// the 5th is due on the 5th in every timezone, until the end of that day in
+// the user's timezone.
+package due
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// The server image has no zoneinfo, so embed it for user timezones
+	_ "time/tzdata"
+)
+
+// DateLayout is how all-day due dates are written
+const DateLayout = "2006-01-02"
+
+// localLayout is a time of day without an offset, as sent by a
+// datetime-local input. It is read in the user's timezone.
+const localLayout = "2006-01-02T15:04"
+
+// Parse reads a due date: YYYY-MM-DD for an all-day date, or RFC 3339 for a
+// moment. A date and time without an offset is in loc. allDay forces the
+// result to be the calendar day of a moment in loc.
+func Parse(value string, allDay bool, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(DateLayout, value); err == nil {
+		return t, true, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.ParseInLocation(localLayout, value, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid due date %q. Use YYYY-MM-DD or RFC 3339", value)
+		}
+	}
+	if allDay {
+		return Date(t.In(loc)), true, nil
+	}
+	return t.UTC(), false, nil
+}
+
+// SetAllDay turns a due date into an all-day one, or back into a moment,
+// keeping its day in loc: a moment becomes its day, and an all-day date the
+// last minute of its day
+func SetAllDay(t time.Time, allDay, toAllDay bool, loc *time.Location) time.Time {
+	switch {
+	case toAllDay && !allDay:
+		return Date(t.In(loc))
+	case !toAllDay && allDay:
+		t = t.UTC()
+		return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 0, 0, loc).UTC()
+	}
+	return t
+}
+
+// Date is the all-day due date of t's calendar day, in t's location
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Day is the calendar day (YYYY-MM-DD) a due date falls on in loc
+func Day(t time.Time, allDay bool, loc *time.Location) string {
+	if allDay {
+		return t.UTC().Format(DateLayout)
+	}
+	return t.In(loc).Format(DateLayout)
+}
+
+// End is the moment a due date passes in loc: the end of the day for
+// all-day dates
+func End(t time.Time, allDay bool, loc *time.Location) time.Time {
+	if allDay {
+		t = t.UTC()
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return t
+}
+
+// Format writes a due date back as Parse reads it
+func Format(t time.Time, allDay bool) string {
+	if allDay {
+		return t.UTC().Format(DateLayout)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Display shows a due date to a user in loc: its day, and its time of day
+// unless it is all-day
+func Display(t time.Time, allDay bool, loc *time.Location) string {
+	if allDay {
+		return t.UTC().Format(DateLayout)
+	}
+	return t.In(loc).Format("2006-01-02 15:04")
+}
+
+// Location loads a user's timezone. Unknown or empty names are UTC.
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidTimezone reports whether name is an IANA timezone such as
+// "Asia/Bangkok"
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
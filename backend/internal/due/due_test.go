@@ -0,0 +1,143 @@
+package due
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	tests := []struct {
+		name       string
+		value      string
+		allDay     bool
+		want       time.Time
+		wantAllDay bool
+	}{
+		{"date only", "2026-03-08", false, utc("2026-03-08T00:00:00Z"), true},
+		{"date only with spaces", " 2026-03-08 ", true, utc("2026-03-08T00:00:00Z"), true},
+		{"rfc 3339", "2026-03-08T01:30:00-05:00", false, utc("2026-03-08T06:30:00Z"), false},
+		{"rfc 3339 in utc", "2026-03-08T12:00:00Z", false, utc("2026-03-08T12:00:00Z"), false},
+		// 03:30 UTC is still the evening of the 7th in New York
+		{"all day takes the local day", "2026-03-08T03:30:00Z", true, utc("2026-03-07T00:00:00Z"), true},
+		// 23:00 at UTC-5 is already midnight of the 9th in daylight time
+		{"all day uses loc, not the offset", "2026-03-08T23:00:00-05:00", true, utc("2026-03-09T00:00:00Z"), true},
+		// Noon on the 8th is after the switch to daylight time (UTC-4)
+		{"local time", "2026-03-08T12:00", false, utc("2026-03-08T16:00:00Z"), false},
+		{"local time before the switch", "2026-03-07T12:00", false, utc("2026-03-07T17:00:00Z"), false},
+		{"local time all day", "2026-11-01T23:30", true, utc("2026-11-01T00:00:00Z"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, allDay, err := Parse(tt.value, tt.allDay, newYork)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) || allDay != tt.wantAllDay {
+				t.Errorf("Parse(%q) = %v, %v; want %v, %v", tt.value, got, allDay, tt.want, tt.wantAllDay)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "tomorrow", "2026-13-01", "08/03/2026", "2026-03-08 12:00"} {
+		if _, _, err := Parse(value, false, newYork); err == nil {
+			t.Errorf("Parse(%q) accepted an invalid date", value)
+		}
+	}
+}
+
+func TestSetAllDay(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	// Daylight time starts on 2026-03-08 and ends on 2026-11-01 in New York
+	tests := []struct {
+		name             string
+		value            time.Time
+		allDay, toAllDay bool
+		want             time.Time
+	}{
+		{"moment to its local day", utc("2026-03-08T04:30:00Z"), false, true, utc("2026-03-07T00:00:00Z")},
+		{"moment after the spring switch", utc("2026-03-08T07:30:00Z"), false, true, utc("2026-03-08T00:00:00Z")},
+		{"moment in the repeated hour", utc("2026-11-01T05:30:00Z"), false, true, utc("2026-11-01T00:00:00Z")},
+		{"moment after the autumn switch", utc("2026-11-02T04:30:00Z"), false, true, utc("2026-11-01T00:00:00Z")},
+		{"day before the spring switch", utc("2026-03-07T00:00:00Z"), true, false, utc("2026-03-08T04:59:00Z")},
+		{"day of the spring switch", utc("2026-03-08T00:00:00Z"), true, false, utc("2026-03-09T03:59:00Z")},
+		{"day of the autumn switch", utc("2026-11-01T00:00:00Z"), true, false, utc("2026-11-02T04:59:00Z")},
+		{"already all day", utc("2026-03-08T00:00:00Z"), true, true, utc("2026-03-08T00:00:00Z")},
+		{"already a moment", utc("2026-03-08T04:30:00Z"), false, false, utc("2026-03-08T04:30:00Z")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SetAllDay(tt.value, tt.allDay, tt.toAllDay, newYork)
+			if !got.Equal(tt.want) {
+				t.Errorf("SetAllDay(%v, %v, %v) = %v, want %v", tt.value, tt.allDay, tt.toAllDay, got.UTC(), tt.want)
+			}
+			// Switching back keeps the day
+			if tt.allDay != tt.toAllDay {
+				back := SetAllDay(got, tt.toAllDay, tt.allDay, newYork)
+				if Day(back, tt.allDay, newYork) != Day(tt.value, tt.allDay, newYork) {
+					t.Errorf("switching back moved the day from %s to %s",
+						Day(tt.value, tt.allDay, newYork), Day(back, tt.allDay, newYork))
+				}
+			}
+		})
+	}
+}
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  time.Time
+		allDay bool
+		zone   string
+		want   time.Time
+	}{
+		{"all day in utc", utc("2026-03-07T00:00:00Z"), true, "UTC", utc("2026-03-08T00:00:00Z")},
+		{"all day behind utc", utc("2026-03-07T00:00:00Z"), true, "America/New_York", utc("2026-03-08T05:00:00Z")},
+		// Midnight after the spring switch is already daylight time
+		{"all day on the spring switch", utc("2026-03-08T00:00:00Z"), true, "America/New_York", utc("2026-03-09T04:00:00Z")},
+		{"all day ahead of utc", utc("2026-12-31T00:00:00Z"), true, "Asia/Tokyo", utc("2026-12-31T15:00:00Z")},
+		{"all day far ahead of utc", utc("2026-10-31T00:00:00Z"), true, "Pacific/Kiritimati", utc("2026-10-31T10:00:00Z")},
+		{"all day at a month end", utc("2026-02-28T00:00:00Z"), true, "Asia/Kolkata", utc("2026-02-28T18:30:00Z")},
+		{"moment", utc("2026-03-08T23:30:00Z"), false, "Asia/Tokyo", utc("2026-03-08T23:30:00Z")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := End(tt.value, tt.allDay, mustLoad(t, tt.zone)); !got.Equal(tt.want) {
+				t.Errorf("End(%v) in %s = %v, want %v", tt.value, tt.zone, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidTimezone(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Asia/Bangkok":     true,
+		"America/New_York": true,
+		"UTC":              true,
+		"":                 false,
+		"Local":            false,
+		"Mars/Olympus":     false,
+		"+07:00":           false,
+	} {
+		if got := ValidTimezone(name); got != want {
+			t.Errorf("ValidTimezone(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
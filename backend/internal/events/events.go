@@ -0,0 +1,81 @@
+// Package events carries domain events from the handlers to subscribers
+// such as outgoing webhooks.
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TaskCreated  = "task.created"
+	TaskUpdated  = "task.updated"
+	TaskDeleted  = "task.deleted"
+	TaskRestored = "task.restored"
+	// TaskMoved is sent to both the old and the new project of a task
+	TaskMoved = "task.moved"
+	// TaskBulkUpdated carries all tasks of one project changed by a bulk operation
+	TaskBulkUpdated = "task.bulk_updated"
+	// Ping is only sent by the webhook test endpoint
+	Ping = "ping"
+)
+
+// Types lists the event types subscribers can choose from
+var Types = []string{TaskCreated, TaskUpdated, TaskDeleted, TaskRestored, TaskMoved, TaskBulkUpdated}
+
+// Event is something that happened in a project
+type Event struct {
+	Type       string      `json:"event"`
+	ProjectID  string      `json:"project_id"`
+	ActorID    string      `json:"actor_id,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Handler receives published events. It runs on the publisher's goroutine,
+// so it should only do quick work such as queueing a job.
+type Handler func(Event)
+
+// Bus delivers every published event to all subscribers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for all events
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish hands the event to every subscriber. A nil Bus drops events.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					log.Printf("[EVENTS] Handler panicked on %s: %v", event.Type, p)
+				}
+			}()
+			handler(event)
+		}()
+	}
+}
@@ -0,0 +1,192 @@
+// Package gitref reads GitHub and GitLab push payloads and finds the tasks
+// referenced in their commit messages.
+package gitref
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultKeywords maps closing keywords to the status they set
+var DefaultKeywords = map[string]string{
+	"fix": "done", "fixes": "done", "fixed": "done",
+	"close": "done", "closes": "done", "closed": "done",
+	"resolve": "done", "resolves": "done", "resolved": "done",
+}
+
+// Commit is a pushed commit
+type Commit struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	URL     string `json:"url"`
+	Author  struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"author"`
+}
+
+// Push is the part of a push event shared by GitHub and GitLab
+type Push struct {
+	Ref        string   `json:"ref"`
+	Repository string   `json:"-"`
+	Commits    []Commit `json:"commits"`
+}
+
+// pushPayload decodes both formats: GitHub names the repository
+// "repository.full_name", GitLab "project.path_with_namespace"
+type pushPayload struct {
+	Push
+	GitHubRepository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	GitLabProject struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+}
+
+// Provider identifies the sender of a hook request
+type Provider string
+
+const (
+	GitHub Provider = "github"
+	GitLab Provider = "gitlab"
+)
+
+// Detect returns the provider of a request and whether it is a push event.
+// ok is false for requests from neither provider.
+func Detect(r *http.Request) (provider Provider, isPush bool, ok bool) {
+	if event := r.Header.Get("X-GitHub-Event"); event != "" {
+		return GitHub, event == "push", true
+	}
+	if event := r.Header.Get("X-Gitlab-Event"); event != "" {
+		return GitLab, event == "Push Hook", true
+	}
+	return "", false, false
+}
+
+// Verify checks the request's shared secret: GitHub signs the body with
+// HMAC-SHA256 in X-Hub-Signature-256, GitLab sends the secret in X-Gitlab-Token
+func Verify(provider Provider, r *http.Request, body []byte, secret string) bool {
+	switch provider {
+	case GitHub:
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Hub-Signature-256")))
+	case GitLab:
+		return subtle.ConstantTimeCompare([]byte(secret), []byte(r.Header.Get("X-Gitlab-Token"))) == 1
+	}
+	return false
+}
+
+// ParsePush decodes a push payload
+func ParsePush(body []byte) (*Push, error) {
+	var payload pushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid push payload: %w", err)
+	}
+	push := payload.Push
+	push.Repository = payload.GitHubRepository.FullName
+	if push.Repository == "" {
+		push.Repository = payload.GitLabProject.PathWithNamespace
+	}
+	return &push, nil
+}
+
+// Reference is a task mentioned in a commit message
+type Reference struct {
+	// Target is a lower-case task UUID or an upper-case task key such as "WEB-42"
+	Target string
+	// Status is set when a keyword precedes the reference, e.g. "fixes WEB-42"
+	Status string
+}
+
+const (
+	uuidPattern = `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`
+	keyPattern  = `[A-Za-z][A-Za-z0-9]{1,9}-[0-9]+`
+	refPattern  = `#?(` + uuidPattern + `|` + keyPattern + `)\b`
+)
+
+var refRegexp = regexp.MustCompile(`\b` + refPattern)
+
+// Parser finds task references using a set of keywords
+type Parser struct {
+	keywords map[string]string
+	keyword  *regexp.Regexp
+}
+
+// NewParser builds a parser for keywords, which map a word such as "fixes"
+// to the status it sets. Matching is case-insensitive.
+func NewParser(keywords map[string]string) *Parser {
+	p := &Parser{keywords: map[string]string{}}
+	words := make([]string, 0, len(keywords))
+	for word, status := range keywords {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		p.keywords[word] = status
+		words = append(words, regexp.QuoteMeta(word))
+	}
+	if len(words) > 0 {
+		// Longest first so "fixes" wins over "fix"
+		sort.Slice(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+		// A keyword governs a list of references: "fixes WEB-1, WEB-2 and WEB-3"
+		list := refPattern + `(?:\s*(?:,|and|&)\s*` + refPattern + `)*`
+		p.keyword = regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `):?\s+(` + list + `)`)
+	}
+	return p
+}
+
+// Parse returns the tasks referenced in message. A task referenced after a
+// keyword gets that keyword's status; any other mention has no status.
+func (p *Parser) Parse(message string) []Reference {
+	statuses := map[string]string{}
+	var order []string
+	add := func(target, status string) {
+		if IsUUID(target) {
+			target = strings.ToLower(target)
+		} else {
+			target = strings.ToUpper(target)
+		}
+		if _, seen := statuses[target]; !seen {
+			order = append(order, target)
+			statuses[target] = status
+		} else if status != "" {
+			statuses[target] = status
+		}
+	}
+
+	if p.keyword != nil {
+		for _, match := range p.keyword.FindAllStringSubmatch(message, -1) {
+			status := p.keywords[strings.ToLower(match[1])]
+			for _, ref := range refRegexp.FindAllStringSubmatch(match[2], -1) {
+				add(ref[1], status)
+			}
+		}
+	}
+	for _, ref := range refRegexp.FindAllStringSubmatch(message, -1) {
+		add(ref[1], "")
+	}
+
+	refs := make([]Reference, 0, len(order))
+	for _, target := range order {
+		refs = append(refs, Reference{Target: target, Status: statuses[target]})
+	}
+	return refs
+}
+
+// IsUUID reports whether a reference target is a task UUID rather than a key
+func IsUUID(target string) bool {
+	return uuidRegexp.MatchString(target)
+}
+
+var uuidRegexp = regexp.MustCompile(`^` + uuidPattern + `$`)
@@ -0,0 +1,207 @@
+package gitref
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestParsePush(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		ref        string
+		repository string
+		commits    []string // commit IDs
+		author     string   // of the first commit
+	}{
+		{"github_push.json", "refs/heads/main", "acme/web",
+			[]string{"0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c", "1481a2de7b2a7d922a56d7c6ee8a4ee3c9a3fd0b"}, "Dana Lee"},
+		{"gitlab_push.json", "refs/heads/develop", "platform/api",
+			[]string{"b6568db1bc1dcd7f8b4d5a946b0b91f9dacd7327"}, "Sam Park"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			push, err := ParsePush(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ParsePush: %v", err)
+			}
+			if push.Ref != tt.ref || push.Repository != tt.repository {
+				t.Errorf("got ref %q, repository %q; want %q, %q", push.Ref, push.Repository, tt.ref, tt.repository)
+			}
+			var ids []string
+			for _, commit := range push.Commits {
+				ids = append(ids, commit.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.commits) {
+				t.Errorf("got commits %v, want %v", ids, tt.commits)
+			}
+			if len(push.Commits) > 0 {
+				commit := push.Commits[0]
+				if commit.Author.Name != tt.author || commit.Message == "" || commit.URL == "" {
+					t.Errorf("first commit decoded as %+v", commit)
+				}
+			}
+		})
+	}
+
+	if _, err := ParsePush([]byte(`{"commits": "not a list"}`)); err == nil {
+		t.Error("ParsePush accepted an invalid payload")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		header, value string
+		provider      Provider
+		isPush, ok    bool
+	}{
+		{"X-GitHub-Event", "push", GitHub, true, true},
+		{"X-GitHub-Event", "ping", GitHub, false, true},
+		{"X-Gitlab-Event", "Push Hook", GitLab, true, true},
+		{"X-Gitlab-Event", "Merge Request Hook", GitLab, false, true},
+		{"X-Other-Event", "push", "", false, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set(tt.header, tt.value)
+		provider, isPush, ok := Detect(r)
+		if provider != tt.provider || isPush != tt.isPush || ok != tt.ok {
+			t.Errorf("Detect(%s: %s) = %q, %v, %v", tt.header, tt.value, provider, isPush, ok)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "It's a Secret to Everybody"
+	body := readFixture(t, "github_push.json")
+	// HMAC-SHA256 of the body with the secret, as GitHub sends it
+	signature := "sha256=" + hexHMAC(secret, body)
+
+	tests := []struct {
+		name     string
+		provider Provider
+		header   string
+		value    string
+		body     []byte
+		want     bool
+	}{
+		{"github signature", GitHub, "X-Hub-Signature-256", signature, body, true},
+		{"github wrong secret", GitHub, "X-Hub-Signature-256", "sha256=" + hexHMAC("wrong", body), body, false},
+		{"github changed body", GitHub, "X-Hub-Signature-256", signature, append([]byte(" "), body...), false},
+		{"github sha1 signature", GitHub, "X-Hub-Signature", signature, body, false},
+		{"github without prefix", GitHub, "X-Hub-Signature-256", strings.TrimPrefix(signature, "sha256="), body, false},
+		{"github missing signature", GitHub, "", "", body, false},
+		{"gitlab token", GitLab, "X-Gitlab-Token", secret, body, true},
+		{"gitlab wrong token", GitLab, "X-Gitlab-Token", "It's a secret to everybody", body, false},
+		{"gitlab missing token", GitLab, "", "", body, false},
+		{"unknown provider", Provider("bitbucket"), "X-Gitlab-Token", secret, body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			if got := Verify(tt.provider, r, tt.body, secret); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	parser := NewParser(DefaultKeywords)
+	const id = "3f2b8c1e-9a4d-4e6f-8b1a-2c3d4e5f6a7b"
+
+	tests := []struct {
+		name    string
+		message string
+		want    []Reference
+	}{
+		{"plain mention", "Work on WEB-42", []Reference{{"WEB-42", ""}}},
+		{"keyword", "Fixes WEB-42: keep the login form", []Reference{{"WEB-42", "done"}}},
+		{"keyword is case-insensitive", "CLOSES web-42", []Reference{{"WEB-42", "done"}}},
+		{"longer keyword wins", "fixed WEB-1", []Reference{{"WEB-1", "done"}}},
+		{"keyword list", "resolves WEB-1, WEB-2 and #WEB-3 & WEB-4", []Reference{
+			{"WEB-1", "done"}, {"WEB-2", "done"}, {"WEB-3", "done"}, {"WEB-4", "done"}}},
+		{"keyword after a mention", "WEB-5 started, fixes WEB-5", []Reference{{"WEB-5", "done"}}},
+		{"mention before keyword keeps order", "See WEB-7; closes WEB-8", []Reference{{"WEB-8", "done"}, {"WEB-7", ""}}},
+		{"duplicate mentions", "WEB-9 WEB-9 web-9", []Reference{{"WEB-9", ""}}},
+		{"uuid is lower-cased", "Fixes #" + strings.ToUpper(id), []Reference{{id, "done"}}},
+		{"uuid mention", "touches " + id, []Reference{{id, ""}}},
+		{"keyword needs a reference", "fix the typo in WEB-1 docs", []Reference{{"WEB-1", ""}}},
+		{"no references", "Update README", []Reference{}},
+		{"single letter is not a key", "A-1 and B2", []Reference{}},
+		{"key run into a word", "WEB-1x and WEB-12ab", []Reference{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.Parse(tt.message); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCustomKeywords(t *testing.T) {
+	parser := NewParser(map[string]string{"Refs": "in_progress", " ": "done"})
+	want := []Reference{{"API-9", "in_progress"}, {"API-10", ""}}
+	if got := parser.Parse("refs: API-9, fixes API-10"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %v, want %v", got, want)
+	}
+
+	// Without keywords references are still found
+	want = []Reference{{"API-9", ""}}
+	if got := NewParser(nil).Parse("fixes API-9"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse without keywords = %v, want %v", got, want)
+	}
+}
+
+func TestParseFixtureCommits(t *testing.T) {
+	push, err := ParsePush(readFixture(t, "gitlab_push.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Reference{
+		{"3f2b8c1e-9a4d-4e6f-8b1a-2c3d4e5f6a7b", "done"},
+		{"API-9", "done"},
+		{"API-10", "done"},
+	}
+	if got := NewParser(DefaultKeywords).Parse(push.Commits[0].Message); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse = %v, want %v", got, want)
+	}
+}
+
+func TestIsUUID(t *testing.T) {
+	for target, want := range map[string]bool{
+		"3f2b8c1e-9a4d-4e6f-8b1a-2c3d4e5f6a7b":  true,
+		"3F2B8C1E-9A4D-4E6F-8B1A-2C3D4E5F6A7B":  true,
+		"3f2b8c1e-9a4d-4e6f-8b1a-2c3d4e5f6a7":   false,
+		"#3f2b8c1e-9a4d-4e6f-8b1a-2c3d4e5f6a7b": false,
+		"WEB-42":                                false,
+	} {
+		if got := IsUUID(target); got != want {
+			t.Errorf("IsUUID(%q) = %v, want %v", target, got, want)
+		}
+	}
+}
+
+func hexHMAC(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
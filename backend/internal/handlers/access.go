@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-management/internal/due"
+	"task-management/internal/repository"
+)
+
+// projectAccess holds the membership checks shared by every handler that
+// works with project-scoped resources
+type projectAccess struct {
+	projectRepo *repository.ProjectRepository
+	userRepo    *repository.UserRepository
+}
+
+// isSystemAdmin reports whether the user has the system admin role
+func (a *projectAccess) isSystemAdmin(userID string) bool {
+	user, err := a.userRepo.GetUserByID(userID)
+	return err == nil && user.SystemRole == "admin"
+}
+
+func (a *projectAccess) hasProjectAccess(userID, projectID string) bool {
+	// Check if system admin
+	if a.isSystemAdmin(userID) {
+		return true
+	}
+
+	// Check if member of project
+	_, err := a.projectRepo.GetMemberRole(projectID, userID)
+	return err == nil
+}
+
+func (a *projectAccess) hasProjectRole(userID, projectID string, allowedRoles []string) bool {
+	// Check if system admin
+	if a.isSystemAdmin(userID) {
+		return true
+	}
+
+	// Check user's role in project
+	role, err := a.projectRepo.GetMemberRole(projectID, userID)
+	if err != nil {
+		return false
+	}
+
+	for _, allowedRole := range allowedRoles {
+		if role == allowedRole {
+			return true
+		}
+	}
+	return false
+}
+
+// userLocation is the user's timezone, which due dates are seen in. It is
+// UTC if the user can't be read.
+func (a *projectAccess) userLocation(userID string) *time.Location {
+	user, err := a.userRepo.GetUserByID(userID)
+	if err != nil {
+		return time.UTC
+	}
+	return due.Location(user.Timezone)
+}
+
+// ensureWritable rejects changes to an archived project, which is read-only
+// until it is unarchived. It writes an error response and returns false if
+// the project is archived or can't be checked. A missing project is left to
+// the caller, which reports it as missing.
+func (a *projectAccess) ensureWritable(w http.ResponseWriter, projectID string) bool {
+	archived, err := a.projectRepo.IsArchived(projectID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return true
+		}
+		log.Printf("Error checking whether project %s is archived: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to check project")
+		return false
+	}
+	if archived {
+		respondWithError(w, http.StatusConflict, "Project is archived and read-only")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"task-management/internal/middleware"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type ActivityHandler struct {
+	projectAccess
+	taskRepo     *repository.TaskRepository
+	activityRepo *repository.ActivityRepository
+}
+
+func NewActivityHandler(activityRepo *repository.ActivityRepository, taskRepo *repository.TaskRepository,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *ActivityHandler {
+	return &ActivityHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		taskRepo:     taskRepo,
+		activityRepo: activityRepo,
+	}
+}
+
+// GetTaskActivity returns the activity log of a task, e.g. linked commits
+func (h *ActivityHandler) GetTaskActivity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	taskID, ok := resolveTaskRef(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			respondWithError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		log.Printf("Error getting task %s: %v", taskID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get task")
+		return
+	}
+
+	if task.UserID != userID && !h.hasProjectAccess(userID, task.ProjectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	entries, err := h.activityRepo.GetActivity(taskID)
+	if err != nil {
+		log.Printf("Error getting activity of task %s: %v", taskID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get activity")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, entries)
+}
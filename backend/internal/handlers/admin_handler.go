@@ -1,19 +1,32 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	"task-management/internal/middleware"
+	"task-management/internal/models"
 	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
 )
 
 type AdminHandler struct {
-	userRepo *repository.UserRepository
+	userRepo    *repository.UserRepository
+	projectRepo *repository.ProjectRepository
+	jobRepo     *repository.JobRepository
 }
 
-func NewAdminHandler(userRepo *repository.UserRepository) *AdminHandler {
-	return &AdminHandler{userRepo: userRepo}
+func NewAdminHandler(userRepo *repository.UserRepository, projectRepo *repository.ProjectRepository, jobRepo *repository.JobRepository) *AdminHandler {
+	return &AdminHandler{
+		userRepo:    userRepo,
+		projectRepo: projectRepo,
+		jobRepo:     jobRepo,
+	}
 }
 
 // GetAllUsers returns all users (admin only)
@@ -103,14 +116,230 @@ func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optional body controls reassignment; an empty body uses the defaults
+	var req models.DeleteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Assignments != "" && req.Assignments != "clear" && req.Assignments != "reassign" {
+		respondWithError(w, http.StatusBadRequest, "Invalid assignments. Must be clear or reassign")
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
 	// Delete user
-	err = h.userRepo.DeleteUser(userIDToDelete)
+	preview, err := h.userRepo.DeleteUser(userIDToDelete, req, dryRun)
 	if err != nil {
 		log.Printf("[ADMIN] Error deleting user %s: %v", userIDToDelete, err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to delete user")
+		switch {
+		case errors.Is(err, repository.ErrSuccessorRequired):
+			respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+				"error":   "User is the last PO of a project. Provide successor_id or successors",
+				"preview": preview,
+			})
+		case err.Error() == "user not found":
+			respondWithError(w, http.StatusNotFound, "User not found")
+		case strings.HasPrefix(err.Error(), "invalid"):
+			respondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete user")
+		}
+		return
+	}
+
+	if dryRun {
+		respondWithJSON(w, http.StatusOK, preview)
+		return
+	}
+
+	log.Printf("[ADMIN] User %s successfully deleted by admin %s (%d task(s) reassigned, %d assignment(s) updated)",
+		userIDToDelete, adminID, preview.CreatedTasks, preview.AssignedTasks)
+	respondWithJSON(w, http.StatusOK, preview)
+}
+
+// GetTrash lists soft-deleted users and projects (admin only)
+func (h *AdminHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		log.Printf("[ADMIN] Method not allowed: %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get admin user ID from context
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		log.Printf("[ADMIN] Unauthorized access attempt - no user ID in context")
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Verify admin status
+	adminUser, err := h.userRepo.GetUserByID(userID)
+	if err != nil {
+		log.Printf("[ADMIN] Error getting admin user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify admin status")
+		return
+	}
+
+	if adminUser.SystemRole != "admin" {
+		log.Printf("[ADMIN] Trash access denied for user %s (role: %s)", userID, adminUser.SystemRole)
+		respondWithError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	users, err := h.userRepo.GetDeletedUsers()
+	if err != nil {
+		log.Printf("[ADMIN] Error getting deleted users: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get trash")
+		return
+	}
+
+	projects, err := h.projectRepo.GetDeletedProjects()
+	if err != nil {
+		log.Printf("[ADMIN] Error getting deleted projects: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get trash")
+		return
+	}
+
+	if users == nil {
+		users = []*models.User{}
+	}
+	if projects == nil {
+		projects = []*models.Project{}
+	}
+
+	respondWithJSON(w, http.StatusOK, models.TrashResponse{Users: users, Projects: projects})
+}
+
+// RestoreUser restores a soft-deleted user (admin only). Memberships and
+// reassigned work stay as DeleteUser left them; the response says so.
+func (h *AdminHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		log.Printf("[ADMIN] Method not allowed: %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get admin user ID from context
+	adminID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || adminID == "" {
+		log.Printf("[ADMIN] Unauthorized restore attempt - no user ID in context")
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Verify admin status
+	adminUser, err := h.userRepo.GetUserByID(adminID)
+	if err != nil {
+		log.Printf("[ADMIN] Error getting admin user %s: %v", adminID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify admin status")
+		return
+	}
+
+	if adminUser.SystemRole != "admin" {
+		log.Printf("[ADMIN] Restore access denied for user %s (role: %s)", adminID, adminUser.SystemRole)
+		respondWithError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	userIDToRestore := mux.Vars(r)["id"]
+	if userIDToRestore == "" {
+		respondWithError(w, http.StatusBadRequest, "User ID is required")
+		return
+	}
+
+	if err := h.userRepo.RestoreUser(userIDToRestore); err != nil {
+		log.Printf("[ADMIN] Error restoring user %s: %v", userIDToRestore, err)
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "User not found in trash")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to restore user")
+		return
+	}
+
+	user, err := h.userRepo.GetUserByID(userIDToRestore)
+	if err != nil {
+		log.Printf("[ADMIN] Error getting restored user %s: %v", userIDToRestore, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get user")
+		return
+	}
+
+	log.Printf("[ADMIN] User %s restored by admin %s", userIDToRestore, adminID)
+	respondWithJSON(w, http.StatusOK, models.RestoredUser{
+		User:    user,
+		Warning: "Project memberships and reassigned tasks are not restored. Add the user to their projects again.",
+	})
+}
+
+// requireAdmin returns the caller's user ID, or writes an error response and
+// returns false if the caller is not a system admin
+func (h *AdminHandler) requireAdmin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	adminID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || adminID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return "", false
+	}
+
+	adminUser, err := h.userRepo.GetUserByID(adminID)
+	if err != nil {
+		log.Printf("[ADMIN] Error getting admin user %s: %v", adminID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify admin status")
+		return "", false
+	}
+
+	if adminUser.SystemRole != "admin" {
+		log.Printf("[ADMIN] Access denied for user %s (role: %s)", adminID, adminUser.SystemRole)
+		respondWithError(w, http.StatusForbidden, "Admin access required")
+		return "", false
+	}
+	return adminID, true
+}
+
+// GetJobs lists background jobs, e.g. ?status=dead for the dead letter queue (admin only)
+func (h *AdminHandler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.requireAdmin(w, r); !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.JobPending, models.JobRunning, models.JobDone, models.JobDead:
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid status. Must be pending, running, done or dead")
+		return
+	}
+
+	jobs, err := h.jobRepo.GetJobs(status, 200)
+	if err != nil {
+		log.Printf("[ADMIN] Error getting jobs: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get jobs")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, jobs)
+}
+
+// RetryJob queues a dead job again with a fresh set of attempts (admin only)
+func (h *AdminHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := h.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	jobID := mux.Vars(r)["id"]
+	if err := h.jobRepo.Retry(jobID); err != nil {
+		log.Printf("[ADMIN] Error retrying job %s: %v", jobID, err)
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Dead job not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to retry job")
 		return
 	}
 
-	log.Printf("[ADMIN] User %s successfully deleted by admin %s", userIDToDelete, adminID)
-	w.WriteHeader(http.StatusNoContent)
+	log.Printf("[ADMIN] Job %s retried by admin %s", jobID, adminID)
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Job queued for retry"})
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// analyticsCacheTTL is how long computed analytics are reused
+	analyticsCacheTTL = time.Minute
+	// analyticsDefaultDays is the range used when none is given
+	analyticsDefaultDays = 30
+	// analyticsMaxDays bounds the range, as every day is a data point
+	analyticsMaxDays = 366
+)
+
+type AnalyticsHandler struct {
+	projectAccess
+	analyticsRepo *repository.AnalyticsRepository
+	cache         *analyticsCache
+}
+
+func NewAnalyticsHandler(analyticsRepo *repository.AnalyticsRepository, projectRepo *repository.ProjectRepository,
+	userRepo *repository.UserRepository) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		analyticsRepo: analyticsRepo,
+		cache:         &analyticsCache{entries: map[string]analyticsEntry{}},
+	}
+}
+
+// GetProjectAnalytics returns task counts, daily throughput, burndown,
+// cumulative flow and lead and cycle time of a project (?from=&to= dates,
+// the last 30 days by default). Results are cached for a minute, so they can
+// lag behind the board slightly.
+func (h *AnalyticsHandler) GetProjectAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if _, err := h.projectRepo.GetProjectByID(projectID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	from, to, msg := parseAnalyticsRange(r)
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	key := projectID + "|" + from.Format("2006-01-02") + "|" + to.Format("2006-01-02")
+	analytics := h.cache.get(key)
+	if analytics == nil {
+		var err error
+		analytics, err = h.analyticsRepo.ProjectAnalytics(projectID, from, to)
+		if err != nil {
+			log.Printf("Error computing analytics of project %s: %v", projectID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to compute analytics")
+			return
+		}
+		h.cache.put(key, analytics)
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	respondWithJSON(w, http.StatusOK, analytics)
+}
+
+// parseAnalyticsRange reads ?from= and ?to=. A missing end is today and a
+// missing start is 30 days before the end. It returns a message describing
+// the first problem, if any.
+func parseAnalyticsRange(r *http.Request) (time.Time, time.Time, string) {
+	query := r.URL.Query()
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := strings.TrimSpace(query.Get("to")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "Invalid to date. Use YYYY-MM-DD"
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -(analyticsDefaultDays - 1))
+	if value := strings.TrimSpace(query.Get("from")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, "Invalid from date. Use YYYY-MM-DD"
+		}
+		from = parsed
+	}
+
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, "to must not be before from"
+	}
+	if to.Sub(from) >= analyticsMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, "The range must not exceed 366 days"
+	}
+	return from, to, ""
+}
+
+// analyticsCache keeps computed analytics for analyticsCacheTTL
+type analyticsCache struct {
+	mu      sync.Mutex
+	entries map[string]analyticsEntry
+}
+
+type analyticsEntry struct {
+	analytics *models.ProjectAnalytics
+	expires   time.Time
+}
+
+// get returns cached analytics, or nil if there are none or they expired
+func (c *analyticsCache) get(key string) *models.ProjectAnalytics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil
+	}
+	return entry.analytics
+}
+
+// put caches analytics and drops expired entries, so ranges that are asked
+// for once don't pile up
+func (c *analyticsCache) put(key string, analytics *models.ProjectAnalytics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = analyticsEntry{analytics: analytics, expires: now.Add(analyticsCacheTTL)}
+}
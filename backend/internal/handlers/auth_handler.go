@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"task-management/internal/due"
 	"task-management/internal/middleware"
 	"task-management/internal/models"
 	"task-management/internal/repository"
@@ -125,6 +126,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Email, password, and name are required")
 		return
 	}
+	if req.Timezone != "" && !h.checkTimezone(w, req.Timezone) {
+		return
+	}
 
 	// Check if user already exists
 	_, err := h.userRepo.GetUserByEmail(req.Email)
@@ -135,8 +139,9 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	// Create user
 	user := &models.User{
-		Email: req.Email,
-		Name:  req.Name,
+		Email:    req.Email,
+		Name:     req.Name,
+		Timezone: req.Timezone,
 	}
 
 	if err := h.userRepo.CreateUser(user, req.Password); err != nil {
@@ -283,6 +288,64 @@ func (h *AuthHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, user)
 }
 
+// checkTimezone writes an error response and returns false unless both Go
+// and the database know the timezone name
+func (h *AuthHandler) checkTimezone(w http.ResponseWriter, name string) bool {
+	if !due.ValidTimezone(name) {
+		respondWithError(w, http.StatusBadRequest, "Invalid timezone. Use an IANA name such as Asia/Bangkok")
+		return false
+	}
+	known, err := h.userRepo.KnownTimezone(name)
+	if err != nil {
+		log.Printf("Error checking timezone %q: %v", name, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to check timezone")
+		return false
+	}
+	if !known {
+		respondWithError(w, http.StatusBadRequest, "Invalid timezone. Use an IANA name such as Asia/Bangkok")
+		return false
+	}
+	return true
+}
+
+// UpdateTimezone sets the current user's timezone, which due dates, overdue
+// tasks and reminders are worked out in
+func (h *AuthHandler) UpdateTimezone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.UpdateTimezoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Timezone = strings.TrimSpace(req.Timezone)
+	if !h.checkTimezone(w, req.Timezone) {
+		return
+	}
+
+	if err := h.userRepo.UpdateTimezone(userID, req.Timezone); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		log.Printf("Error updating timezone of user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update timezone")
+		return
+	}
+
+	user, err := h.userRepo.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Error getting user by ID %s: %v", userID, err)
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, user)
+}
+
 // RefreshToken handles refresh token request
 func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -0,0 +1,369 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-management/internal/events"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// maxBulkTasks bounds the number of tasks of one bulk operation
+const maxBulkTasks = 500
+
+// BulkTasks applies one operation to many tasks (POST /api/tasks/bulk).
+// Every task is checked on its own with the rule of the single-task
+// endpoints (see canChangeTask). The allowed tasks are changed in one
+// transaction, which locks them and checks them again, and each affected
+// project gets a single batched event.
+func (h *TaskHandler) BulkTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.BulkTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.TaskIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "task_ids is required")
+		return
+	}
+	if len(req.TaskIDs) > maxBulkTasks {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d tasks can be changed at once", maxBulkTasks))
+		return
+	}
+
+	req.Value = strings.TrimSpace(req.Value)
+	if msg := h.validateBulkValue(userID, &req); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	response := models.BulkTaskResponse{Operation: req.Operation, Results: []models.BulkTaskResult{}}
+	// allowed holds the index in Results and the current state of each task
+	// that passed its checks
+	type allowedTask struct {
+		index int
+		task  *models.Task
+	}
+	var allowed []allowedTask
+	seen := map[string]bool{}
+	moves := map[string]repository.TaskMove{}
+
+	var target *bulkMoveTarget
+	if req.Operation == models.BulkMove {
+		var err error
+		if target, err = h.bulkMoveTarget(req.Value); err != nil {
+			log.Printf("Error preparing bulk move to project %s: %v", req.Value, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get custom fields")
+			return
+		}
+	}
+
+	for _, ref := range req.TaskIDs {
+		result := models.BulkTaskResult{TaskID: ref}
+		task, err := h.bulkTask(ref)
+		switch {
+		case err != nil:
+			log.Printf("Error getting task %s for bulk %s: %v", ref, req.Operation, err)
+			result.Status = models.BulkResultFailed
+			result.Error = "Failed to get task"
+		case task == nil:
+			result.Status = models.BulkResultNotFound
+			result.Error = "Task not found"
+		case seen[task.ID]:
+			continue
+		case !h.canChangeTask(userID, task, req.Operation == models.BulkMove):
+			result.TaskID = task.ID
+			result.Status = models.BulkResultForbidden
+			result.Error = "Access denied"
+		default:
+			result.TaskID = task.ID
+			if msg := h.checkBulkItem(task, &req); msg != "" {
+				result.Status = models.BulkResultFailed
+				result.Error = msg
+				break
+			}
+			if target != nil {
+				move, err := target.move(userID, task)
+				if err != nil {
+					result.Status = models.BulkResultFailed
+					result.Error = err.Error()
+					break
+				}
+				moves[task.ID] = move
+			}
+			allowed = append(allowed, allowedTask{index: len(response.Results), task: task})
+		}
+		if task != nil {
+			seen[task.ID] = true
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	ids := make([]string, 0, len(allowed))
+	for _, item := range allowed {
+		ids = append(ids, item.task.ID)
+	}
+
+	if len(ids) > 0 {
+		// The tasks may have changed since they were checked above; the
+		// repository checks them again once they are locked. The checks
+		// above still hold for a task with the same creator and project, so
+		// that and the project's archived flag, read under the lock, are all
+		// the recheck needs; it runs without further queries.
+		checked := make(map[string]*models.Task, len(allowed))
+		for _, item := range allowed {
+			checked[item.task.ID] = item.task
+		}
+		recheck := func(task *models.Task, archived bool) bool {
+			before := checked[task.ID]
+			return before != nil && !archived && task.UserID == before.UserID && task.ProjectID == before.ProjectID
+		}
+		updated, err := h.taskRepo.BulkUpdateTasks(ids, req.Operation, req.Value, moves, recheck)
+		if err != nil {
+			log.Printf("Error applying bulk %s to %d task(s) for user %s: %v", req.Operation, len(ids), userID, err)
+			for _, item := range allowed {
+				response.Results[item.index].Status = models.BulkResultFailed
+				response.Results[item.index].Error = "Failed to update task; no tasks were changed"
+			}
+			allowed = nil
+		}
+
+		var kept []allowedTask
+		for _, item := range allowed {
+			if containsString(updated, item.task.ID) {
+				kept = append(kept, item)
+				continue
+			}
+			response.Results[item.index].Status = models.BulkResultFailed
+			response.Results[item.index].Error = "Task was changed by another request; try again"
+		}
+		allowed = kept
+	}
+
+	// Group the changed tasks by project for the batched events
+	changed := map[string][]*models.Task{}
+	previous := map[string][]*models.Task{}
+	var projectIDs []string
+	addChange := func(projectID string, task, before *models.Task) {
+		if _, ok := changed[projectID]; !ok {
+			projectIDs = append(projectIDs, projectID)
+		}
+		changed[projectID] = append(changed[projectID], task)
+		previous[projectID] = append(previous[projectID], before)
+	}
+
+	for _, item := range allowed {
+		result := &response.Results[item.index]
+		result.Status = models.BulkResultOK
+
+		task := item.task
+		if req.Operation != models.BulkDelete {
+			updated, err := h.taskRepo.GetTaskByID(task.ID)
+			if err != nil {
+				log.Printf("Error getting task %s after bulk %s: %v", task.ID, req.Operation, err)
+			} else {
+				task = updated
+			}
+		}
+		result.Task = task
+
+		// Completing an occurrence schedules the next one
+		before := item.task
+		if req.Operation == models.BulkSetStatus && before.RecurrenceID != nil && before.Status != "done" && req.Value == "done" {
+			if _, err := h.recurrenceRepo.EnsureOccurrence(*before.RecurrenceID, *before.RecurrenceIndex+1); err != nil {
+				log.Printf("Error creating next occurrence after task %s: %v", before.ID, err)
+			}
+		}
+
+		addChange(before.ProjectID, task, before)
+		if req.Operation == models.BulkMove {
+			addChange(req.Value, task, before)
+		}
+	}
+
+	for _, result := range response.Results {
+		if result.Status == models.BulkResultOK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+
+	for _, projectID := range projectIDs {
+		h.events.Publish(events.Event{
+			Type:      events.TaskBulkUpdated,
+			ProjectID: projectID,
+			ActorID:   userID,
+			Data: map[string]interface{}{
+				"operation": req.Operation,
+				"value":     req.Value,
+				"tasks":     changed[projectID],
+				"previous":  previous[projectID],
+			},
+		})
+	}
+
+	log.Printf("[TASK] User %s applied bulk %s: %d succeeded, %d failed", userID, req.Operation, response.Succeeded, response.Failed)
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// validateBulkValue checks the operation and the value it takes, returning
+// an error message for the client or "" if they are valid
+func (h *TaskHandler) validateBulkValue(userID string, req *models.BulkTaskRequest) string {
+	switch req.Operation {
+	case models.BulkSetStatus:
+		if !models.IsValidStatus(req.Value) {
+			return "Invalid status. Must be todo, in-progress or done"
+		}
+	case models.BulkSetPriority:
+		if !models.IsValidPriority(req.Value) {
+			return "Invalid priority. Must be low, medium or high"
+		}
+	case models.BulkAddLabel, models.BulkRemoveLabel:
+		if req.Value == "" {
+			return "value must be a label"
+		}
+	case models.BulkMove:
+		if req.Value == "" {
+			return "value must be the target project ID"
+		}
+		if _, err := h.projectRepo.GetProjectByID(req.Value); err != nil {
+			return "Target project not found"
+		}
+		if !h.hasProjectRole(userID, req.Value, []string{models.RolePO, models.RolePM, models.RoleMember}) {
+			return "You cannot add tasks to the target project"
+		}
+		if archived, err := h.projectRepo.IsArchived(req.Value); err == nil && archived {
+			return "Target project is archived and read-only"
+		}
+	case models.BulkSetAssignee, models.BulkDelete:
+	default:
+		return "Invalid operation. Must be set_status, set_priority, set_assignee, move, add_label, remove_label or delete"
+	}
+	return ""
+}
+
+// checkBulkItem checks the operation against one task, returning an error
+// message for its result or "" if the task can be changed
+func (h *TaskHandler) checkBulkItem(task *models.Task, req *models.BulkTaskRequest) string {
+	if archived, err := h.projectRepo.IsArchived(task.ProjectID); err == nil && archived {
+		return "Project is archived and read-only"
+	}
+
+	switch req.Operation {
+	case models.BulkSetAssignee:
+		if req.Value != "" && !h.memberChecker(task.ProjectID)(req.Value) {
+			return "Assignee is not a member of the task's project"
+		}
+	case models.BulkMove:
+		if task.ProjectID == req.Value {
+			return "Task is already in the target project"
+		}
+	}
+	return ""
+}
+
+// bulkMoveTarget is the target project of a bulk move with what moving a
+// task there needs
+type bulkMoveTarget struct {
+	project  *models.Project
+	fields   []*models.CustomField
+	isMember func(userID string) bool
+	// sourceFields caches the custom fields of the tasks' projects
+	sourceFields map[string][]*models.CustomField
+	fieldRepo    *repository.CustomFieldRepository
+}
+
+func (h *TaskHandler) bulkMoveTarget(projectID string) (*bulkMoveTarget, error) {
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := h.fieldRepo.GetFieldsByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &bulkMoveTarget{
+		project:      project,
+		fields:       fields,
+		isMember:     h.memberChecker(projectID),
+		sourceFields: map[string][]*models.CustomField{},
+		fieldRepo:    h.fieldRepo,
+	}, nil
+}
+
+// move works out the move of one task, as MoveTask does without a request
+// body: the assignee stays if they are a member of the target project and
+// custom field values are carried over by name. It fails if the target
+// requires a field the task has no value for.
+func (t *bulkMoveTarget) move(userID string, task *models.Task) (repository.TaskMove, error) {
+	sourceFields, ok := t.sourceFields[task.ProjectID]
+	if !ok {
+		var err error
+		sourceFields, err = t.fieldRepo.GetFieldsByProjectID(task.ProjectID)
+		if err != nil {
+			log.Printf("Error getting custom fields for project %s: %v", task.ProjectID, err)
+			return repository.TaskMove{}, fmt.Errorf("Failed to get custom fields")
+		}
+		t.sourceFields[task.ProjectID] = sourceFields
+	}
+	return bulkTaskMove(userID, task, t.project, sourceFields, t.fields, t.isMember)
+}
+
+// bulkTaskMove is the transfer and activity note of one task of a bulk move
+func bulkTaskMove(userID string, task *models.Task, target *models.Project, sourceFields, targetFields []*models.CustomField,
+	isMember func(userID string) bool) (repository.TaskMove, error) {
+	customFields, err := transferCustomFields(sourceFields, targetFields, task.CustomFields, nil, isMember)
+	if err != nil {
+		return repository.TaskMove{}, err
+	}
+	return repository.TaskMove{
+		To:   repository.TaskTransfer{ProjectID: target.ID, CustomFields: customFields},
+		Note: moveNote(userID, task, target),
+	}, nil
+}
+
+// bulkTask looks up a task of a bulk operation by UUID or key. It returns
+// nil without an error if there is no such task.
+func (h *TaskHandler) bulkTask(ref string) (*models.Task, error) {
+	taskID := ref
+	if _, _, isKey := models.ParseTaskKey(ref); isKey {
+		id, err := h.taskRepo.GetTaskIDByKey(ref)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return nil, nil
+			}
+			return nil, err
+		}
+		taskID = id
+	} else if _, err := uuid.Parse(ref); err != nil {
+		return nil, nil
+	}
+
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return task, nil
+}
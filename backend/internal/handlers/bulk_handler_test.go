@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"task-management/internal/models"
+)
+
+func TestBulkTaskMoveCarriesCustomFields(t *testing.T) {
+	sourceFields := []*models.CustomField{
+		{ID: "s-points", Name: "Points", Type: models.FieldTypeNumber},
+		{ID: "s-team", Name: "Team", Type: models.FieldTypeSingleSelect, Options: []string{"web", "api"}},
+		{ID: "s-owner", Name: "Owner", Type: models.FieldTypeUser},
+		{ID: "s-notes", Name: "Notes", Type: models.FieldTypeText},
+	}
+	targetFields := []*models.CustomField{
+		{ID: "t-points", Name: "points", Type: models.FieldTypeNumber},
+		// Only "web" is an option in the target
+		{ID: "t-team", Name: "Team", Type: models.FieldTypeSingleSelect, Options: []string{"web", "mobile"}},
+		{ID: "t-owner", Name: "Owner", Type: models.FieldTypeUser},
+		// Same name, other type: not carried
+		{ID: "t-notes", Name: "Notes", Type: models.FieldTypeNumber},
+	}
+	target := &models.Project{ID: "target", Name: "Mobile"}
+	isMember := func(userID string) bool { return userID == "member" }
+
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			"matching fields carried by name",
+			map[string]interface{}{"s-points": 5.0, "s-team": "web", "s-owner": "member", "s-notes": "x"},
+			map[string]interface{}{"t-points": 5.0, "t-team": "web", "t-owner": "member"},
+		},
+		{
+			"values the target doesn't accept are dropped",
+			map[string]interface{}{"s-points": 3.0, "s-team": "api", "s-owner": "outsider"},
+			map[string]interface{}{"t-points": 3.0},
+		},
+		{"no values", nil, map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &models.Task{ID: "task", ProjectID: "source", Key: "WEB-7", CustomFields: tt.values}
+			move, err := bulkTaskMove("user", task, target, sourceFields, targetFields, isMember)
+			if err != nil {
+				t.Fatalf("bulkTaskMove: %v", err)
+			}
+			if move.To.ProjectID != "target" {
+				t.Errorf("moved to %q, want target", move.To.ProjectID)
+			}
+			if move.To.AssignedTo != nil {
+				t.Errorf("assignee set to %q; it should be left to the member check", *move.To.AssignedTo)
+			}
+			if !reflect.DeepEqual(move.To.CustomFields, tt.want) {
+				t.Errorf("custom fields = %v, want %v", move.To.CustomFields, tt.want)
+			}
+			if move.Note == nil || move.Note.Type != models.ActivityMoved || move.Note.Message != "Moved from WEB-7 to Mobile" {
+				t.Errorf("note = %+v, want a moved note", move.Note)
+			}
+		})
+	}
+}
+
+func TestBulkTaskMoveRequiredField(t *testing.T) {
+	sourceFields := []*models.CustomField{{ID: "s-points", Name: "Points", Type: models.FieldTypeNumber}}
+	targetFields := []*models.CustomField{{ID: "t-points", Name: "Points", Type: models.FieldTypeNumber, Required: true}}
+	target := &models.Project{ID: "target", Name: "Mobile"}
+	isMember := func(string) bool { return false }
+
+	task := &models.Task{ID: "task", ProjectID: "source", CustomFields: map[string]interface{}{"s-points": 8.0}}
+	if _, err := bulkTaskMove("user", task, target, sourceFields, targetFields, isMember); err != nil {
+		t.Errorf("required field with a carried value: %v", err)
+	}
+
+	task.CustomFields = map[string]interface{}{}
+	if _, err := bulkTaskMove("user", task, target, sourceFields, targetFields, isMember); err == nil {
+		t.Error("moved a task without a value for a field the target requires")
+	}
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"task-management/internal/ical"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// calendarProductID identifies this application in generated calendars
+const calendarProductID = "-//Task Management//Task Due Dates//EN"
+
+type CalendarHandler struct {
+	projectAccess
+	feedRepo *repository.CalendarFeedRepository
+	taskRepo *repository.TaskRepository
+}
+
+func NewCalendarHandler(feedRepo *repository.CalendarFeedRepository, taskRepo *repository.TaskRepository,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *CalendarHandler {
+	return &CalendarHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		feedRepo: feedRepo,
+		taskRepo: taskRepo,
+	}
+}
+
+// GetFeeds lists the current user's calendar feeds. Tokens are only shown
+// when a feed is created.
+func (h *CalendarHandler) GetFeeds(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	feeds, err := h.feedRepo.GetFeeds(userID)
+	if err != nil {
+		log.Printf("Error getting calendar feeds of user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get calendar feeds")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, feeds)
+}
+
+// CreateFeed creates a secret feed URL of the current user's assigned tasks
+// or, with project_id, of a project they are a member of. Creating a feed
+// that exists gives it a new URL and revokes the old one.
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// The body is optional
+	var req models.CreateCalendarFeedRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	feed := &models.CalendarFeed{UserID: userID}
+	if req.ProjectID != nil && strings.TrimSpace(*req.ProjectID) != "" {
+		projectID := strings.TrimSpace(*req.ProjectID)
+		if _, err := uuid.Parse(projectID); err != nil {
+			respondWithError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		project, err := h.projectRepo.GetProjectByID(projectID)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		if !h.hasProjectAccess(userID, projectID) {
+			respondWithError(w, http.StatusForbidden, "Access denied")
+			return
+		}
+		feed.ProjectID = &project.ID
+		feed.ProjectName = &project.Name
+	}
+
+	token, err := newFeedToken()
+	if err != nil {
+		log.Printf("Error generating calendar feed token: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create calendar feed")
+		return
+	}
+	if err := h.feedRepo.CreateFeed(feed, hashFeedToken(token)); err != nil {
+		log.Printf("Error creating calendar feed for user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create calendar feed")
+		return
+	}
+
+	feed.Token = token
+	feed.URL = feedURL(r, token)
+	log.Printf("[CALENDAR] User %s created calendar feed %s", userID, feed.ID)
+	respondWithJSON(w, http.StatusCreated, feed)
+}
+
+// DeleteFeed revokes one of the current user's feeds
+func (h *CalendarHandler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	feedID := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(feedID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Calendar feed not found")
+		return
+	}
+	if err := h.feedRepo.DeleteFeed(userID, feedID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Calendar feed not found")
+			return
+		}
+		log.Printf("Error deleting calendar feed %s: %v", feedID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete calendar feed")
+		return
+	}
+
+	log.Printf("[CALENDAR] User %s revoked calendar feed %s", userID, feedID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFeed serves a feed as iCalendar (GET /api/calendar/{token}.ics). The
+// token in the URL is the only credential, as calendar apps can't log in.
+// Tasks are events at their due time, or all-day events on their due date;
+// ?component=vtodo lists them as to-dos instead, for apps that show those.
+// A project feed stops working when its user leaves the project.
+func (h *CalendarHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	feed, err := h.feedRepo.GetFeedByTokenHash(hashFeedToken(mux.Vars(r)["token"]))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Calendar not found", http.StatusNotFound)
+		} else {
+			log.Printf("Error getting calendar feed: %v", err)
+			http.Error(w, "Failed to get calendar", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	component := strings.ToUpper(r.URL.Query().Get("component"))
+	if component == "" {
+		component = "VEVENT"
+	}
+	if component != "VEVENT" && component != "VTODO" {
+		http.Error(w, "Invalid component. Use vevent or vtodo", http.StatusBadRequest)
+		return
+	}
+
+	filter := repository.TaskFilter{Dated: true, Sort: "due_date"}
+	var tasks []*models.Task
+	name := "My tasks"
+	if feed.ProjectID != nil {
+		if !h.hasProjectAccess(feed.UserID, *feed.ProjectID) {
+			http.Error(w, "Calendar not found", http.StatusNotFound)
+			return
+		}
+		name = *feed.ProjectName
+		tasks, err = h.taskRepo.GetTasksByProjectID(*feed.ProjectID, filter)
+	} else {
+		tasks, err = h.taskRepo.GetTasksForUser(feed.UserID, repository.RelationAssigned, filter)
+	}
+	if err != nil {
+		log.Printf("Error getting tasks for calendar feed %s: %v", feed.ID, err)
+		http.Error(w, "Failed to get calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="tasks.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeCalendar(w, name, component, tasks); err != nil {
+		log.Printf("Error writing calendar feed %s: %v", feed.ID, err)
+	}
+}
+
+// writeCalendar writes tasks as an iCalendar object. Each task keeps its
+// UID, and its version is the SEQUENCE, so calendar apps update entries in
+// place when tasks change.
+func writeCalendar(w http.ResponseWriter, name, component string, tasks []*models.Task) error {
+	cal := ical.NewWriter(w)
+	cal.Begin("VCALENDAR")
+	cal.Line("VERSION", "2.0")
+	cal.Line("PRODID", calendarProductID)
+	cal.Line("CALSCALE", "GREGORIAN")
+	cal.Line("METHOD", "PUBLISH")
+	cal.Text("X-WR-CALNAME", name)
+
+	for _, task := range tasks {
+		modified := task.CreatedAt
+		if task.UpdatedAt != nil {
+			modified = *task.UpdatedAt
+		}
+
+		cal.Begin(component)
+		cal.Line("UID", task.ID+"@task-management")
+		cal.Line("DTSTAMP", ical.DateTime(modified))
+		cal.Line("CREATED", ical.DateTime(task.CreatedAt))
+		cal.Line("LAST-MODIFIED", ical.DateTime(modified))
+		cal.Line("SEQUENCE", strconv.Itoa(task.Version))
+		cal.Text("SUMMARY", fmt.Sprintf("[%s] %s", task.Key, task.Title))
+		if task.Description != "" {
+			cal.Text("DESCRIPTION", task.Description)
+		}
+		if len(task.Labels) > 0 {
+			escaped := make([]string, len(task.Labels))
+			for i, label := range task.Labels {
+				escaped[i] = ical.EscapeText(label)
+			}
+			cal.Line("CATEGORIES", strings.Join(escaped, ","))
+		}
+		cal.Line("PRIORITY", calendarPriority(task.Priority))
+
+		if component == "VTODO" {
+			if task.DueAllDay {
+				cal.Line("DUE;VALUE=DATE", ical.Date(*task.DueDate))
+			} else {
+				cal.Line("DUE", ical.DateTime(*task.DueDate))
+			}
+			switch task.Status {
+			case models.StatusDone:
+				cal.Line("STATUS", "COMPLETED")
+			case models.StatusInProgress:
+				cal.Line("STATUS", "IN-PROCESS")
+			default:
+				cal.Line("STATUS", "NEEDS-ACTION")
+			}
+		} else {
+			if task.DueAllDay {
+				// An all-day event ends on the following day (RFC 5545 3.6.1)
+				cal.Line("DTSTART;VALUE=DATE", ical.Date(*task.DueDate))
+				cal.Line("DTEND;VALUE=DATE", ical.Date(task.DueDate.AddDate(0, 0, 1)))
+			} else {
+				// A deadline is an instant: without DTEND the event ends as it starts
+				cal.Line("DTSTART", ical.DateTime(*task.DueDate))
+			}
+			cal.Line("TRANSP", "TRANSPARENT")
+		}
+		cal.End(component)
+	}
+
+	cal.End("VCALENDAR")
+	return cal.Flush()
+}
+
+// calendarPriority maps a task priority to iCalendar's 1 (highest) to 9 scale
+func calendarPriority(priority string) string {
+	switch priority {
+	case models.PriorityHigh:
+		return "1"
+	case models.PriorityLow:
+		return "9"
+	}
+	return "5"
+}
+
+// newFeedToken returns a random feed token
+func newFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashFeedToken returns the hash a feed token is stored as
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// feedURL is the absolute URL of a feed as seen by the client
+func feedURL(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/api/calendar/%s.ics", scheme, r.Host, token)
+}
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type CustomFieldHandler struct {
+	projectAccess
+	fieldRepo *repository.CustomFieldRepository
+}
+
+func NewCustomFieldHandler(fieldRepo *repository.CustomFieldRepository, projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		fieldRepo: fieldRepo,
+	}
+}
+
+var customFieldTypes = map[string]bool{
+	models.FieldTypeText:         true,
+	models.FieldTypeNumber:       true,
+	models.FieldTypeDate:         true,
+	models.FieldTypeSingleSelect: true,
+	models.FieldTypeMultiSelect:  true,
+	models.FieldTypeUser:         true,
+}
+
+// GetFields lists the custom fields of a project
+func (h *CustomFieldHandler) GetFields(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	fields, err := h.fieldRepo.GetFieldsByProjectID(projectID)
+	if err != nil {
+		log.Printf("Error getting custom fields for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get custom fields")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, fields)
+}
+
+// CreateField adds a custom field to a project
+func (h *CustomFieldHandler) CreateField(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage custom fields")
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	var req models.CustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !customFieldTypes[req.Type] {
+		respondWithError(w, http.StatusBadRequest, "Invalid type. Must be text, number, date, single_select, multi_select, or user")
+		return
+	}
+
+	field := &models.CustomField{
+		ProjectID: projectID,
+		Type:      req.Type,
+	}
+	if err := applyCustomFieldRequest(field, req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.fieldRepo.CreateField(field); err != nil {
+		log.Printf("Error creating custom field: %v", err)
+		statusCode, errorMsg := handleDatabaseError(err)
+		respondWithError(w, statusCode, errorMsg)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, field)
+}
+
+// UpdateField changes a custom field's name, options, rules or position
+func (h *CustomFieldHandler) UpdateField(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	fieldID := vars["fieldId"]
+
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage custom fields")
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	var req models.CustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	field, err := h.fieldRepo.GetFieldByID(projectID, fieldID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Custom field not found")
+		return
+	}
+
+	if req.Type != "" && req.Type != field.Type {
+		respondWithError(w, http.StatusBadRequest, "The type of a custom field cannot be changed")
+		return
+	}
+
+	if err := applyCustomFieldRequest(field, req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.fieldRepo.UpdateField(field); err != nil {
+		log.Printf("Error updating custom field %s: %v", fieldID, err)
+		statusCode, errorMsg := handleDatabaseError(err)
+		respondWithError(w, statusCode, errorMsg)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, field)
+}
+
+// DeleteField removes a custom field and its values
+func (h *CustomFieldHandler) DeleteField(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	fieldID := vars["fieldId"]
+
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage custom fields")
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	if err := h.fieldRepo.DeleteField(projectID, fieldID); err != nil {
+		log.Printf("Error deleting custom field %s: %v", fieldID, err)
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Custom field not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete custom field")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyCustomFieldRequest validates a field definition and copies it onto field
+func applyCustomFieldRequest(field *models.CustomField, req models.CustomFieldRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("Field name is required")
+	}
+
+	isSelect := field.Type == models.FieldTypeSingleSelect || field.Type == models.FieldTypeMultiSelect
+	options := []string{}
+	if isSelect {
+		seen := map[string]bool{}
+		for _, option := range req.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || seen[option] {
+				continue
+			}
+			seen[option] = true
+			options = append(options, option)
+		}
+		if len(options) == 0 {
+			return fmt.Errorf("Select fields need at least one option")
+		}
+	}
+
+	rules := req.Validation
+	if rules.Min != nil && rules.Max != nil && *rules.Min > *rules.Max {
+		return fmt.Errorf("Validation min must not be greater than max")
+	}
+	if rules.MaxLength != nil && *rules.MaxLength < 1 {
+		return fmt.Errorf("Validation max_length must be positive")
+	}
+	if rules.Pattern != "" {
+		if _, err := regexp.Compile(rules.Pattern); err != nil {
+			return fmt.Errorf("Invalid validation pattern: %v", err)
+		}
+	}
+
+	field.Name = name
+	field.Options = options
+	field.Required = req.Required
+	field.Validation = rules
+	field.Position = req.Position
+	return nil
+}
+
+// mergeCustomFieldValues validates values against the project's fields and
+// merges them into existing. A nil value clears the field. isMember checks
+// values of user fields.
+func mergeCustomFieldValues(fields []*models.CustomField, existing, values map[string]interface{}, isMember func(userID string) bool) (map[string]interface{}, error) {
+	byID := make(map[string]*models.CustomField, len(fields))
+	for _, field := range fields {
+		byID[field.ID] = field
+	}
+
+	merged := make(map[string]interface{}, len(existing)+len(values))
+	for key, value := range existing {
+		if _, ok := byID[key]; ok {
+			merged[key] = value
+		}
+	}
+
+	for key, value := range values {
+		field, ok := byID[key]
+		if !ok {
+			return nil, fmt.Errorf("Unknown custom field %q", key)
+		}
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		normalized, err := normalizeCustomFieldValue(field, value, isMember)
+		if err != nil {
+			return nil, fmt.Errorf("Custom field %q: %v", field.Name, err)
+		}
+		if normalized == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = normalized
+	}
+
+	for _, field := range fields {
+		if _, ok := merged[field.ID]; field.Required && !ok {
+			return nil, fmt.Errorf("Custom field %q is required", field.Name)
+		}
+	}
+
+	return merged, nil
+}
+
+// normalizeCustomFieldValue checks a single value and returns its stored form.
+// Empty strings and empty selections return nil, which clears the field.
+func normalizeCustomFieldValue(field *models.CustomField, value interface{}, isMember func(userID string) bool) (interface{}, error) {
+	rules := field.Validation
+
+	switch field.Type {
+	case models.FieldTypeText:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if text == "" {
+			return nil, nil
+		}
+		if rules.MaxLength != nil && len([]rune(text)) > *rules.MaxLength {
+			return nil, fmt.Errorf("must be at most %d characters", *rules.MaxLength)
+		}
+		if rules.Pattern != "" {
+			matched, err := regexp.MatchString(rules.Pattern, text)
+			if err != nil || !matched {
+				return nil, fmt.Errorf("does not match the required format")
+			}
+		}
+		return text, nil
+
+	case models.FieldTypeNumber:
+		var number float64
+		switch v := value.(type) {
+		case float64:
+			number = v
+		case string:
+			if v == "" {
+				return nil, nil
+			}
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			number = parsed
+		default:
+			return nil, fmt.Errorf("must be a number")
+		}
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, fmt.Errorf("must be a finite number")
+		}
+		if rules.Min != nil && number < *rules.Min {
+			return nil, fmt.Errorf("must be at least %v", *rules.Min)
+		}
+		if rules.Max != nil && number > *rules.Max {
+			return nil, fmt.Errorf("must be at most %v", *rules.Max)
+		}
+		return number, nil
+
+	case models.FieldTypeDate:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a date in YYYY-MM-DD format")
+		}
+		if text == "" {
+			return nil, nil
+		}
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			return nil, fmt.Errorf("must be a date in YYYY-MM-DD format")
+		}
+		return text, nil
+
+	case models.FieldTypeSingleSelect:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be one of the field's options")
+		}
+		if text == "" {
+			return nil, nil
+		}
+		if !containsString(field.Options, text) {
+			return nil, fmt.Errorf("%q is not one of the field's options", text)
+		}
+		return text, nil
+
+	case models.FieldTypeMultiSelect:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("must be a list of the field's options")
+		}
+		selected := []string{}
+		for _, item := range list {
+			text, ok := item.(string)
+			if !ok || !containsString(field.Options, text) {
+				return nil, fmt.Errorf("%v is not one of the field's options", item)
+			}
+			if !containsString(selected, text) {
+				selected = append(selected, text)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, nil
+		}
+		return selected, nil
+
+	case models.FieldTypeUser:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a user ID")
+		}
+		if text == "" {
+			return nil, nil
+		}
+		if !isMember(text) {
+			return nil, fmt.Errorf("user is not a member of the project")
+		}
+		return text, nil
+	}
+
+	return nil, fmt.Errorf("has an unsupported type")
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
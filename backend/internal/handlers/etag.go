@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// etag formats a row version as a strong entity tag
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setETag sets the ETag header of a response
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// notModified handles If-None-Match on a GET. It writes 304 and returns true
+// if the client already has the current version.
+func notModified(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !matchesETag(header, version, true) {
+		return false
+	}
+	setETag(w, version)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// checkIfMatch enforces If-Match on an update. A missing header gets 428 and
+// a stale one 412 with the current representation, so the client can merge
+// and retry. It returns false if a response was written.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, version int, current interface{}) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		respondWithError(w, http.StatusPreconditionRequired, "If-Match header with the current ETag is required")
+		return false
+	}
+	if !matchesETag(header, version, false) {
+		respondPreconditionFailed(w, version, current)
+		return false
+	}
+	return true
+}
+
+// respondPreconditionFailed writes 412 with the current representation
+func respondPreconditionFailed(w http.ResponseWriter, version int, current interface{}) {
+	setETag(w, version)
+	respondWithJSON(w, http.StatusPreconditionFailed, current)
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header lists the
+// version's tag or "*". If-Match compares strongly, so weak tags never
+// match it; If-None-Match compares weakly.
+func matchesETag(header string, version int, weak bool) bool {
+	want := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == want {
+			return true
+		}
+	}
+	return false
+}
+
+// mergePatchContentType is the media type of JSON Merge Patch (RFC 7386)
+const mergePatchContentType = "application/merge-patch+json"
+
+// isMergePatch reports whether a request body is a JSON Merge Patch.
+// application/json and a missing Content-Type are accepted as well.
+func isMergePatch(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == mergePatchContentType || mediaType == "application/json")
+}
+
+// readMergePatch reads a JSON Merge Patch body, which must be an object
+func readMergePatch(r *http.Request) (map[string]interface{}, error) {
+	var patch interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("Patch body is required")
+		}
+		return nil, fmt.Errorf("Invalid request body")
+	}
+	object, ok := patch.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Patch must be a JSON object")
+	}
+	return object, nil
+}
+
+// mergePatch applies a JSON Merge Patch to target as RFC 7386 describes:
+// objects merge recursively, null removes a member and anything else
+// replaces it
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// applyMergePatch patches the editable fields of a resource. base holds
+// their current values; the patched document is decoded into out. Members
+// of the patch that aren't editable are rejected.
+func applyMergePatch(base map[string]interface{}, patch map[string]interface{}, out interface{}) error {
+	for key := range patch {
+		if _, ok := base[key]; !ok {
+			return fmt.Errorf("Field %q cannot be patched", key)
+		}
+	}
+
+	data, err := json.Marshal(mergePatch(base, patch))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("Invalid patch: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type versioned struct {
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	current := versioned{Title: "Current", Version: 3}
+	tests := []struct {
+		name     string
+		header   string // If-Match; none if empty
+		ok       bool
+		status   int
+		wantBody bool // the current representation is returned
+	}{
+		{"missing header", "", false, http.StatusPreconditionRequired, false},
+		{"current version", `"3"`, true, http.StatusOK, false},
+		{"any version", "*", true, http.StatusOK, false},
+		{"one of a list", `"1", "3"`, true, http.StatusOK, false},
+		{"stale version", `"2"`, false, http.StatusPreconditionFailed, true},
+		{"weak tag", `W/"3"`, false, http.StatusPreconditionFailed, true},
+		{"unquoted tag", "3", false, http.StatusPreconditionFailed, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ok bool
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if ok = checkIfMatch(w, r, current.Version, current); ok {
+					w.WriteHeader(http.StatusOK)
+				}
+			}
+			r := httptest.NewRequest(http.MethodPatch, "/api/tasks/1", strings.NewReader(`{}`))
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if ok != tt.ok || w.Code != tt.status {
+				t.Fatalf("checkIfMatch = %v with status %d, want %v with %d", ok, w.Code, tt.ok, tt.status)
+			}
+			if !tt.wantBody {
+				return
+			}
+			if got := w.Header().Get("ETag"); got != `"3"` {
+				t.Errorf("ETag = %q, want the current version", got)
+			}
+			var body versioned
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body != current {
+				t.Errorf("body = %s, want the current representation", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		header string // If-None-Match; none if empty
+		want   bool
+	}{
+		{"", false},
+		{`"3"`, true},
+		{`W/"3"`, true},
+		{`"1", W/"3"`, true},
+		{"*", true},
+		{`"2"`, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/tasks/1", nil)
+		if tt.header != "" {
+			r.Header.Set("If-None-Match", tt.header)
+		}
+		w := httptest.NewRecorder()
+		if got := notModified(w, r, 3); got != tt.want {
+			t.Errorf("notModified(%q) = %v, want %v", tt.header, got, tt.want)
+			continue
+		}
+		if tt.want && (w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"3"` || w.Body.Len() != 0) {
+			t.Errorf("If-None-Match %q: status %d, ETag %q, body %q; want an empty 304 with the ETag",
+				tt.header, w.Code, w.Header().Get("ETag"), w.Body.String())
+		}
+		if !tt.want && w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %q wrote a response", tt.header)
+		}
+	}
+}
+
+func decodeJSON(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	return v
+}
+
+func TestMergePatch(t *testing.T) {
+	// The examples of RFC 7396, appendix A
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// Removing a member that isn't there, and an empty patch
+		{`{"a":1}`, `{"b":null}`, `{"a":1}`},
+		{`{"a":{"b":1}}`, `{}`, `{"a":{"b":1}}`},
+		{`{"a":{"b":1,"c":2}}`, `{"a":{"c":null,"d":{"e":3}}}`, `{"a":{"b":1,"d":{"e":3}}}`},
+	}
+	for _, tt := range tests {
+		got := mergePatch(decodeJSON(t, tt.target), decodeJSON(t, tt.patch))
+		if want := decodeJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	base := map[string]interface{}{"title": "Old", "version": 3.0}
+
+	var out versioned
+	if err := applyMergePatch(base, map[string]interface{}{"title": "New"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != (versioned{Title: "New", Version: 3}) {
+		t.Errorf("patched into %+v", out)
+	}
+
+	if err := applyMergePatch(base, map[string]interface{}{"owner": "x"}, &out); err == nil {
+		t.Error("patched a field that isn't editable")
+	}
+	if err := applyMergePatch(base, map[string]interface{}{"version": "three"}, &out); err == nil {
+		t.Error("accepted a value of the wrong type")
+	}
+}
+
+func TestReadMergePatch(t *testing.T) {
+	for body, wantErr := range map[string]bool{
+		`{"title":"New"}`: false,
+		`{}`:              false,
+		``:                true,
+		`["title"]`:       true,
+		`"title"`:         true,
+		`{"title":`:       true,
+	} {
+		r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+		if _, err := readMergePatch(r); (err != nil) != wantErr {
+			t.Errorf("readMergePatch(%q) error = %v, want error %v", body, err, wantErr)
+		}
+	}
+
+	for contentType, want := range map[string]bool{
+		"":                                true,
+		"application/merge-patch+json":    true,
+		"application/json; charset=utf-8": true,
+		"application/json-patch+json":     false,
+		"text/plain":                      false,
+	} {
+		r := httptest.NewRequest(http.MethodPatch, "/", nil)
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		if got := isMergePatch(r); got != want {
+			t.Errorf("isMergePatch(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
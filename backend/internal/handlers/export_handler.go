@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-management/internal/due"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// Export formats
+const (
+	ExportCSV    = "csv"
+	ExportJSON   = "json"
+	ExportNDJSON = "ndjson"
+)
+
+// exportTimeout bounds how long an export may stream
+const exportTimeout = 10 * time.Minute
+
+// exportColumns are the fixed CSV columns; custom fields follow as one column each
+var exportColumns = []string{
+	"key", "id", "title", "description", "status", "priority", "assignee_name", "assignee_email",
+	"due_date", "labels", "created_at", "updated_at",
+}
+
+type ExportHandler struct {
+	projectAccess
+	taskRepo  *repository.TaskRepository
+	fieldRepo *repository.CustomFieldRepository
+}
+
+func NewExportHandler(taskRepo *repository.TaskRepository, fieldRepo *repository.CustomFieldRepository,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *ExportHandler {
+	return &ExportHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		taskRepo:  taskRepo,
+		fieldRepo: fieldRepo,
+	}
+}
+
+// ExportTasks streams the project's tasks as CSV, a JSON array or NDJSON
+// (?format=, default csv). It takes the same filter and sort parameters as
+// the task list. Any project member, viewers included, may export.
+func (h *ExportHandler) ExportTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = ExportCSV
+	}
+	var contentType string
+	switch format {
+	case ExportCSV:
+		contentType = "text/csv; charset=utf-8"
+	case ExportJSON:
+		contentType = "application/json"
+	case ExportNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		respondWithError(w, http.StatusBadRequest, "Invalid format. Use csv, json or ndjson")
+		return
+	}
+
+	fields, err := h.fieldRepo.GetFieldsByProjectID(projectID)
+	if err != nil {
+		log.Printf("Error getting custom fields for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to export tasks")
+		return
+	}
+	filter, err := parseTaskFilter(r, fields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("%s-tasks-%s.%s", strings.ToLower(project.Key), time.Now().Format("20060102"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	// Large projects take longer than the server's write timeout to stream
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportTimeout)); err != nil {
+		log.Printf("Error extending write deadline for export: %v", err)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// Headers are sent; from here on an error can only cut the stream short
+	out := bufio.NewWriter(w)
+	var count int
+	switch format {
+	case ExportCSV:
+		count, err = h.exportCSV(r, out, projectID, filter, fields)
+	default:
+		count, err = h.exportJSON(r, out, projectID, filter, format == ExportNDJSON)
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		log.Printf("Error exporting tasks of project %s after %d rows: %v", projectID, count, err)
+		return
+	}
+	log.Printf("[EXPORT] User %s exported %d task(s) of project %s as %s", userID, count, projectID, format)
+}
+
+// exportCSV writes a header row and one row per task
+func (h *ExportHandler) exportCSV(r *http.Request, out *bufio.Writer, projectID string, filter repository.TaskFilter, fields []*models.CustomField) (int, error) {
+	writer := csv.NewWriter(out)
+	header := append([]string{}, exportColumns...)
+	for _, field := range fields {
+		header = append(header, field.Name)
+	}
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err := h.taskRepo.EachTaskByProjectID(r.Context(), projectID, filter, func(task *models.Task) error {
+		row := []string{
+			task.Key,
+			task.ID,
+			task.Title,
+			task.Description,
+			task.Status,
+			task.Priority,
+			stringValue(task.AssigneeName),
+			stringValue(task.AssigneeEmail),
+			formatDueDate(task),
+			strings.Join(task.Labels, "; "),
+			task.CreatedAt.UTC().Format(time.RFC3339),
+			formatTimestamp(task.UpdatedAt),
+		}
+		for _, field := range fields {
+			row = append(row, formatFieldValue(task.CustomFields[field.ID]))
+		}
+		for i := range row {
+			row[i] = escapeFormula(row[i])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	writer.Flush()
+	return count, writer.Error()
+}
+
+// exportJSON writes the tasks as they appear in the task API, either as one
+// JSON array or as one object per line
+func (h *ExportHandler) exportJSON(r *http.Request, out *bufio.Writer, projectID string, filter repository.TaskFilter, lines bool) (int, error) {
+	encoder := json.NewEncoder(out)
+	if !lines {
+		out.WriteString("[")
+	}
+
+	count := 0
+	err := h.taskRepo.EachTaskByProjectID(r.Context(), projectID, filter, func(task *models.Task) error {
+		if !lines && count > 0 {
+			out.WriteString(",")
+		}
+		if err := encoder.Encode(task); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if !lines {
+		out.WriteString("]\n")
+	}
+	return count, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatDueDate(task *models.Task) string {
+	if task.DueDate == nil {
+		return ""
+	}
+	return due.Format(*task.DueDate, task.DueAllDay)
+}
+
+func formatTimestamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatFieldValue renders a custom field value for a CSV cell. Multi-select
+// selections are joined with "; ".
+func formatFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, "; ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// escapeFormula keeps spreadsheet apps from evaluating a cell that starts
+// like a formula (CSV injection) by prefixing it with a quote. Numbers such
+// as -5 are left alone.
+func escapeFormula(cell string) string {
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-management/internal/events"
+	"task-management/internal/gitref"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+	"task-management/internal/webhooks"
+
+	"github.com/gorilla/mux"
+)
+
+// maxPushBody bounds the size of an incoming push payload
+const maxPushBody = 5 << 20
+
+type GitHandler struct {
+	projectAccess
+	integrationRepo *repository.GitIntegrationRepository
+	taskRepo        *repository.TaskRepository
+	activityRepo    *repository.ActivityRepository
+	recurrenceRepo  *repository.RecurrenceRepository
+	events          *events.Bus
+}
+
+func NewGitHandler(integrationRepo *repository.GitIntegrationRepository, taskRepo *repository.TaskRepository,
+	activityRepo *repository.ActivityRepository, recurrenceRepo *repository.RecurrenceRepository, bus *events.Bus,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *GitHandler {
+	return &GitHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		integrationRepo: integrationRepo,
+		taskRepo:        taskRepo,
+		activityRepo:    activityRepo,
+		recurrenceRepo:  recurrenceRepo,
+		events:          bus,
+	}
+}
+
+// GetIntegration returns the project's Git integration without its secret (PO/PM)
+func (h *GitHandler) GetIntegration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage the Git integration")
+		return
+	}
+
+	integration, err := h.integrationRepo.GetIntegration(projectID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Git integration not configured")
+			return
+		}
+		log.Printf("Error getting git integration for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get Git integration")
+		return
+	}
+
+	integration.Secret = ""
+	respondWithJSON(w, http.StatusOK, integration)
+}
+
+// SaveIntegration creates or updates the project's Git integration (PO/PM).
+// The secret is returned when the integration is created or the secret rotated.
+func (h *GitHandler) SaveIntegration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage the Git integration")
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	var req models.GitIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	integration, err := h.integrationRepo.GetIntegration(projectID)
+	created := false
+	if err != nil {
+		if !strings.Contains(err.Error(), "not found") {
+			log.Printf("Error getting git integration for project %s: %v", projectID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get Git integration")
+			return
+		}
+		integration = &models.GitIntegration{ProjectID: projectID, Keywords: gitref.DefaultKeywords, Active: true}
+		created = true
+	}
+
+	if req.Keywords != nil {
+		keywords := map[string]string{}
+		for word, status := range req.Keywords {
+			word = strings.ToLower(strings.TrimSpace(word))
+			if word == "" || strings.ContainsAny(word, " \t\n") {
+				respondWithError(w, http.StatusBadRequest, "Keywords must be single words")
+				return
+			}
+			if !models.IsValidStatus(status) {
+				respondWithError(w, http.StatusBadRequest, "Keyword statuses must be todo, in-progress or done")
+				return
+			}
+			keywords[word] = status
+		}
+		integration.Keywords = keywords
+	}
+	if req.Active != nil {
+		integration.Active = *req.Active
+	}
+
+	showSecret := created || req.RotateSecret
+	if showSecret {
+		if integration.Secret, err = webhooks.NewSecret(); err != nil {
+			log.Printf("Error creating git integration secret: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to save Git integration")
+			return
+		}
+	}
+
+	if err := h.integrationRepo.SaveIntegration(integration); err != nil {
+		log.Printf("Error saving git integration for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save Git integration")
+		return
+	}
+
+	saved, err := h.integrationRepo.GetIntegration(projectID)
+	if err != nil {
+		log.Printf("Error getting saved git integration for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get Git integration")
+		return
+	}
+	if !showSecret {
+		saved.Secret = ""
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondWithJSON(w, status, saved)
+}
+
+// DeleteIntegration removes the project's Git integration (PO/PM)
+func (h *GitHandler) DeleteIntegration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage the Git integration")
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	if err := h.integrationRepo.DeleteIntegration(projectID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Git integration not configured")
+			return
+		}
+		log.Printf("Error deleting git integration for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete Git integration")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Git integration deleted successfully"})
+}
+
+// ReceivePush handles a GitHub or GitLab push hook for a project. It is not
+// behind the JWT middleware; requests are verified with the shared secret.
+// Tasks referenced after a keyword ("fixes WEB-42") get the keyword's status
+// and every referenced task gets an activity entry linking the commit.
+func (h *GitHandler) ReceivePush(w http.ResponseWriter, r *http.Request) {
+	projectID := mux.Vars(r)["projectId"]
+
+	provider, isPush, ok := gitref.Detect(r)
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Unsupported hook: expected a GitHub or GitLab event")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushBody+1))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	if len(body) > maxPushBody {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "Payload too large")
+		return
+	}
+
+	integration, err := h.integrationRepo.GetIntegration(projectID)
+	if err != nil || !integration.Active {
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			log.Printf("Error getting git integration for project %s: %v", projectID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to process hook")
+			return
+		}
+		respondWithError(w, http.StatusNotFound, "Git integration not configured")
+		return
+	}
+
+	if !gitref.Verify(provider, r, body, integration.Secret) {
+		log.Printf("[GIT] Rejected %s hook for project %s: invalid secret", provider, projectID)
+		respondWithError(w, http.StatusUnauthorized, "Invalid signature or token")
+		return
+	}
+
+	if !isPush {
+		// e.g. GitHub's ping when the hook is added
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Event ignored"})
+		return
+	}
+
+	// Pushes can't change the tasks of an archived project
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	push, err := gitref.ParsePush(body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := h.applyPush(projectID, provider, push, gitref.NewParser(integration.Keywords))
+	log.Printf("[GIT] %s push to %s (%s): %d commit(s), %d task(s) updated, %d linked",
+		provider, push.Repository, projectID, result.Commits, len(result.TasksUpdated), len(result.TasksLinked))
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// applyPush links the pushed commits to the tasks they reference and applies
+// keyword status changes. Replaying a push links nothing twice.
+func (h *GitHandler) applyPush(projectID string, provider gitref.Provider, push *gitref.Push, parser *gitref.Parser) *models.GitPushResult {
+	result := &models.GitPushResult{
+		Commits:        len(push.Commits),
+		TasksUpdated:   []string{},
+		TasksLinked:    []string{},
+		UnresolvedRefs: []string{},
+	}
+
+	for _, commit := range push.Commits {
+		for _, ref := range parser.Parse(commit.Message) {
+			task := h.resolveTask(projectID, ref.Target)
+			if task == nil {
+				if !containsString(result.UnresolvedRefs, ref.Target) {
+					result.UnresolvedRefs = append(result.UnresolvedRefs, ref.Target)
+				}
+				continue
+			}
+
+			data := map[string]interface{}{
+				"provider":     provider,
+				"repository":   push.Repository,
+				"ref":          push.Ref,
+				"sha":          commit.ID,
+				"url":          commit.URL,
+				"message":      commit.Message,
+				"author_name":  commit.Author.Name,
+				"author_email": commit.Author.Email,
+			}
+
+			if ref.Status != "" && ref.Status != task.Status {
+				changed, err := h.taskRepo.SetStatus(task.ID, ref.Status)
+				if err != nil {
+					log.Printf("[GIT] Error setting status of task %s: %v", task.ID, err)
+				} else if changed {
+					data["status_from"] = task.Status
+					data["status_to"] = ref.Status
+					h.afterStatusChange(task, ref.Status)
+					result.TasksUpdated = append(result.TasksUpdated, task.ID)
+				}
+			}
+
+			entry := &models.TaskActivity{
+				TaskID:  task.ID,
+				Type:    models.ActivityCommit,
+				Message: commitSummary(commit),
+				Data:    data,
+			}
+			added, err := h.activityRepo.AddActivity(entry, commit.ID)
+			if err != nil {
+				log.Printf("[GIT] Error linking commit %s to task %s: %v", commit.ID, task.ID, err)
+			} else if added && !containsString(result.TasksLinked, task.ID) {
+				result.TasksLinked = append(result.TasksLinked, task.ID)
+			}
+		}
+	}
+	return result
+}
+
+// resolveTask finds a task of the project referenced by UUID or key, or returns nil
+func (h *GitHandler) resolveTask(projectID, target string) *models.Task {
+	taskID := target
+	if !gitref.IsUUID(target) {
+		id, err := h.taskRepo.GetTaskIDByKey(target)
+		if err != nil {
+			return nil
+		}
+		taskID = id
+	}
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil || task.ProjectID != projectID {
+		return nil
+	}
+	return task
+}
+
+// afterStatusChange schedules the next occurrence of a completed recurring
+// task and announces the update
+func (h *GitHandler) afterStatusChange(previous *models.Task, status string) {
+	if status == models.StatusDone && previous.RecurrenceID != nil {
+		if _, err := h.recurrenceRepo.EnsureOccurrence(*previous.RecurrenceID, *previous.RecurrenceIndex+1); err != nil {
+			log.Printf("[GIT] Error creating next occurrence after task %s: %v", previous.ID, err)
+		}
+	}
+
+	updated, err := h.taskRepo.GetTaskByID(previous.ID)
+	if err != nil {
+		log.Printf("[GIT] Error getting updated task %s: %v", previous.ID, err)
+		return
+	}
+	h.events.Publish(events.Event{
+		Type:      events.TaskUpdated,
+		ProjectID: updated.ProjectID,
+		Data:      map[string]interface{}{"task": updated, "previous": previous},
+	})
+}
+
+// commitSummary is the activity message for a commit: short SHA, author and
+// the first line of the message
+func commitSummary(commit gitref.Commit) string {
+	sha := commit.ID
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	subject := strings.TrimSpace(strings.SplitN(commit.Message, "\n", 2)[0])
+	if commit.Author.Name != "" {
+		return "Commit " + sha + " by " + commit.Author.Name + ": " + subject
+	}
+	return "Commit " + sha + ": " + subject
+}
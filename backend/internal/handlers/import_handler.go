@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-management/internal/importer"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxImportBody bounds the size of an import file
+	maxImportBody = 10 << 20
+	// importSyncLimit is the most tasks imported within the request; larger
+	// imports run as a background job
+	importSyncLimit = 100
+)
+
+type ImportHandler struct {
+	projectAccess
+	importRepo *repository.ImportRepository
+	fieldRepo  *repository.CustomFieldRepository
+}
+
+func NewImportHandler(importRepo *repository.ImportRepository, fieldRepo *repository.CustomFieldRepository,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *ImportHandler {
+	return &ImportHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		importRepo: importRepo,
+		fieldRepo:  fieldRepo,
+	}
+}
+
+// importOptions are the query parameters of an import
+type importOptions struct {
+	format string
+	dryRun bool
+	// mapping maps task fields to CSV columns (map.<field>=<column>)
+	mapping map[string]string
+	// statuses and priorities map source values (status.<value>=<status>)
+	statuses   map[string]string
+	priorities map[string]string
+	// unassignUnknown imports rows whose assignee isn't a member unassigned
+	// instead of rejecting them
+	unassignUnknown bool
+}
+
+func parseImportOptions(r *http.Request) (*importOptions, error) {
+	query := r.URL.Query()
+	opts := &importOptions{
+		format:     strings.ToLower(query.Get("format")),
+		dryRun:     query.Get("dry_run") == "true" || query.Get("dry_run") == "1",
+		mapping:    map[string]string{},
+		statuses:   map[string]string{},
+		priorities: map[string]string{},
+	}
+	if opts.format == "" {
+		opts.format = importer.FormatCSV
+	}
+	if !containsString(importer.Formats, opts.format) {
+		return nil, fmt.Errorf("Invalid format. Use %s", strings.Join(importer.Formats, ", "))
+	}
+
+	switch query.Get("unknown_assignee") {
+	case "", "error":
+	case "unassign":
+		opts.unassignUnknown = true
+	default:
+		return nil, fmt.Errorf("Invalid unknown_assignee. Use error or unassign")
+	}
+
+	for key, values := range query {
+		if len(values) == 0 {
+			continue
+		}
+		value := strings.TrimSpace(values[0])
+		switch {
+		case strings.HasPrefix(key, "map."):
+			opts.mapping[strings.TrimPrefix(key, "map.")] = value
+		case strings.HasPrefix(key, "status."):
+			if !models.IsValidStatus(value) {
+				return nil, fmt.Errorf("Invalid %s: statuses are todo, in-progress or done", key)
+			}
+			opts.statuses[strings.TrimPrefix(key, "status.")] = value
+		case strings.HasPrefix(key, "priority."):
+			if !models.IsValidPriority(value) {
+				return nil, fmt.Errorf("Invalid %s: priorities are low, medium or high", key)
+			}
+			opts.priorities[strings.TrimPrefix(key, "priority.")] = value
+		}
+	}
+	return opts, nil
+}
+
+// readImportFile returns the uploaded file: the "file" field of a multipart
+// form, or else the raw request body
+func readImportFile(w http.ResponseWriter, r *http.Request) (io.Reader, func(), error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, nil, fmt.Errorf("Missing file: upload it as the \"file\" field")
+		}
+		return file, func() { file.Close() }, nil
+	}
+	return r.Body, func() {}, nil
+}
+
+// ImportTasks imports tasks from a CSV file, a Trello board export or a Jira
+// CSV export (?format=csv|trello|jira). Every row is validated first; with
+// ?dry_run=true, or if any row is invalid, only the report is returned.
+// Otherwise all tasks are created in one transaction, by a background job
+// when there are more than importSyncLimit of them.
+func (h *ImportHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if _, err := h.projectRepo.GetProjectByID(projectID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM, models.RoleMember}) {
+		respondWithError(w, http.StatusForbidden, "Viewers cannot import tasks")
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	file, closeFile, err := readImportFile(w, r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer closeFile()
+
+	rows, err := importer.Parse(opts.format, file, opts.mapping)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		respondWithError(w, http.StatusBadRequest, "The file contains no tasks")
+		return
+	}
+
+	tasks, report, err := h.validateRows(userID, projectID, rows, opts)
+	if err != nil {
+		log.Printf("Error validating import for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to import tasks")
+		return
+	}
+
+	if opts.dryRun {
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+	if report.Invalid > 0 {
+		respondWithJSON(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	if len(tasks) <= importSyncLimit {
+		if err := h.importRepo.ImportTasks(tasks); err != nil {
+			log.Printf("Error importing tasks into project %s: %v", projectID, err)
+			statusCode, errorMsg := handleDatabaseError(err)
+			respondWithError(w, statusCode, errorMsg)
+			return
+		}
+		created := len(tasks)
+		report.Created = &created
+		log.Printf("[IMPORT] User %s imported %d task(s) into project %s from %s", userID, created, projectID, opts.format)
+		respondWithJSON(w, http.StatusCreated, report)
+		return
+	}
+
+	imp := &models.TaskImport{ProjectID: projectID, UserID: &userID, Format: opts.format}
+	if err := h.importRepo.CreateImport(imp, tasks, importer.JobTypeImport); err != nil {
+		log.Printf("Error queueing import into project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to import tasks")
+		return
+	}
+	report.Import = imp
+	log.Printf("[IMPORT] User %s queued import %s of %d task(s) into project %s", userID, imp.ID, len(tasks), projectID)
+	respondWithJSON(w, http.StatusAccepted, report)
+}
+
+// validateRows maps the rows onto the project and builds the tasks to create.
+// Problems are collected per row in the report rather than returned.
+func (h *ImportHandler) validateRows(userID, projectID string, rows []importer.Row, opts *importOptions) ([]*models.Task, *models.ImportReport, error) {
+	members, err := h.projectRepo.GetProjectMembers(projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	memberIDs := map[string]string{}
+	for _, member := range members {
+		memberIDs[strings.ToLower(member.UserEmail)] = member.UserID
+	}
+	isMember := func(id string) bool {
+		for _, memberID := range memberIDs {
+			if memberID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	fields, err := h.fieldRepo.GetFieldsByProjectID(projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &models.ImportReport{
+		Format: opts.format,
+		DryRun: opts.dryRun,
+		Total:  len(rows),
+		Rows:   []models.ImportRowResult{},
+	}
+	tasks := make([]*models.Task, 0, len(rows))
+
+	for _, row := range rows {
+		result := models.ImportRowResult{Line: row.Line, Title: row.Title, Warnings: row.Warnings}
+		task := &models.Task{
+			ProjectID:   projectID,
+			UserID:      userID,
+			Title:       row.Title,
+			Description: row.Description,
+			Labels:      normalizeLabels(row.Labels),
+		}
+
+		if row.Title == "" {
+			result.Errors = append(result.Errors, "title is required")
+		} else if len(row.Title) > 255 {
+			result.Errors = append(result.Errors, "title is longer than 255 characters")
+		}
+
+		if status, ok := importer.MapStatus(row.Status, opts.statuses); ok {
+			task.Status = status
+		} else {
+			result.Errors = append(result.Errors, fmt.Sprintf("unknown status %q; map it with status.%s=<todo|in-progress|done>", row.Status, row.Status))
+		}
+		if priority, ok := importer.MapPriority(row.Priority, opts.priorities); ok {
+			task.Priority = priority
+		} else {
+			result.Errors = append(result.Errors, fmt.Sprintf("unknown priority %q; map it with priority.%s=<low|medium|high>", row.Priority, row.Priority))
+		}
+
+		if row.DueDate != "" {
+			dueDate, err := importer.ParseDate(row.DueDate)
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			} else {
+				task.DueDate = &dueDate
+				task.DueAllDay = true
+			}
+		}
+
+		if row.Assignee != "" {
+			if memberID, ok := memberIDs[strings.ToLower(row.Assignee)]; ok {
+				task.AssignedTo = &memberID
+			} else if opts.unassignUnknown {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("assignee %s is not a project member; imported unassigned", row.Assignee))
+			} else {
+				result.Errors = append(result.Errors, fmt.Sprintf("assignee %s is not a project member (assignees are matched by email)", row.Assignee))
+			}
+		}
+
+		// Imports can't fill custom fields, so required ones reject the row
+		task.CustomFields, err = mergeCustomFieldValues(fields, nil, nil, isMember)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+
+		if len(result.Errors) > 0 {
+			report.Invalid++
+		} else {
+			report.Valid++
+			task.CreatedAt = time.Now()
+			tasks = append(tasks, task)
+		}
+		if len(result.Errors) > 0 || len(result.Warnings) > 0 {
+			report.Rows = append(report.Rows, result)
+		}
+	}
+	return tasks, report, nil
+}
+
+// GetImport returns the progress of a background import
+func (h *ImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	imp, err := h.importRepo.GetImport(projectID, vars["importId"])
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Import not found")
+			return
+		}
+		log.Printf("Error getting import %s: %v", vars["importId"], err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get import")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, imp)
+}
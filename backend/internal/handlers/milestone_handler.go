@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type MilestoneHandler struct {
+	projectAccess
+	milestoneRepo *repository.MilestoneRepository
+}
+
+func NewMilestoneHandler(milestoneRepo *repository.MilestoneRepository, projectRepo *repository.ProjectRepository,
+	userRepo *repository.UserRepository) *MilestoneHandler {
+	return &MilestoneHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		milestoneRepo: milestoneRepo,
+	}
+}
+
+// GetMilestones lists the milestones of a project with completion
+// percentage, overdue counts and at-risk flags
+func (h *MilestoneHandler) GetMilestones(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	milestones, err := h.milestoneRepo.GetMilestones(projectID, h.userLocation(userID))
+	if err != nil {
+		log.Printf("Error getting milestones of project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get milestones")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, milestones)
+}
+
+// GetMilestone returns a milestone with its progress
+func (h *MilestoneHandler) GetMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	milestone, ok := h.loadMilestone(w, projectID, vars["milestoneId"], h.userLocation(userID))
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, milestone)
+}
+
+// CreateMilestone creates a milestone (PO or PM)
+func (h *MilestoneHandler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage milestones")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	var req models.MilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	milestone := &models.Milestone{ProjectID: projectID}
+	if msg := applyMilestoneRequest(milestone, &req); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.milestoneRepo.CreateMilestone(milestone); err != nil {
+		log.Printf("Error creating milestone in project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create milestone")
+		return
+	}
+
+	log.Printf("[MILESTONE] User %s created milestone %s in project %s", userID, milestone.ID, projectID)
+	h.respondWithMilestone(w, http.StatusCreated, projectID, milestone.ID, h.userLocation(userID))
+}
+
+// UpdateMilestone changes a milestone's title, description and due date (PO or PM)
+func (h *MilestoneHandler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage milestones")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	milestone, ok := h.loadMilestone(w, projectID, vars["milestoneId"], h.userLocation(userID))
+	if !ok {
+		return
+	}
+
+	var req models.MilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := applyMilestoneRequest(milestone, &req); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.milestoneRepo.UpdateMilestone(milestone); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Milestone not found")
+			return
+		}
+		log.Printf("Error updating milestone %s: %v", milestone.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update milestone")
+		return
+	}
+
+	h.respondWithMilestone(w, http.StatusOK, projectID, milestone.ID, h.userLocation(userID))
+}
+
+// DeleteMilestone deletes a milestone (PO or PM); its tasks are unlinked
+func (h *MilestoneHandler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage milestones")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	milestoneID := vars["milestoneId"]
+	if _, err := uuid.Parse(milestoneID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Milestone not found")
+		return
+	}
+	if err := h.milestoneRepo.DeleteMilestone(projectID, milestoneID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Milestone not found")
+			return
+		}
+		log.Printf("Error deleting milestone %s: %v", milestoneID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete milestone")
+		return
+	}
+
+	log.Printf("[MILESTONE] User %s deleted milestone %s of project %s", userID, milestoneID, projectID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadMilestone gets a milestone of the project, with its progress in loc.
+// It writes an error response and returns false if there is none.
+func (h *MilestoneHandler) loadMilestone(w http.ResponseWriter, projectID, milestoneID string, loc *time.Location) (*models.Milestone, bool) {
+	if _, err := uuid.Parse(milestoneID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Milestone not found")
+		return nil, false
+	}
+	milestone, err := h.milestoneRepo.GetMilestone(projectID, milestoneID, loc)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Milestone not found")
+		} else {
+			log.Printf("Error getting milestone %s: %v", milestoneID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get milestone")
+		}
+		return nil, false
+	}
+	return milestone, true
+}
+
+// respondWithMilestone writes a milestone with its progress in loc after a
+// change
+func (h *MilestoneHandler) respondWithMilestone(w http.ResponseWriter, status int, projectID, milestoneID string, loc *time.Location) {
+	milestone, err := h.milestoneRepo.GetMilestone(projectID, milestoneID, loc)
+	if err != nil {
+		log.Printf("Error getting milestone %s: %v", milestoneID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get milestone")
+		return
+	}
+	respondWithJSON(w, status, milestone)
+}
+
+// applyMilestoneRequest validates a milestone request and copies it onto the
+// milestone. It returns a message describing the first problem, if any.
+func applyMilestoneRequest(milestone *models.Milestone, req *models.MilestoneRequest) string {
+	milestone.Title = strings.TrimSpace(req.Title)
+	if milestone.Title == "" {
+		return "Title is required"
+	}
+	milestone.Description = req.Description
+
+	milestone.DueDate = nil
+	if req.DueDate != nil && strings.TrimSpace(*req.DueDate) != "" {
+		dueDate, err := time.Parse("2006-01-02", strings.TrimSpace(*req.DueDate))
+		if err != nil {
+			return "Invalid due_date format. Use YYYY-MM-DD"
+		}
+		milestone.DueDate = &dueDate
+	}
+	return ""
+}
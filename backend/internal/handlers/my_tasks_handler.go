@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"task-management/internal/due"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+)
+
+// GetMyTasks lists the caller's work across all their projects (GET
+// /api/me/tasks): tasks assigned to or created by them, or only one of the
+// two with ?relation=assigned|created. It takes the same filters and sort as
+// the project task list except custom fields, which belong to a project.
+// Without a status filter completed tasks are left out. Tasks are grouped
+// into overdue, due today, this week and later, by due date unless sorted
+// otherwise. Days are those of the user's timezone.
+func (h *TaskHandler) GetMyTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	relation := r.URL.Query().Get("relation")
+	if relation != "" && relation != repository.RelationAssigned && relation != repository.RelationCreated {
+		respondWithError(w, http.StatusBadRequest, "Invalid relation. Use assigned or created")
+		return
+	}
+
+	filter, err := parseTaskFilter(r, nil)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.Status != "" && !models.IsValidStatus(filter.Status) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+	filter.Open = filter.Status == ""
+	if filter.Sort == "" && filter.SortField == nil {
+		// Board order only makes sense within one project
+		filter.Sort = "due_date"
+	}
+
+	tasks, err := h.taskRepo.GetTasksForUser(userID, relation, filter)
+	if err != nil {
+		log.Printf("Error getting tasks of user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get tasks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, groupByDueDate(tasks, time.Now().In(h.userLocation(userID))))
+}
+
+// groupByDueDate sorts tasks into due date groups relative to now, in now's
+// location, keeping their order. A task is overdue once its due date has
+// passed. Weeks end on Sunday.
+func groupByDueDate(tasks []*models.Task, now time.Time) models.MyTasksResponse {
+	response := models.MyTasksResponse{
+		Overdue:  []*models.Task{},
+		DueToday: []*models.Task{},
+		ThisWeek: []*models.Task{},
+		Later:    []*models.Task{},
+	}
+
+	today := now.Format(due.DateLayout)
+	daysToSunday := (7 - int(now.Weekday())) % 7
+	weekEnd := now.AddDate(0, 0, daysToSunday).Format(due.DateLayout)
+
+	for _, task := range tasks {
+		if task.DueDate == nil {
+			response.Later = append(response.Later, task)
+			continue
+		}
+		day := due.Day(*task.DueDate, task.DueAllDay, now.Location())
+		switch {
+		case task.Status != models.StatusDone && !due.End(*task.DueDate, task.DueAllDay, now.Location()).After(now):
+			response.Overdue = append(response.Overdue, task)
+		case day == today:
+			response.DueToday = append(response.DueToday, task)
+		case day > today && day <= weekEnd:
+			response.ThisWeek = append(response.ThisWeek, task)
+		default:
+			response.Later = append(response.Later, task)
+		}
+	}
+	return response
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"task-management/internal/middleware"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationHandler struct {
+	notificationRepo *repository.NotificationRepository
+}
+
+func NewNotificationHandler(notificationRepo *repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: notificationRepo}
+}
+
+// GetNotifications returns the caller's notifications, newest first.
+// ?unread=true returns only unread ones; ?limit caps the count (default 50).
+func (h *NotificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 200 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit. Must be between 1 and 200")
+			return
+		}
+		limit = n
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := h.notificationRepo.GetNotifications(userID, unreadOnly, limit)
+	if err != nil {
+		log.Printf("Error getting notifications for user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notifications")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, notifications)
+}
+
+// MarkRead marks one of the caller's notifications as read
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	notificationID := mux.Vars(r)["id"]
+	if err := h.notificationRepo.MarkRead(notificationID, userID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Notification not found")
+			return
+		}
+		log.Printf("Error marking notification %s read: %v", notificationID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update notification")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Notification marked as read"})
+}
+
+// MarkAllRead marks all of the caller's notifications as read
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if err := h.notificationRepo.MarkAllRead(userID); err != nil {
+		log.Printf("Error marking notifications read for user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update notifications")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "All notifications marked as read"})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-management/internal/events"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// PositionTask saves where a task was dropped on the board (PUT
+// /api/tasks/{id}/position): its status column and the tasks directly above
+// and below it. Members may reorder tasks; changing the status takes the
+// task's creator or a PO/PM. A stale board gets 409 and should reload.
+func (h *TaskHandler) PositionTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	taskID, ok := resolveTaskRef(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req models.TaskPositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Task not found")
+		} else {
+			log.Printf("Error getting task %s to position: %v", taskID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task")
+		}
+		return
+	}
+
+	status := strings.TrimSpace(req.Status)
+	if status == "" {
+		status = task.Status
+	}
+	if !models.IsValidStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	allowed := h.hasProjectRole(userID, task.ProjectID, []string{models.RolePO, models.RolePM, models.RoleMember})
+	if status != task.Status {
+		allowed = task.UserID == userID || h.hasProjectRole(userID, task.ProjectID, []string{models.RolePO, models.RolePM})
+	}
+	if !allowed {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if !h.ensureWritable(w, task.ProjectID) {
+		return
+	}
+
+	// Neighbours may be given by key like the task itself
+	neighbours := []*string{&req.BeforeID, &req.AfterID}
+	for _, ref := range neighbours {
+		*ref = strings.TrimSpace(*ref)
+		if *ref == "" {
+			continue
+		}
+		id, ok := resolveTaskRef(w, h.taskRepo, *ref)
+		if !ok {
+			return
+		}
+		if id == task.ID {
+			respondWithError(w, http.StatusBadRequest, "A task cannot be its own neighbour")
+			return
+		}
+		*ref = id
+	}
+	if req.BeforeID != "" && req.BeforeID == req.AfterID {
+		respondWithError(w, http.StatusBadRequest, "before_id and after_id must be different tasks")
+		return
+	}
+
+	if err := h.taskRepo.PositionTask(task.ID, status, req.BeforeID, req.AfterID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRankConflict):
+			respondWithError(w, http.StatusConflict, "The board has changed. Reload and try again")
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusNotFound, "Task not found")
+		default:
+			log.Printf("Error positioning task %s: %v", task.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to move task")
+		}
+		return
+	}
+
+	updatedTask, err := h.taskRepo.GetTaskByID(task.ID)
+	if err != nil {
+		log.Printf("Error getting positioned task %s: %v", task.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get updated task")
+		return
+	}
+
+	if status != task.Status {
+		// Completing an occurrence schedules the next one, as in UpdateTask
+		if task.RecurrenceID != nil && status == models.StatusDone {
+			if _, err := h.recurrenceRepo.EnsureOccurrence(*task.RecurrenceID, *task.RecurrenceIndex+1); err != nil {
+				log.Printf("Error creating next occurrence after task %s: %v", task.ID, err)
+			}
+		}
+		h.publishTaskEvent(events.TaskUpdated, userID, updatedTask, task)
+	}
+
+	respondWithJSON(w, http.StatusOK, updatedTask)
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"task-management/internal/middleware"
 	"task-management/internal/models"
 	"task-management/internal/repository"
@@ -12,14 +15,20 @@ import (
 )
 
 type ProjectHandler struct {
-	projectRepo *repository.ProjectRepository
-	userRepo    *repository.UserRepository
+	projectAccess
+	taskRepo     *repository.TaskRepository
+	templateRepo *repository.TemplateRepository
 }
 
-func NewProjectHandler(projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *ProjectHandler {
+func NewProjectHandler(projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository, taskRepo *repository.TaskRepository,
+	templateRepo *repository.TemplateRepository) *ProjectHandler {
 	return &ProjectHandler{
-		projectRepo: projectRepo,
-		userRepo:    userRepo,
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		taskRepo:     taskRepo,
+		templateRepo: templateRepo,
 	}
 }
 
@@ -42,20 +51,48 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.TemplateID != "" {
+		template, err := h.templateRepo.GetTemplate(req.TemplateID)
+		if err != nil || !h.canUseTemplate(userID, template) {
+			respondWithError(w, http.StatusBadRequest, "Template not found")
+			return
+		}
+		description := req.Description
+		if description == "" {
+			description = template.Content.ProjectDescription
+		}
+		project, ok := createProjectFrom(w, h.projectRepo, h.templateRepo, userID, req.Name, description, req.Key, &template.Content)
+		if !ok {
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, project)
+		return
+	}
+
+	key, ok := chooseProjectKey(w, h.projectRepo, req.Name, req.Key)
+	if !ok {
+		return
+	}
+
 	// Create project
 	project := &models.Project{
 		Name:        req.Name,
 		Description: req.Description,
+		Key:         key,
 	}
 
 	if err := h.projectRepo.CreateProject(project); err != nil {
+		if isProjectKeyConflict(err) {
+			respondWithError(w, http.StatusConflict, "Project key "+key+" is already in use")
+			return
+		}
 		log.Printf("Error creating project: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to create project")
 		return
 	}
 
 	// Add creator as PO (Product Owner)
-	if err := h.projectRepo.AddMember(project.ID, userID, "po"); err != nil {
+	if err := h.projectRepo.AddMember(project.ID, userID, models.RolePO); err != nil {
 		log.Printf("Error adding creator as PO: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to add project owner")
 		return
@@ -80,13 +117,16 @@ func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Archived projects are only listed on request
+	includeArchived := r.URL.Query().Get("include_archived") == "true" || r.URL.Query().Get("include_archived") == "1"
+
 	var projects []*models.Project
 	if user.SystemRole == "admin" {
 		// System admin can see all projects
-		projects, err = h.projectRepo.GetAllProjects()
+		projects, err = h.projectRepo.GetAllProjects(includeArchived)
 	} else {
 		// Regular user sees only their projects
-		projects, err = h.projectRepo.GetProjectsByUserID(userID)
+		projects, err = h.projectRepo.GetProjectsByUserID(userID, includeArchived)
 	}
 
 	if err != nil {
@@ -121,10 +161,15 @@ func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if notModified(w, r, project.Version) {
+		return
+	}
+	setETag(w, project.Version)
 	respondWithJSON(w, http.StatusOK, project)
 }
 
-// UpdateProject updates a project
+// UpdateProject replaces a project's name and description (PUT); the key is
+// kept unless sent. PatchProject changes only the fields sent.
 func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
@@ -132,38 +177,130 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	projectID := vars["id"]
+	var req models.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	h.updateProject(w, r, userID, mux.Vars(r)["id"], func(*models.Project) (*models.UpdateProjectRequest, error) {
+		return &req, nil
+	})
+}
 
+// PatchProject changes only the name, description or key of a project sent
+// as a JSON Merge Patch (PATCH)
+func (h *ProjectHandler) PatchProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	if !isMergePatch(r) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType)
+		return
+	}
+	patch, err := readMergePatch(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.updateProject(w, r, userID, mux.Vars(r)["id"], func(project *models.Project) (*models.UpdateProjectRequest, error) {
+		for _, key := range []string{"name", "key"} {
+			if value, ok := patch[key]; ok && value == nil {
+				return nil, fmt.Errorf("%s cannot be null", key)
+			}
+		}
+		base := map[string]interface{}{
+			"name":        project.Name,
+			"description": project.Description,
+			"key":         project.Key,
+		}
+		var req models.UpdateProjectRequest
+		if err := applyMergePatch(base, patch, &req); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(req.Name) == "" {
+			return nil, fmt.Errorf("Project name is required")
+		}
+		return &req, nil
+	})
+}
+
+// updateProject applies the request built from the current project. The
+// caller must send the project's current ETag in If-Match.
+func (h *ProjectHandler) updateProject(w http.ResponseWriter, r *http.Request, userID, projectID string,
+	build func(project *models.Project) (*models.UpdateProjectRequest, error)) {
 	// Check if user is PO or PM
-	if !h.hasProjectRole(userID, projectID, []string{"po", "pm"}) {
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
 		respondWithError(w, http.StatusForbidden, "Only PO or PM can update project")
 		return
 	}
 
-	var req models.UpdateProjectRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+	if !h.ensureWritable(w, projectID) {
 		return
 	}
 
-	project := &models.Project{
-		ID:          projectID,
-		Name:        req.Name,
-		Description: req.Description,
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	if !checkIfMatch(w, r, project.Version, project) {
+		return
+	}
+
+	req, err := build(project)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	project.Name = req.Name
+	project.Description = req.Description
+
+	// Renaming the key changes the key of every task in the project
+	if req.Key != nil {
+		key, valid := models.NormalizeProjectKey(*req.Key)
+		if !valid {
+			respondWithError(w, http.StatusBadRequest, invalidProjectKeyMessage)
+			return
+		}
+		if key != project.Key && !h.hasProjectRole(userID, projectID, []string{models.RolePO}) {
+			respondWithError(w, http.StatusForbidden, "Only PO can change the project key")
+			return
+		}
+		project.Key = key
 	}
 
 	if err := h.projectRepo.UpdateProject(project); err != nil {
+		if isProjectKeyConflict(err) {
+			respondWithError(w, http.StatusConflict, "Project key "+project.Key+" is already in use")
+			return
+		}
+		if errors.Is(err, repository.ErrVersionConflict) {
+			// Someone else changed the project after it was read
+			if current, err := h.projectRepo.GetProjectByID(projectID); err == nil {
+				respondPreconditionFailed(w, current.Version, current)
+				return
+			}
+		}
 		log.Printf("Error updating project: %v", err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to update project")
 		return
 	}
 
 	updatedProject, _ := h.projectRepo.GetProjectByID(projectID)
+	if updatedProject != nil {
+		setETag(w, updatedProject.Version)
+	}
 	respondWithJSON(w, http.StatusOK, updatedProject)
 }
 
-// DeleteProject deletes a project
+// DeleteProject deletes a project. Archived projects may be deleted too:
+// archiving freezes a project's content, not whether it is kept.
 func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok || userID == "" {
@@ -175,7 +312,7 @@ func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	projectID := vars["id"]
 
 	// Only PO can delete project
-	if !h.hasProjectRole(userID, projectID, []string{"po"}) {
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO}) {
 		respondWithError(w, http.StatusForbidden, "Only PO can delete project")
 		return
 	}
@@ -189,6 +326,113 @@ func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ArchiveProject makes a project read-only and hides it from project lists (PO only)
+func (h *ProjectHandler) ArchiveProject(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// UnarchiveProject makes an archived project writable again (PO only)
+func (h *ProjectHandler) UnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *ProjectHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO}) {
+		respondWithError(w, http.StatusForbidden, "Only PO can archive or unarchive project")
+		return
+	}
+
+	changed, err := h.projectRepo.SetArchived(projectID, archived)
+	if err != nil {
+		log.Printf("Error archiving project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update project")
+		return
+	}
+
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if changed {
+		log.Printf("[PROJECT] User %s set project %s archived=%t", userID, projectID, archived)
+	}
+	respondWithJSON(w, http.StatusOK, project)
+}
+
+// RestoreProject restores a soft-deleted project from the trash
+func (h *ProjectHandler) RestoreProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+
+	// Only PO can restore project
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO}) {
+		respondWithError(w, http.StatusForbidden, "Only PO can restore project")
+		return
+	}
+
+	if err := h.projectRepo.RestoreProject(projectID); err != nil {
+		log.Printf("Error restoring project: %v", err)
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Project not found in trash")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to restore project")
+		return
+	}
+
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		log.Printf("Error getting restored project: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get project")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, project)
+}
+
+// GetTrash lists the soft-deleted tasks of a project
+func (h *ProjectHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+
+	// Only PO or PM can see the trash
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can view the trash")
+		return
+	}
+
+	tasks, err := h.taskRepo.GetDeletedTasksByProjectID(projectID)
+	if err != nil {
+		log.Printf("Error getting trash for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get trash")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, tasks)
+}
+
 // InviteMember invites a user to the project
 func (h *ProjectHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
@@ -211,11 +455,15 @@ func (h *ProjectHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
 	isAdmin := user.SystemRole == "admin"
 
 	// Check if user is PO or PM (skip for admin)
-	if !isAdmin && !h.hasProjectRole(userID, projectID, []string{"po", "pm"}) {
+	if !isAdmin && !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
 		respondWithError(w, http.StatusForbidden, "Only PO or PM can invite members")
 		return
 	}
 
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
 	var req models.InviteMemberRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
@@ -230,11 +478,18 @@ func (h *ProjectHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate role
-	validRoles := map[string]bool{"pm": true, "member": true, "viewer": true}
-	if !validRoles[req.Role] {
+	role, valid := models.NormalizeRole(req.Role)
+	if !valid || role == models.RolePO {
 		respondWithError(w, http.StatusBadRequest, "Invalid role. Must be pm, member, or viewer")
 		return
 	}
+	req.Role = role
+
+	// PMs may only invite members and viewers
+	if !isAdmin && !h.canManageRole(userID, projectID, models.RoleViewer, role) {
+		respondWithError(w, http.StatusForbidden, "Only PO can invite a PM")
+		return
+	}
 
 	// Add member
 	if err := h.projectRepo.AddMember(projectID, invitedUser.ID, req.Role); err != nil {
@@ -286,20 +541,41 @@ func (h *ProjectHandler) UpdateMemberRole(w http.ResponseWriter, r *http.Request
 	memberID := vars["userId"]
 
 	// Only PO or PM can update roles
-	if !h.hasProjectRole(userID, projectID, []string{"po", "pm"}) {
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
 		respondWithError(w, http.StatusForbidden, "Only PO or PM can update member roles")
 		return
 	}
 
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
 	var req models.UpdateMemberRoleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	if err := h.projectRepo.UpdateMemberRole(projectID, memberID, req.Role); err != nil {
+	role, valid := models.NormalizeRole(req.Role)
+	if !valid {
+		respondWithError(w, http.StatusBadRequest, "Invalid role. Must be po, pm, member, or viewer")
+		return
+	}
+
+	currentRole, err := h.projectRepo.GetMemberRole(projectID, memberID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	if !h.canManageRole(userID, projectID, currentRole, role) {
+		respondWithError(w, http.StatusForbidden, "Only PO can change PO or PM roles")
+		return
+	}
+
+	if err := h.projectRepo.UpdateMemberRole(projectID, memberID, role); err != nil {
 		log.Printf("Error updating member role: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to update role")
+		respondWithMembershipError(w, err, "Failed to update role")
 		return
 	}
 
@@ -319,50 +595,177 @@ func (h *ProjectHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
 	memberID := vars["userId"]
 
 	// Only PO or PM can remove members
-	if !h.hasProjectRole(userID, projectID, []string{"po", "pm"}) {
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
 		respondWithError(w, http.StatusForbidden, "Only PO or PM can remove members")
 		return
 	}
 
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	currentRole, err := h.projectRepo.GetMemberRole(projectID, memberID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	if !h.canManageRole(userID, projectID, currentRole, models.RoleViewer) {
+		respondWithError(w, http.StatusForbidden, "Only PO can remove a PO or PM")
+		return
+	}
+
 	if err := h.projectRepo.RemoveMember(projectID, memberID); err != nil {
 		log.Printf("Error removing member: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Failed to remove member")
+		respondWithMembershipError(w, err, "Failed to remove member")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// Helper functions
-func (h *ProjectHandler) hasProjectAccess(userID, projectID string) bool {
-	// Check if system admin
-	user, err := h.userRepo.GetUserByID(userID)
-	if err == nil && user.SystemRole == "admin" {
-		return true
+// TransferOwnership hands the PO role to another member of the project
+func (h *ProjectHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
 	}
 
-	// Check if member of project
-	_, err = h.projectRepo.GetMemberRole(projectID, userID)
-	return err == nil
-}
+	vars := mux.Vars(r)
+	projectID := vars["id"]
 
-func (h *ProjectHandler) hasProjectRole(userID, projectID string, allowedRoles []string) bool {
-	// Check if system admin
-	user, err := h.userRepo.GetUserByID(userID)
-	if err == nil && user.SystemRole == "admin" {
-		return true
+	var req models.TransferOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.UserID == "" {
+		respondWithError(w, http.StatusBadRequest, "user_id is required")
+		return
+	}
+	if req.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, "You already own this project")
+		return
 	}
 
-	// Check user's role in project
+	previousRole := models.RolePM
+	if req.PreviousOwnerRole != "" {
+		role, valid := models.NormalizeRole(req.PreviousOwnerRole)
+		if !valid {
+			respondWithError(w, http.StatusBadRequest, "Invalid previous_owner_role. Must be po, pm, member, or viewer")
+			return
+		}
+		previousRole = role
+	}
+
+	// Only the PO can hand over ownership
 	role, err := h.projectRepo.GetMemberRole(projectID, userID)
+	if err != nil || role != models.RolePO {
+		respondWithError(w, http.StatusForbidden, "Only PO can transfer ownership")
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	if err := h.projectRepo.TransferOwnership(projectID, userID, req.UserID, previousRole); err != nil {
+		log.Printf("Error transferring ownership of project %s: %v", projectID, err)
+		respondWithMembershipError(w, err, "Failed to transfer ownership")
+		return
+	}
+
+	members, err := h.projectRepo.GetProjectMembers(projectID)
 	if err != nil {
-		return false
+		log.Printf("Error getting members: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get members")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, members)
+}
+
+// LeaveProject removes the authenticated user from the project
+func (h *ProjectHandler) LeaveProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
 	}
 
-	for _, allowedRole := range allowedRoles {
-		if role == allowedRole {
-			return true
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	if err := h.projectRepo.RemoveMember(projectID, userID); err != nil {
+		log.Printf("Error leaving project %s: %v", projectID, err)
+		if errors.Is(err, repository.ErrLastOwner) {
+			respondWithError(w, http.StatusConflict, "You are the last PO. Transfer ownership before leaving")
+			return
 		}
+		respondWithMembershipError(w, err, "Failed to leave project")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// canManageRole reports whether the caller may move a member from
+// currentRole to newRole. POs and system admins may make any change; PMs
+// may only manage members and viewers.
+func (h *ProjectHandler) canManageRole(callerID, projectID, currentRole, newRole string) bool {
+	if h.hasProjectRole(callerID, projectID, []string{models.RolePO}) {
+		return true
+	}
+	isPrivileged := func(role string) bool {
+		return role == models.RolePO || role == models.RolePM
+	}
+	return !isPrivileged(currentRole) && !isPrivileged(newRole)
+}
+
+const invalidProjectKeyMessage = "Project key must be 2-10 letters or digits and start with a letter"
+
+// chooseProjectKey normalizes a requested project key, or suggests one from
+// the name if none was given. It writes an error response and returns false
+// if the key is invalid.
+func chooseProjectKey(w http.ResponseWriter, projectRepo *repository.ProjectRepository, name, key string) (string, bool) {
+	// The key prefixes task keys (WEB-42); without one, suggest one from the name
+	if key == "" {
+		key, err := projectRepo.AvailableProjectKey(models.ProjectKeyFromName(name))
+		if err != nil {
+			log.Printf("Error choosing project key: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to create project")
+			return "", false
+		}
+		return key, true
+	}
+
+	normalized, valid := models.NormalizeProjectKey(key)
+	if !valid {
+		respondWithError(w, http.StatusBadRequest, invalidProjectKeyMessage)
+		return "", false
+	}
+	return normalized, true
+}
+
+// isProjectKeyConflict reports whether err is a violation of the unique project key
+func isProjectKeyConflict(err error) bool {
+	return strings.Contains(err.Error(), "idx_projects_key")
+}
+
+// respondWithMembershipError maps membership repository errors to responses
+func respondWithMembershipError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, repository.ErrLastOwner):
+		respondWithError(w, http.StatusConflict, "A project must keep at least one PO")
+	case errors.Is(err, repository.ErrMemberNotFound):
+		respondWithError(w, http.StatusNotFound, "Member not found")
+	default:
+		respondWithError(w, http.StatusInternalServerError, fallback)
 	}
-	return false
 }
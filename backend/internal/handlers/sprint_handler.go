@@ -0,0 +1,583 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type SprintHandler struct {
+	projectAccess
+	sprintRepo *repository.SprintRepository
+	taskRepo   *repository.TaskRepository
+	fieldRepo  *repository.CustomFieldRepository
+}
+
+func NewSprintHandler(sprintRepo *repository.SprintRepository, taskRepo *repository.TaskRepository, fieldRepo *repository.CustomFieldRepository,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *SprintHandler {
+	return &SprintHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		sprintRepo: sprintRepo,
+		taskRepo:   taskRepo,
+		fieldRepo:  fieldRepo,
+	}
+}
+
+// sprintDateLayout is the format of sprint start and end dates
+const sprintDateLayout = "2006-01-02"
+
+// GetSprints lists the sprints of a project
+func (h *SprintHandler) GetSprints(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	sprints, err := h.sprintRepo.GetSprints(projectID)
+	if err != nil {
+		log.Printf("Error getting sprints of project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get sprints")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sprints)
+}
+
+// CreateSprint plans a new sprint (PO or PM)
+func (h *SprintHandler) CreateSprint(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage sprints")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	var req models.SprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	sprint := &models.Sprint{ProjectID: projectID}
+	if msg := applySprintRequest(sprint, &req); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.sprintRepo.CreateSprint(sprint); err != nil {
+		log.Printf("Error creating sprint in project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create sprint")
+		return
+	}
+
+	log.Printf("[SPRINT] User %s created sprint %s in project %s", userID, sprint.ID, projectID)
+	respondWithJSON(w, http.StatusCreated, sprint)
+}
+
+// UpdateSprint changes a sprint's name, goal and dates (PO or PM). Closed
+// sprints can't be changed.
+func (h *SprintHandler) UpdateSprint(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage sprints")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	sprint, ok := h.loadSprint(w, projectID, vars["sprintId"])
+	if !ok {
+		return
+	}
+	if sprint.State == models.SprintClosed {
+		respondWithError(w, http.StatusConflict, "Sprint is closed")
+		return
+	}
+
+	var req models.SprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := applySprintRequest(sprint, &req); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.sprintRepo.UpdateSprint(sprint); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusConflict, "Sprint is closed")
+			return
+		}
+		log.Printf("Error updating sprint %s: %v", sprint.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update sprint")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sprint)
+}
+
+// DeleteSprint deletes a planned sprint (PO or PM); its tasks go back to the
+// backlog
+func (h *SprintHandler) DeleteSprint(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage sprints")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	sprint, ok := h.loadSprint(w, projectID, vars["sprintId"])
+	if !ok {
+		return
+	}
+	if sprint.State != models.SprintPlanned {
+		respondWithError(w, http.StatusConflict, "Only planned sprints can be deleted")
+		return
+	}
+
+	if err := h.sprintRepo.DeleteSprint(projectID, sprint.ID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusConflict, "Only planned sprints can be deleted")
+			return
+		}
+		log.Printf("Error deleting sprint %s: %v", sprint.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete sprint")
+		return
+	}
+
+	log.Printf("[SPRINT] User %s deleted sprint %s of project %s", userID, sprint.ID, projectID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartSprint makes a planned sprint active (PO or PM). A project has at most
+// one active sprint.
+func (h *SprintHandler) StartSprint(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage sprints")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	sprint, ok := h.loadSprint(w, projectID, vars["sprintId"])
+	if !ok {
+		return
+	}
+	if sprint.State != models.SprintPlanned {
+		respondWithError(w, http.StatusConflict, "Only planned sprints can be started")
+		return
+	}
+
+	if err := h.sprintRepo.StartSprint(projectID, sprint.ID); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrSprintActive):
+			respondWithError(w, http.StatusConflict, "Project already has an active sprint")
+		case strings.Contains(err.Error(), "not found"):
+			respondWithError(w, http.StatusConflict, "Only planned sprints can be started")
+		default:
+			log.Printf("Error starting sprint %s: %v", sprint.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to start sprint")
+		}
+		return
+	}
+
+	log.Printf("[SPRINT] User %s started sprint %s of project %s", userID, sprint.ID, projectID)
+	h.respondWithSprint(w, projectID, sprint.ID)
+}
+
+// CloseSprint closes the active sprint (PO or PM). Unfinished tasks are
+// carried over to the sprint named by carry_over_to, to the next planned
+// sprint ("next", the default) or to the backlog ("backlog").
+func (h *SprintHandler) CloseSprint(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage sprints")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	sprint, ok := h.loadSprint(w, projectID, vars["sprintId"])
+	if !ok {
+		return
+	}
+	if sprint.State != models.SprintActive {
+		respondWithError(w, http.StatusConflict, "Only the active sprint can be closed")
+		return
+	}
+
+	// Every option has a default, so the body may be empty
+	var req models.CloseSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	carryTo := ""
+	switch target := strings.TrimSpace(req.CarryOverTo); target {
+	case "backlog":
+	case "", "next":
+		next, err := h.sprintRepo.NextPlannedSprint(projectID)
+		if err != nil {
+			log.Printf("Error getting next sprint of project %s: %v", projectID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to close sprint")
+			return
+		}
+		if next != nil {
+			carryTo = next.ID
+		}
+	default:
+		if _, err := uuid.Parse(target); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid carry_over_to. Must be a sprint ID, next or backlog")
+			return
+		}
+		next, err := h.sprintRepo.GetSprint(projectID, target)
+		if err != nil || next.State != models.SprintPlanned {
+			respondWithError(w, http.StatusBadRequest, "carry_over_to must be a planned sprint of this project")
+			return
+		}
+		carryTo = next.ID
+	}
+
+	carried, err := h.sprintRepo.CloseSprint(projectID, sprint.ID, carryTo)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not active") {
+			respondWithError(w, http.StatusConflict, "Only the active sprint can be closed")
+			return
+		}
+		log.Printf("Error closing sprint %s: %v", sprint.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to close sprint")
+		return
+	}
+
+	if carryTo == "" {
+		log.Printf("[SPRINT] User %s closed sprint %s, %d tasks back to the backlog", userID, sprint.ID, carried)
+	} else {
+		log.Printf("[SPRINT] User %s closed sprint %s, %d tasks carried over to %s", userID, sprint.ID, carried, carryTo)
+	}
+	h.respondWithSprint(w, projectID, sprint.ID)
+}
+
+// AddTasks adds tasks of the project to a sprint that isn't closed. Tasks
+// already in another sprint are moved. task_ids accepts UUIDs and keys.
+func (h *SprintHandler) AddTasks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM, models.RoleMember}) {
+		respondWithError(w, http.StatusForbidden, "Viewers cannot plan sprints")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	sprint, ok := h.loadSprint(w, projectID, vars["sprintId"])
+	if !ok {
+		return
+	}
+	if sprint.State == models.SprintClosed {
+		respondWithError(w, http.StatusConflict, "Sprint is closed")
+		return
+	}
+
+	var req models.SprintTasksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.TaskIDs) == 0 {
+		respondWithError(w, http.StatusBadRequest, "task_ids is required")
+		return
+	}
+	if len(req.TaskIDs) > maxBulkTasks {
+		respondWithError(w, http.StatusBadRequest, "Too many tasks")
+		return
+	}
+
+	var taskIDs []string
+	for _, ref := range req.TaskIDs {
+		ref = strings.TrimSpace(ref)
+		if _, _, isKey := models.ParseTaskKey(ref); isKey {
+			id, err := h.taskRepo.GetTaskIDByKey(ref)
+			if err != nil {
+				if !strings.Contains(err.Error(), "not found") {
+					log.Printf("Error resolving task key %s: %v", ref, err)
+				}
+				continue
+			}
+			ref = id
+		} else if _, err := uuid.Parse(ref); err != nil {
+			continue
+		}
+		taskIDs = append(taskIDs, ref)
+	}
+
+	added := []string{}
+	if len(taskIDs) > 0 {
+		var err error
+		added, err = h.sprintRepo.SetTasksSprint(projectID, sprint.ID, taskIDs)
+		if err != nil {
+			log.Printf("Error adding tasks to sprint %s: %v", sprint.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to add tasks to sprint")
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"sprint_id": sprint.ID,
+		"added":     added,
+		"skipped":   len(req.TaskIDs) - len(added),
+	})
+}
+
+// RemoveTask moves a task of a sprint back to the backlog
+func (h *SprintHandler) RemoveTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	vars := mux.Vars(r)
+	projectID := vars["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM, models.RoleMember}) {
+		respondWithError(w, http.StatusForbidden, "Viewers cannot plan sprints")
+		return
+	}
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	sprint, ok := h.loadSprint(w, projectID, vars["sprintId"])
+	if !ok {
+		return
+	}
+	if sprint.State == models.SprintClosed {
+		respondWithError(w, http.StatusConflict, "Sprint is closed")
+		return
+	}
+
+	taskID, ok := resolveTaskRef(w, h.taskRepo, vars["taskId"])
+	if !ok {
+		return
+	}
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil || task.SprintID == nil || *task.SprintID != sprint.ID {
+		respondWithError(w, http.StatusNotFound, "Task not found in sprint")
+		return
+	}
+
+	if _, err := h.sprintRepo.SetTasksSprint(projectID, "", []string{task.ID}); err != nil {
+		log.Printf("Error removing task %s from sprint %s: %v", task.ID, sprint.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to remove task from sprint")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetVelocity reports the tasks, and optionally points, completed in each
+// started sprint. points_field names a number custom field by ID or name
+// whose values are summed as points.
+func (h *SprintHandler) GetVelocity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	report := models.VelocityReport{}
+	pointsFieldID := ""
+	if ref := strings.TrimSpace(r.URL.Query().Get("points_field")); ref != "" {
+		fields, err := h.fieldRepo.GetFieldsByProjectID(projectID)
+		if err != nil {
+			log.Printf("Error getting custom fields of project %s: %v", projectID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get velocity")
+			return
+		}
+		for _, field := range fields {
+			if field.ID == ref || strings.EqualFold(field.Name, ref) {
+				if field.Type != models.FieldTypeNumber {
+					respondWithError(w, http.StatusBadRequest, "points_field must be a number field")
+					return
+				}
+				pointsFieldID = field.ID
+				report.PointsField = &field.Name
+				break
+			}
+		}
+		if pointsFieldID == "" {
+			respondWithError(w, http.StatusBadRequest, "Unknown points_field")
+			return
+		}
+	}
+
+	velocity, err := h.sprintRepo.Velocity(projectID, pointsFieldID)
+	if err != nil {
+		log.Printf("Error getting velocity of project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get velocity")
+		return
+	}
+	report.Sprints = velocity
+
+	// Averages only count closed sprints; the active one is still running
+	closed, tasks, points := 0, 0, 0.0
+	for _, v := range velocity {
+		if v.State != models.SprintClosed {
+			continue
+		}
+		closed++
+		tasks += v.CompletedTasks
+		if v.CompletedPoints != nil {
+			points += *v.CompletedPoints
+		}
+	}
+	if closed > 0 {
+		report.AverageTasks = float64(tasks) / float64(closed)
+		if pointsFieldID != "" {
+			average := points / float64(closed)
+			report.AveragePoints = &average
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, report)
+}
+
+// loadSprint gets a sprint of the project. It writes an error response and
+// returns false if there is none.
+func (h *SprintHandler) loadSprint(w http.ResponseWriter, projectID, sprintID string) (*models.Sprint, bool) {
+	if _, err := uuid.Parse(sprintID); err != nil {
+		respondWithError(w, http.StatusNotFound, "Sprint not found")
+		return nil, false
+	}
+	sprint, err := h.sprintRepo.GetSprint(projectID, sprintID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Sprint not found")
+		} else {
+			log.Printf("Error getting sprint %s: %v", sprintID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get sprint")
+		}
+		return nil, false
+	}
+	return sprint, true
+}
+
+// respondWithSprint writes the current state of a sprint after a change
+func (h *SprintHandler) respondWithSprint(w http.ResponseWriter, projectID, sprintID string) {
+	sprint, err := h.sprintRepo.GetSprint(projectID, sprintID)
+	if err != nil {
+		log.Printf("Error getting sprint %s: %v", sprintID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get sprint")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, sprint)
+}
+
+// applySprintRequest validates a sprint request and copies it onto the
+// sprint. It returns a message describing the first problem, if any.
+func applySprintRequest(sprint *models.Sprint, req *models.SprintRequest) string {
+	sprint.Name = strings.TrimSpace(req.Name)
+	if sprint.Name == "" {
+		return "Sprint name is required"
+	}
+	sprint.Goal = strings.TrimSpace(req.Goal)
+
+	parse := func(value *string) (*time.Time, bool) {
+		if value == nil || strings.TrimSpace(*value) == "" {
+			return nil, true
+		}
+		date, err := time.Parse(sprintDateLayout, strings.TrimSpace(*value))
+		if err != nil {
+			return nil, false
+		}
+		return &date, true
+	}
+	var ok bool
+	if sprint.StartDate, ok = parse(req.StartDate); !ok {
+		return "Invalid start_date. Use YYYY-MM-DD"
+	}
+	if sprint.EndDate, ok = parse(req.EndDate); !ok {
+		return "Invalid end_date. Use YYYY-MM-DD"
+	}
+	if sprint.StartDate != nil && sprint.EndDate != nil && sprint.EndDate.Before(*sprint.StartDate) {
+		return "end_date must not be before start_date"
+	}
+	return ""
+}
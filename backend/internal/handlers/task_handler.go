@@ -2,25 +2,48 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"task-management/internal/due"
+	"task-management/internal/events"
 	"task-management/internal/middleware"
 	"task-management/internal/models"
+	"task-management/internal/recurrence"
 	"task-management/internal/repository"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
 type TaskHandler struct {
-	taskRepo *repository.TaskRepository
+	projectAccess
+	taskRepo       *repository.TaskRepository
+	fieldRepo      *repository.CustomFieldRepository
+	recurrenceRepo *repository.RecurrenceRepository
+	milestoneRepo  *repository.MilestoneRepository
+	events         *events.Bus
 }
 
-func NewTaskHandler(taskRepo *repository.TaskRepository) *TaskHandler {
-	return &TaskHandler{taskRepo: taskRepo}
+func NewTaskHandler(taskRepo *repository.TaskRepository, projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository,
+	fieldRepo *repository.CustomFieldRepository, recurrenceRepo *repository.RecurrenceRepository, milestoneRepo *repository.MilestoneRepository,
+	bus *events.Bus) *TaskHandler {
+	return &TaskHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		taskRepo:       taskRepo,
+		fieldRepo:      fieldRepo,
+		recurrenceRepo: recurrenceRepo,
+		milestoneRepo:  milestoneRepo,
+		events:         bus,
+	}
 }
 
 // GetTasks retrieves all tasks for a project (team collaboration)
@@ -51,17 +74,79 @@ func (h *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	fields, err := h.fieldRepo.GetFieldsByProjectID(projectID)
+	if err != nil {
+		log.Printf("Error getting custom fields for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get tasks")
+		return
+	}
+
+	filter, err := parseTaskFilter(r, fields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupBy != "milestone" {
+		respondWithError(w, http.StatusBadRequest, "Invalid group_by. Use milestone")
+		return
+	}
+
 	// Get tasks by project (for team collaboration)
-	tasks, err := h.taskRepo.GetTasksByProjectID(projectID)
+	tasks, err := h.taskRepo.GetTasksByProjectID(projectID, filter)
 	if err != nil {
 		log.Printf("Error getting tasks for project %s: %v", projectID, err)
 		respondWithError(w, http.StatusInternalServerError, "Failed to get tasks")
 		return
 	}
 
+	if groupBy == "milestone" {
+		groups, err := h.groupByMilestone(projectID, tasks, h.userLocation(userID))
+		if err != nil {
+			log.Printf("Error grouping tasks of project %s by milestone: %v", projectID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get tasks")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, groups)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, tasks)
 }
 
+// groupByMilestone groups tasks by milestone in milestone due date order,
+// keeping the task order within each group. Milestones without matching
+// tasks are left out; tasks without a milestone come last. Milestone
+// progress is worked out in loc.
+func (h *TaskHandler) groupByMilestone(projectID string, tasks []*models.Task, loc *time.Location) ([]models.TaskGroup, error) {
+	milestones, err := h.milestoneRepo.GetMilestones(projectID, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	byMilestone := map[string][]*models.Task{}
+	var unlinked []*models.Task
+	for _, task := range tasks {
+		if task.MilestoneID == nil {
+			unlinked = append(unlinked, task)
+		} else {
+			byMilestone[*task.MilestoneID] = append(byMilestone[*task.MilestoneID], task)
+		}
+	}
+
+	groups := []models.TaskGroup{}
+	for _, milestone := range milestones {
+		if linked := byMilestone[milestone.ID]; len(linked) > 0 {
+			groups = append(groups, models.TaskGroup{Milestone: milestone, Tasks: linked})
+		}
+	}
+	if len(unlinked) > 0 {
+		groups = append(groups, models.TaskGroup{Tasks: unlinked})
+	}
+	return groups, nil
+}
+
 // GetTask retrieves a single task by ID
 func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -78,9 +163,8 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 
 	// Get task ID from URL
 	vars := mux.Vars(r)
-	taskID := vars["id"]
-	if taskID == "" {
-		respondWithError(w, http.StatusBadRequest, "Task ID is required")
+	taskID, ok := resolveTaskRef(w, h.taskRepo, vars["id"])
+	if !ok {
 		return
 	}
 
@@ -108,6 +192,10 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if notModified(w, r, task.Version) {
+		return
+	}
+	setETag(w, task.Version)
 	respondWithJSON(w, http.StatusOK, task)
 }
 
@@ -148,6 +236,10 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.ensureWritable(w, req.ProjectID) {
+		return
+	}
+
 	// Set default values
 	if req.Status == "" {
 		req.Status = "todo"
@@ -167,21 +259,65 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		Status:      req.Status,
 		Priority:    req.Priority,
 		AssignedTo:  req.AssignedTo,
+		Labels:      normalizeLabels(req.Labels),
 		CreatedAt:   time.Now(),
 	}
 
+	if req.MilestoneID != nil && *req.MilestoneID != "" {
+		if !h.checkMilestone(w, req.ProjectID, *req.MilestoneID) {
+			return
+		}
+		task.MilestoneID = req.MilestoneID
+	}
+	if req.EstimateMinutes != nil {
+		if *req.EstimateMinutes < 0 {
+			respondWithError(w, http.StatusBadRequest, "estimate_minutes must not be negative")
+			return
+		}
+		if *req.EstimateMinutes > 0 {
+			task.EstimateMinutes = req.EstimateMinutes
+		}
+	}
+
 	// Parse due_date if provided
-	if req.DueDate != nil && *req.DueDate != "" {
-		parsedDate, err := time.Parse("2006-01-02", *req.DueDate)
+	if !h.applyDueDate(w, userID, task, nil, req.DueDate, req.DueAllDay) {
+		return
+	}
+
+	// Recurring tasks repeat from their due date
+	var rule *recurrence.Rule
+	if req.RecurrenceRule != nil && *req.RecurrenceRule != "" {
+		parsedRule, err := recurrence.Parse(*req.RecurrenceRule)
 		if err != nil {
-			log.Printf("[TASK] Invalid due date format for user %s: %s (error: %v)", userID, *req.DueDate, err)
-			respondWithError(w, http.StatusBadRequest, "Invalid due_date format. Use YYYY-MM-DD")
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid recurrence_rule: %v", err))
+			return
+		}
+		if task.DueDate == nil {
+			respondWithError(w, http.StatusBadRequest, "Recurring tasks need a due_date")
 			return
 		}
-		task.DueDate = &parsedDate
+		rule = parsedRule
+	}
+
+	// Validate custom field values against the project's fields
+	fields, err := h.fieldRepo.GetFieldsByProjectID(req.ProjectID)
+	if err != nil {
+		log.Printf("[TASK] Error getting custom fields for project %s: %v", req.ProjectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create task")
+		return
+	}
+	task.CustomFields, err = mergeCustomFieldValues(fields, nil, req.CustomFields, h.memberChecker(req.ProjectID))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	if err := h.taskRepo.CreateTask(task); err != nil {
+	if rule != nil {
+		err = h.recurrenceRepo.CreateSeries(task, rule.String())
+	} else {
+		err = h.taskRepo.CreateTask(task)
+	}
+	if err != nil {
 		log.Printf("[TASK] Error creating task for user %s: %v", userID, err)
 		log.Printf("[TASK] Task details - ProjectID: %s, Title: %s, Status: %s, Priority: %s",
 			task.ProjectID, task.Title, task.Status, task.Priority)
@@ -195,10 +331,12 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[TASK] Successfully created task %s for user %s", task.ID, userID)
+	h.publishTaskEvent(events.TaskCreated, userID, task, nil)
 	respondWithJSON(w, http.StatusCreated, task)
 }
 
-// UpdateTask updates an existing task
+// UpdateTask replaces an existing task's fields (PUT). Fields missing from
+// the body are cleared; PatchTask changes only the fields sent.
 func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -214,9 +352,8 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	// Get task ID from URL
 	vars := mux.Vars(r)
-	taskID := vars["id"]
-	if taskID == "" {
-		respondWithError(w, http.StatusBadRequest, "Task ID is required")
+	taskID, ok := resolveTaskRef(w, h.taskRepo, vars["id"])
+	if !ok {
 		return
 	}
 
@@ -227,6 +364,122 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.updateTask(w, r, userID, taskID, func(*models.Task) (*models.UpdateTaskRequest, error) {
+		return &req, nil
+	})
+}
+
+// PatchTask changes only the fields of a task sent as a JSON Merge Patch
+// (PATCH). null clears a field; custom_fields merges by field.
+func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	taskID, ok := resolveTaskRef(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if !isMergePatch(r) {
+		respondWithError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType)
+		return
+	}
+	patch, err := readMergePatch(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.updateTask(w, r, userID, taskID, func(existing *models.Task) (*models.UpdateTaskRequest, error) {
+		return taskPatchRequest(existing, patch)
+	})
+}
+
+// taskPatchRequest turns a merge patch into the update request that leaves
+// every field the patch doesn't mention unchanged
+func taskPatchRequest(existing *models.Task, patch map[string]interface{}) (*models.UpdateTaskRequest, error) {
+	for _, key := range []string{"title", "status", "priority"} {
+		if value, ok := patch[key]; ok && value == nil {
+			return nil, fmt.Errorf("%s cannot be null", key)
+		}
+	}
+
+	base := map[string]interface{}{
+		"title":            existing.Title,
+		"description":      existing.Description,
+		"status":           existing.Status,
+		"priority":         existing.Priority,
+		"due_date":         nil,
+		"assigned_to":      existing.AssignedTo,
+		"labels":           existing.Labels,
+		"milestone_id":     existing.MilestoneID,
+		"estimate_minutes": existing.EstimateMinutes,
+		"custom_fields":    nil,
+		"recurrence_rule":  nil,
+	}
+	// due_all_day alone switches the existing date, see applyDueDate
+	_, sentDate := patch["due_date"]
+	allDayOnly := patch["due_all_day"] != nil && !sentDate
+	if existing.DueDate != nil && !allDayOnly {
+		base["due_date"] = due.Format(*existing.DueDate, existing.DueAllDay)
+	}
+
+	var req models.UpdateTaskRequest
+	if err := applyMergePatch(base, patch, &req); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		return nil, fmt.Errorf("Title is required")
+	}
+
+	// Clearing is spelled null in a merge patch; the request spells it empty
+	if value, ok := patch["labels"]; ok && value == nil {
+		req.Labels = []string{}
+	}
+	if value, ok := patch["milestone_id"]; ok && value == nil {
+		req.MilestoneID = new(string)
+	}
+	if value, ok := patch["recurrence_rule"]; ok && value == nil {
+		req.RecurrenceRule = new(string)
+	}
+	if value, ok := patch["estimate_minutes"]; ok && value == nil {
+		req.EstimateMinutes = new(int)
+	}
+
+	// custom_fields is already merged per field by the update; null clears them all
+	if value, ok := patch["custom_fields"]; ok {
+		switch fields := value.(type) {
+		case nil:
+			req.CustomFields = map[string]interface{}{}
+			for id := range existing.CustomFields {
+				req.CustomFields[id] = nil
+			}
+		case map[string]interface{}:
+			req.CustomFields = fields
+		default:
+			return nil, fmt.Errorf("custom_fields must be an object")
+		}
+	}
+	return &req, nil
+}
+
+// updateTask applies the request built from the existing task. The caller
+// must send the task's current ETag in If-Match.
+func (h *TaskHandler) updateTask(w http.ResponseWriter, r *http.Request, userID, taskID string,
+	build func(existing *models.Task) (*models.UpdateTaskRequest, error)) {
+	// Recurring tasks can be edited alone (default) or with all future occurrences
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		scope = models.ScopeThis
+	}
+	if scope != models.ScopeThis && scope != models.ScopeFuture {
+		respondWithError(w, http.StatusBadRequest, "Invalid scope. Must be this or future")
+		return
+	}
+
 	// Get existing task to verify ownership
 	existingTask, err := h.taskRepo.GetTaskByID(taskID)
 	if err != nil {
@@ -244,35 +497,122 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if existingTask.UserID != userID {
+	if !h.canChangeTask(userID, existingTask, false) {
 		log.Printf("Access denied: user %s tried to update task %s owned by user %s", userID, taskID, existingTask.UserID)
 		respondWithError(w, http.StatusForbidden, "Access denied")
 		return
 	}
 
+	if !h.ensureWritable(w, existingTask.ProjectID) {
+		return
+	}
+
+	if !checkIfMatch(w, r, existingTask.Version, existingTask) {
+		return
+	}
+
+	req, err := build(existingTask)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.RecurrenceRule != nil && scope != models.ScopeFuture {
+		respondWithError(w, http.StatusBadRequest, "recurrence_rule can only be changed with scope=future")
+		return
+	}
+
+	if scope == models.ScopeFuture && existingTask.RecurrenceID == nil && req.RecurrenceRule == nil {
+		respondWithError(w, http.StatusBadRequest, "Task is not recurring")
+		return
+	}
+
 	// Update task
 	task := &models.Task{
-		ID:          taskID,
-		UserID:      userID,
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      req.Status,
-		Priority:    req.Priority,
-		CreatedAt:   existingTask.CreatedAt,
-		UpdatedAt:   &time.Time{},
+		ID:              taskID,
+		ProjectID:       existingTask.ProjectID,
+		UserID:          userID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Status:          req.Status,
+		Priority:        req.Priority,
+		AssignedTo:      req.AssignedTo,
+		Labels:          existingTask.Labels,
+		RecurrenceID:    existingTask.RecurrenceID,
+		RecurrenceIndex: existingTask.RecurrenceIndex,
+		MilestoneID:     existingTask.MilestoneID,
+		EstimateMinutes: existingTask.EstimateMinutes,
+		Version:         existingTask.Version,
+		CreatedAt:       existingTask.CreatedAt,
+		UpdatedAt:       &time.Time{},
+	}
+	if task.AssignedTo != nil && *task.AssignedTo == "" {
+		task.AssignedTo = nil
+	}
+	if req.EstimateMinutes != nil {
+		switch {
+		case *req.EstimateMinutes < 0:
+			respondWithError(w, http.StatusBadRequest, "estimate_minutes must not be negative")
+			return
+		case *req.EstimateMinutes == 0:
+			task.EstimateMinutes = nil
+		default:
+			task.EstimateMinutes = req.EstimateMinutes
+		}
+	}
+	if req.MilestoneID != nil {
+		if *req.MilestoneID == "" {
+			task.MilestoneID = nil
+		} else {
+			if !h.checkMilestone(w, existingTask.ProjectID, *req.MilestoneID) {
+				return
+			}
+			task.MilestoneID = req.MilestoneID
+		}
+	}
+	if req.Labels != nil {
+		task.Labels = normalizeLabels(req.Labels)
 	}
 
 	// Parse due_date if provided
-	if req.DueDate != nil && *req.DueDate != "" {
-		parsedDate, err := time.Parse("2006-01-02", *req.DueDate)
+	if !h.applyDueDate(w, userID, task, existingTask, req.DueDate, req.DueAllDay) {
+		return
+	}
+
+	var newRule string
+	if req.RecurrenceRule != nil && *req.RecurrenceRule != "" {
+		parsedRule, err := recurrence.Parse(*req.RecurrenceRule)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid due_date format. Use YYYY-MM-DD")
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid recurrence_rule: %v", err))
 			return
 		}
-		task.DueDate = &parsedDate
+		if task.DueDate == nil {
+			respondWithError(w, http.StatusBadRequest, "Recurring tasks need a due_date")
+			return
+		}
+		newRule = parsedRule.String()
+	}
+
+	// Merge custom field values; fields not sent keep their values
+	fields, err := h.fieldRepo.GetFieldsByProjectID(existingTask.ProjectID)
+	if err != nil {
+		log.Printf("Error getting custom fields for project %s: %v", existingTask.ProjectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update task")
+		return
+	}
+	task.CustomFields, err = mergeCustomFieldValues(fields, existingTask.CustomFields, req.CustomFields, h.memberChecker(existingTask.ProjectID))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	if err := h.taskRepo.UpdateTask(task); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			// Someone else changed the task after it was read
+			if current, err := h.taskRepo.GetTaskByID(taskID); err == nil {
+				respondPreconditionFailed(w, current.Version, current)
+				return
+			}
+		}
 		log.Printf("Error updating task %s for user %s: %v", taskID, userID, err)
 		statusCode, errorMsg := handleDatabaseError(err)
 		if isDevelopment() {
@@ -283,6 +623,26 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if scope == models.ScopeFuture {
+		if req.RecurrenceRule != nil {
+			err = h.recurrenceRepo.Reschedule(task, newRule)
+		} else {
+			err = h.recurrenceRepo.UpdateFuture(task)
+		}
+		if err != nil {
+			log.Printf("Error updating future occurrences of task %s: %v", taskID, err)
+			respondWithError(w, http.StatusInternalServerError, "Task updated but future occurrences could not be changed")
+			return
+		}
+	}
+
+	// Completing an occurrence schedules the next one
+	if existingTask.RecurrenceID != nil && existingTask.Status != "done" && task.Status == "done" {
+		if _, err := h.recurrenceRepo.EnsureOccurrence(*existingTask.RecurrenceID, *existingTask.RecurrenceIndex+1); err != nil {
+			log.Printf("Error creating next occurrence after task %s: %v", taskID, err)
+		}
+	}
+
 	// Get updated task
 	updatedTask, err := h.taskRepo.GetTaskByID(taskID)
 	if err != nil {
@@ -296,9 +656,25 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.publishTaskEvent(events.TaskUpdated, userID, updatedTask, existingTask)
+	setETag(w, updatedTask.Version)
 	respondWithJSON(w, http.StatusOK, updatedTask)
 }
 
+// canChangeTask reports whether the user may change a task, or move it to
+// another project if move is set. It is the rule of the single-task
+// endpoints, which bulk operations share: only the creator edits or deletes
+// a task, and the creator or a PO/PM of its project may move it.
+func (h *TaskHandler) canChangeTask(userID string, task *models.Task, move bool) bool {
+	if task.UserID == userID {
+		return true
+	}
+	if move {
+		return h.hasProjectRole(userID, task.ProjectID, []string{models.RolePO, models.RolePM})
+	}
+	return false
+}
+
 // DeleteTask deletes a task
 func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -315,9 +691,14 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 
 	// Get task ID from URL
 	vars := mux.Vars(r)
-	taskID := vars["id"]
-	if taskID == "" {
-		respondWithError(w, http.StatusBadRequest, "Task ID is required")
+	taskID, ok := resolveTaskRef(w, h.taskRepo, vars["id"])
+	if !ok {
+		return
+	}
+
+	// Keep a copy for the deleted event; a missing task is reported by DeleteTask
+	task, _ := h.taskRepo.GetTaskByID(taskID)
+	if task != nil && !h.ensureWritable(w, task.ProjectID) {
 		return
 	}
 
@@ -338,5 +719,273 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if task != nil {
+		h.publishTaskEvent(events.TaskDeleted, userID, task, nil)
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// RestoreTask restores a soft-deleted task from its project trash
+func (h *TaskHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get user ID from context
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	// Get task ID from URL
+	vars := mux.Vars(r)
+	taskID, ok := resolveTaskRef(w, h.taskRepo, vars["id"])
+	if !ok {
+		return
+	}
+
+	deletedTask, err := h.taskRepo.GetDeletedTaskByID(taskID)
+	if err != nil {
+		log.Printf("Error getting deleted task %s for user %s: %v", taskID, userID, err)
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			respondWithError(w, http.StatusNotFound, "Task not found in trash")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task")
+		}
+		return
+	}
+
+	// The creator, a PO/PM of the project or a system admin may restore
+	if deletedTask.UserID != userID && !h.hasProjectRole(userID, deletedTask.ProjectID, []string{models.RolePO, models.RolePM}) {
+		log.Printf("Access denied: user %s tried to restore task %s owned by user %s", userID, taskID, deletedTask.UserID)
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	if !h.ensureWritable(w, deletedTask.ProjectID) {
+		return
+	}
+
+	if err := h.taskRepo.RestoreTask(taskID); err != nil {
+		log.Printf("Error restoring task %s for user %s: %v", taskID, userID, err)
+		statusCode, errorMsg := handleDatabaseError(err)
+		if isDevelopment() {
+			respondWithError(w, statusCode, fmt.Sprintf("%s: %v", errorMsg, err))
+		} else {
+			respondWithError(w, statusCode, "Failed to restore task")
+		}
+		return
+	}
+
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		// The task is restored but its project may still be in the trash
+		log.Printf("Error getting restored task %s: %v", taskID, err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.publishTaskEvent(events.TaskRestored, userID, task, nil)
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// publishTaskEvent announces a task change to event subscribers such as
+// webhooks. previous is the task before an update.
+func (h *TaskHandler) publishTaskEvent(eventType, actorID string, task, previous *models.Task) {
+	data := map[string]interface{}{"task": task}
+	if previous != nil {
+		data["previous"] = previous
+	}
+	h.events.Publish(events.Event{Type: eventType, ProjectID: task.ProjectID, ActorID: actorID, Data: data})
+}
+
+// resolveTaskRef returns the task ID for a task route, which accepts either
+// the task's UUID or its key (WEB-42). It writes an error response and
+// returns false if the ref is empty or names no task.
+func resolveTaskRef(w http.ResponseWriter, taskRepo *repository.TaskRepository, ref string) (string, bool) {
+	if ref == "" {
+		respondWithError(w, http.StatusBadRequest, "Task ID is required")
+		return "", false
+	}
+	if _, _, isKey := models.ParseTaskKey(ref); !isKey {
+		return ref, true
+	}
+
+	taskID, err := taskRepo.GetTaskIDByKey(ref)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Task not found")
+		} else {
+			log.Printf("Error resolving task key %s: %v", ref, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task")
+		}
+		return "", false
+	}
+	return taskID, true
+}
+
+// loadTask resolves a task ID or key and gets the task. It writes an error
+// response and returns false if there is none.
+func loadTask(w http.ResponseWriter, taskRepo *repository.TaskRepository, ref string) (*models.Task, bool) {
+	taskID, ok := resolveTaskRef(w, taskRepo, ref)
+	if !ok {
+		return nil, false
+	}
+	task, err := taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Task not found")
+		} else {
+			log.Printf("Error getting task %s: %v", taskID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task")
+		}
+		return nil, false
+	}
+	return task, true
+}
+
+// checkMilestone verifies that a milestone belongs to the project. It writes
+// an error response and returns false otherwise.
+func (h *TaskHandler) checkMilestone(w http.ResponseWriter, projectID, milestoneID string) bool {
+	if _, err := uuid.Parse(milestoneID); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid milestone_id")
+		return false
+	}
+	if _, err := h.milestoneRepo.GetMilestone(projectID, milestoneID, time.UTC); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusBadRequest, "Milestone not found in this project")
+		} else {
+			log.Printf("Error getting milestone %s: %v", milestoneID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get milestone")
+		}
+		return false
+	}
+	return true
+}
+
+// applyDueDate sets the task's due date from a request: YYYY-MM-DD is due
+// all day, RFC 3339 at a moment. A date and time without an offset is in the
+// user's timezone. Sent without a date, due_all_day switches the existing
+// task's date between all-day and a moment on the same day. It writes an
+// error response and returns false if the date is invalid.
+func (h *TaskHandler) applyDueDate(w http.ResponseWriter, userID string, task, existing *models.Task, value *string, allDay *bool) bool {
+	if value == nil && allDay != nil && existing != nil && existing.DueDate != nil {
+		dueDate := due.SetAllDay(*existing.DueDate, existing.DueAllDay, *allDay, h.userLocation(userID))
+		task.DueDate = &dueDate
+		task.DueAllDay = *allDay
+		return true
+	}
+	if value == nil || *value == "" {
+		return true
+	}
+	dueDate, dueAllDay, err := due.Parse(*value, allDay != nil && *allDay, h.userLocation(userID))
+	if err != nil {
+		log.Printf("[TASK] Invalid due date format for user %s: %s (error: %v)", userID, *value, err)
+		respondWithError(w, http.StatusBadRequest, "Invalid due_date format. Use YYYY-MM-DD or RFC 3339, e.g. 2006-01-02T15:04:05+07:00")
+		return false
+	}
+	task.DueDate = &dueDate
+	task.DueAllDay = dueAllDay
+	return true
+}
+
+// normalizeLabels trims labels and drops empty and duplicate ones
+func normalizeLabels(labels []string) []string {
+	normalized := []string{}
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label != "" && !containsString(normalized, label) {
+			normalized = append(normalized, label)
+		}
+	}
+	return normalized
+}
+
+// memberChecker returns a function reporting whether a user belongs to the project
+func (h *TaskHandler) memberChecker(projectID string) func(userID string) bool {
+	return func(userID string) bool {
+		_, err := h.projectRepo.GetMemberRole(projectID, userID)
+		return err == nil
+	}
+}
+
+// parseTaskFilter reads the task list filters from the query string:
+// status, priority, assigned_to ("none" for unassigned), sprint (a sprint
+// ID, "active" or "backlog"), milestone (a milestone ID or "none"),
+// cf.<field>=value and sort=[-]created_at|due_date|title|priority|cf.<field>.
+// Custom fields may be referenced by ID or by name.
+func parseTaskFilter(r *http.Request, fields []*models.CustomField) (repository.TaskFilter, error) {
+	query := r.URL.Query()
+	filter := repository.TaskFilter{
+		Status:     query.Get("status"),
+		Priority:   query.Get("priority"),
+		AssignedTo: query.Get("assigned_to"),
+		Sprint:     query.Get("sprint"),
+		Milestone:  query.Get("milestone"),
+	}
+	if filter.Sprint != "" && filter.Sprint != "backlog" && filter.Sprint != "active" {
+		if _, err := uuid.Parse(filter.Sprint); err != nil {
+			return filter, fmt.Errorf("Invalid sprint. Use a sprint ID, active or backlog")
+		}
+	}
+	if filter.Milestone != "" && filter.Milestone != "none" {
+		if _, err := uuid.Parse(filter.Milestone); err != nil {
+			return filter, fmt.Errorf("Invalid milestone. Use a milestone ID or none")
+		}
+	}
+
+	findField := func(ref string) *models.CustomField {
+		for _, field := range fields {
+			if field.ID == ref || strings.EqualFold(field.Name, ref) {
+				return field
+			}
+		}
+		return nil
+	}
+
+	for key, values := range query {
+		if !strings.HasPrefix(key, "cf.") || len(values) == 0 {
+			continue
+		}
+		field := findField(strings.TrimPrefix(key, "cf."))
+		if field == nil {
+			return filter, fmt.Errorf("Unknown custom field %q", strings.TrimPrefix(key, "cf."))
+		}
+		value := values[0]
+		switch field.Type {
+		case models.FieldTypeNumber:
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return filter, fmt.Errorf("Filter on %q must be a number", field.Name)
+			}
+		case models.FieldTypeDate:
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return filter, fmt.Errorf("Filter on %q must be a date in YYYY-MM-DD format", field.Name)
+			}
+		}
+		filter.CustomFields = append(filter.CustomFields, repository.CustomFieldFilter{Field: field, Value: value})
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		if strings.HasPrefix(sort, "-") {
+			filter.SortDesc = true
+			sort = strings.TrimPrefix(sort, "-")
+		}
+		if strings.HasPrefix(sort, "cf.") {
+			filter.SortField = findField(strings.TrimPrefix(sort, "cf."))
+			if filter.SortField == nil {
+				return filter, fmt.Errorf("Unknown custom field %q", strings.TrimPrefix(sort, "cf."))
+			}
+		} else {
+			switch sort {
+			case "created_at", "due_date", "title", "priority":
+				filter.Sort = sort
+			default:
+				return filter, fmt.Errorf("Invalid sort. Use created_at, due_date, title, priority or cf.<field>")
+			}
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type TemplateHandler struct {
+	projectAccess
+	templateRepo *repository.TemplateRepository
+}
+
+func NewTemplateHandler(templateRepo *repository.TemplateRepository, projectRepo *repository.ProjectRepository,
+	userRepo *repository.UserRepository) *TemplateHandler {
+	return &TemplateHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		templateRepo: templateRepo,
+	}
+}
+
+// canUseTemplate reports whether a user may see and use a template: its
+// creator, members of the project it was saved from and system admins
+func (a *projectAccess) canUseTemplate(userID string, template *models.ProjectTemplate) bool {
+	if template.CreatedBy != nil && *template.CreatedBy == userID {
+		return true
+	}
+	if template.SourceProjectID != nil {
+		return a.hasProjectAccess(userID, *template.SourceProjectID)
+	}
+	return a.isSystemAdmin(userID)
+}
+
+// SaveTemplate saves a project's custom fields and open tasks as a template
+// (PO only). The project is only read, so archived projects can be saved too.
+func (h *TemplateHandler) SaveTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO}) {
+		respondWithError(w, http.StatusForbidden, "Only PO can save a project as a template")
+		return
+	}
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	var req models.CreateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = project.Name
+	}
+
+	opts := models.SnapshotOptions{
+		CustomFields: true,
+		Tasks:        req.IncludeTasks == nil || *req.IncludeTasks,
+		DueDates:     models.DueDatesClear,
+	}
+	if req.RelativeDueDates {
+		opts.DueDates = models.DueDatesRelative
+	}
+
+	content, err := h.templateRepo.Snapshot(project, opts)
+	if err != nil {
+		log.Printf("Error capturing project %s for a template: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save template")
+		return
+	}
+
+	template := &models.ProjectTemplate{
+		Name:            req.Name,
+		Description:     req.Description,
+		SourceProjectID: &projectID,
+		CreatedBy:       &userID,
+		Content:         *content,
+	}
+	if err := h.templateRepo.CreateTemplate(template); err != nil {
+		log.Printf("Error saving template of project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to save template")
+		return
+	}
+
+	log.Printf("[TEMPLATE] User %s saved project %s as template %s", userID, projectID, template.ID)
+	respondWithJSON(w, http.StatusCreated, template)
+}
+
+// GetTemplates lists the templates the user can use
+func (h *TemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	templates, err := h.templateRepo.GetTemplatesForUser(userID, h.isSystemAdmin(userID))
+	if err != nil {
+		log.Printf("Error getting templates for user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get templates")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, templates)
+}
+
+// GetTemplate returns a template with its content
+func (h *TemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	template, ok := h.loadTemplate(w, userID, mux.Vars(r)["templateId"])
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, template)
+}
+
+// DeleteTemplate deletes a template (its creator or a system admin)
+func (h *TemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	template, ok := h.loadTemplate(w, userID, mux.Vars(r)["templateId"])
+	if !ok {
+		return
+	}
+	if (template.CreatedBy == nil || *template.CreatedBy != userID) && !h.isSystemAdmin(userID) {
+		respondWithError(w, http.StatusForbidden, "Only the template's creator can delete it")
+		return
+	}
+
+	if err := h.templateRepo.DeleteTemplate(template.ID); err != nil {
+		log.Printf("Error deleting template %s: %v", template.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadTemplate gets a template the user may use. It writes an error response
+// and returns false otherwise.
+func (h *TemplateHandler) loadTemplate(w http.ResponseWriter, userID, templateID string) (*models.ProjectTemplate, bool) {
+	template, err := h.templateRepo.GetTemplate(templateID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Template not found")
+		} else {
+			log.Printf("Error getting template %s: %v", templateID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get template")
+		}
+		return nil, false
+	}
+	if !h.canUseTemplate(userID, template) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return nil, false
+	}
+	return template, true
+}
+
+// CloneProject copies a project into a new one owned by the caller (PO or PM
+// of the source). Options choose whether custom fields, open and completed
+// tasks and members are copied and whether due dates are kept, shifted to
+// the new project's start or cleared. The source is only read and may be
+// archived; the clone is not.
+func (h *TemplateHandler) CloneProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can clone project")
+		return
+	}
+	source, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	// All options have defaults, so the body may be empty
+	var req models.CloneProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		req.Name = source.Name + " (copy)"
+	}
+
+	opts := models.SnapshotOptions{CustomFields: true, Tasks: true, DueDates: models.DueDatesKeep}
+	if req.Options != nil {
+		opts = *req.Options
+		if opts.DueDates == "" {
+			opts.DueDates = models.DueDatesKeep
+		}
+	}
+	if opts.DueDates != models.DueDatesKeep && opts.DueDates != models.DueDatesRelative && opts.DueDates != models.DueDatesClear {
+		respondWithError(w, http.StatusBadRequest, "Invalid due_dates. Must be keep, relative or clear")
+		return
+	}
+	if opts.CompletedTasks {
+		opts.Tasks = true
+	}
+
+	content, err := h.templateRepo.Snapshot(source, opts)
+	if err != nil {
+		log.Printf("Error capturing project %s for a clone: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to clone project")
+		return
+	}
+
+	description := content.ProjectDescription
+	if req.Description != nil {
+		description = *req.Description
+	}
+	project, ok := createProjectFrom(w, h.projectRepo, h.templateRepo, userID, req.Name, description, req.Key, content)
+	if !ok {
+		return
+	}
+
+	log.Printf("[PROJECT] User %s cloned project %s into %s", userID, projectID, project.ID)
+	respondWithJSON(w, http.StatusCreated, project)
+}
+
+// createProjectFrom creates a project owned by the user with the given
+// content. It writes an error response and returns false if the project
+// can't be created.
+func createProjectFrom(w http.ResponseWriter, projectRepo *repository.ProjectRepository, templateRepo *repository.TemplateRepository,
+	userID, name, description, key string, content *models.TemplateContent) (*models.Project, bool) {
+	key, ok := chooseProjectKey(w, projectRepo, name, key)
+	if !ok {
+		return nil, false
+	}
+
+	project := &models.Project{Name: name, Description: description, Key: key}
+	if err := templateRepo.CreateProjectFrom(project, userID, content); err != nil {
+		if isProjectKeyConflict(err) {
+			respondWithError(w, http.StatusConflict, "Project key "+key+" is already in use")
+			return nil, false
+		}
+		log.Printf("Error creating project: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create project")
+		return nil, false
+	}
+	return project, true
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-management/internal/events"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// MoveTask moves a task to another project. The caller must be able to
+// edit the task (its creator or a PO/PM of its project) and to add tasks to
+// the target project. The task gets a new key there and the move is noted in
+// its activity log.
+func (h *TaskHandler) MoveTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	source, ok := h.transferSource(w, r)
+	if !ok {
+		return
+	}
+	if !h.canChangeTask(userID, source, true) {
+		log.Printf("Access denied: user %s tried to move task %s owned by user %s", userID, source.ID, source.UserID)
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if !h.ensureWritable(w, source.ProjectID) {
+		return
+	}
+
+	to, targetProject, ok := h.transferTarget(w, r, userID, source)
+	if !ok {
+		return
+	}
+	if to.ProjectID == source.ProjectID {
+		respondWithError(w, http.StatusBadRequest, "Task is already in this project")
+		return
+	}
+
+	if err := h.taskRepo.MoveTask(source.ID, *to, moveNote(userID, source, targetProject)); err != nil {
+		log.Printf("Error moving task %s to project %s for user %s: %v", source.ID, to.ProjectID, userID, err)
+		statusCode, errorMsg := handleDatabaseError(err)
+		if isDevelopment() {
+			respondWithError(w, statusCode, fmt.Sprintf("%s: %v", errorMsg, err))
+		} else {
+			respondWithError(w, statusCode, "Failed to move task")
+		}
+		return
+	}
+
+	task, err := h.taskRepo.GetTaskByID(source.ID)
+	if err != nil {
+		log.Printf("Error getting moved task %s: %v", source.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Task moved but could not be loaded")
+		return
+	}
+
+	log.Printf("[TASK] User %s moved task %s from project %s to %s", userID, source.ID, source.ProjectID, to.ProjectID)
+	for _, projectID := range []string{source.ProjectID, to.ProjectID} {
+		h.events.Publish(events.Event{
+			Type:      events.TaskMoved,
+			ProjectID: projectID,
+			ActorID:   userID,
+			Data:      map[string]interface{}{"task": task, "previous": source},
+		})
+	}
+	respondWithJSON(w, http.StatusOK, task)
+}
+
+// moveNote is the activity log entry of moving source to target
+func moveNote(userID string, source *models.Task, target *models.Project) *models.TaskActivity {
+	return &models.TaskActivity{
+		ActorID: &userID,
+		Type:    models.ActivityMoved,
+		Message: fmt.Sprintf("Moved from %s to %s", source.Key, target.Name),
+		Data: map[string]interface{}{
+			"from_project_id": source.ProjectID,
+			"to_project_id":   target.ID,
+			"previous_key":    source.Key,
+		},
+	}
+}
+
+// CopyTask copies a task into a project, which may be its own. Anyone who
+// can see the task may copy it into a project they can add tasks to; the
+// copy is theirs. The task's activity log is carried over.
+func (h *TaskHandler) CopyTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	source, ok := h.transferSource(w, r)
+	if !ok {
+		return
+	}
+	if source.UserID != userID && !h.hasProjectAccess(userID, source.ProjectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	to, _, ok := h.transferTarget(w, r, userID, source)
+	if !ok {
+		return
+	}
+
+	task := &models.Task{
+		ProjectID:    to.ProjectID,
+		UserID:       userID,
+		Title:        source.Title,
+		Description:  source.Description,
+		Status:       source.Status,
+		Priority:     source.Priority,
+		DueDate:      source.DueDate,
+		DueAllDay:    source.DueAllDay,
+		Labels:       source.Labels,
+		CustomFields: to.CustomFields,
+		// The estimate carries over; logged time stays with the original
+		EstimateMinutes: source.EstimateMinutes,
+	}
+	if *to.AssignedTo != "" {
+		task.AssignedTo = to.AssignedTo
+	}
+
+	note := &models.TaskActivity{
+		ActorID: &userID,
+		Type:    models.ActivityCopied,
+		Message: fmt.Sprintf("Copied from %s", source.Key),
+		Data: map[string]interface{}{
+			"source_task_id": source.ID,
+			"source_key":     source.Key,
+		},
+	}
+	if err := h.taskRepo.CopyTask(source.ID, task, note); err != nil {
+		log.Printf("Error copying task %s to project %s for user %s: %v", source.ID, to.ProjectID, userID, err)
+		statusCode, errorMsg := handleDatabaseError(err)
+		if isDevelopment() {
+			respondWithError(w, statusCode, fmt.Sprintf("%s: %v", errorMsg, err))
+		} else {
+			respondWithError(w, statusCode, "Failed to copy task")
+		}
+		return
+	}
+
+	// Reload for the assignee details
+	if copied, err := h.taskRepo.GetTaskByID(task.ID); err == nil {
+		task = copied
+	} else {
+		log.Printf("Error getting copied task %s: %v", task.ID, err)
+	}
+
+	log.Printf("[TASK] User %s copied task %s to %s in project %s", userID, source.ID, task.ID, to.ProjectID)
+	h.publishTaskEvent(events.TaskCreated, userID, task, nil)
+	respondWithJSON(w, http.StatusCreated, task)
+}
+
+// transferSource loads the task of a move or copy route. It writes an error
+// response and returns false if there is no such task.
+func (h *TaskHandler) transferSource(w http.ResponseWriter, r *http.Request) (*models.Task, bool) {
+	taskID, ok := resolveTaskRef(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return nil, false
+	}
+
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			respondWithError(w, http.StatusNotFound, "Task not found")
+		} else {
+			log.Printf("Error getting task %s: %v", taskID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get task")
+		}
+		return nil, false
+	}
+	return task, true
+}
+
+// transferTarget reads the target of a move or copy and checks that the
+// user may add tasks to it. It resolves the assignee, who must be a member
+// of the target project, and the target's custom field values. It writes an
+// error response and returns false if the request can't be carried out.
+func (h *TaskHandler) transferTarget(w http.ResponseWriter, r *http.Request, userID string, source *models.Task) (*repository.TaskTransfer, *models.Project, bool) {
+	var req models.TransferTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, nil, false
+	}
+	if req.ProjectID == "" {
+		respondWithError(w, http.StatusBadRequest, "project_id is required")
+		return nil, nil, false
+	}
+
+	project, err := h.projectRepo.GetProjectByID(req.ProjectID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Target project not found")
+		return nil, nil, false
+	}
+	if !h.hasProjectRole(userID, project.ID, []string{models.RolePO, models.RolePM, models.RoleMember}) {
+		respondWithError(w, http.StatusForbidden, "You cannot add tasks to the target project")
+		return nil, nil, false
+	}
+	if !h.ensureWritable(w, project.ID) {
+		return nil, nil, false
+	}
+
+	isMember := h.memberChecker(project.ID)
+	assignee := ""
+	if req.AssignedTo != nil {
+		assignee = strings.TrimSpace(*req.AssignedTo)
+		if assignee != "" && !isMember(assignee) {
+			respondWithError(w, http.StatusBadRequest, "Assignee is not a member of the target project")
+			return nil, nil, false
+		}
+	} else if source.AssignedTo != nil && isMember(*source.AssignedTo) {
+		assignee = *source.AssignedTo
+	}
+
+	sourceFields, err := h.fieldRepo.GetFieldsByProjectID(source.ProjectID)
+	if err != nil {
+		log.Printf("Error getting custom fields for project %s: %v", source.ProjectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get custom fields")
+		return nil, nil, false
+	}
+	targetFields := sourceFields
+	if project.ID != source.ProjectID {
+		targetFields, err = h.fieldRepo.GetFieldsByProjectID(project.ID)
+		if err != nil {
+			log.Printf("Error getting custom fields for project %s: %v", project.ID, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get custom fields")
+			return nil, nil, false
+		}
+	}
+
+	customFields, err := transferCustomFields(sourceFields, targetFields, source.CustomFields, req.CustomFields, isMember)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+
+	return &repository.TaskTransfer{
+		ProjectID:    project.ID,
+		AssignedTo:   &assignee,
+		CustomFields: customFields,
+	}, project, true
+}
+
+// transferCustomFields works out a task's custom field values in another
+// project: its values are carried over by name, then values sets the
+// target's fields. Fields the target requires must end up set.
+func transferCustomFields(from, to []*models.CustomField, existing, values map[string]interface{}, isMember func(userID string) bool) (map[string]interface{}, error) {
+	carried := carryCustomFieldValues(from, to, existing, isMember)
+	return mergeCustomFieldValues(to, carried, values, isMember)
+}
+
+// carryCustomFieldValues maps a task's custom field values onto the fields
+// of another project by name and type. Values the target field doesn't
+// accept, such as a select option it lacks, are dropped.
+func carryCustomFieldValues(from, to []*models.CustomField, values map[string]interface{}, isMember func(userID string) bool) map[string]interface{} {
+	carried := map[string]interface{}{}
+	for _, source := range from {
+		value, ok := values[source.ID]
+		if !ok {
+			continue
+		}
+		for _, target := range to {
+			if !strings.EqualFold(target.Name, source.Name) || target.Type != source.Type {
+				continue
+			}
+			if normalized, err := normalizeCustomFieldValue(target, value, isMember); err == nil && normalized != nil {
+				carried[target.ID] = normalized
+			}
+			break
+		}
+	}
+	return carried
+}
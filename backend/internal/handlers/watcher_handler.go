@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/gorilla/mux"
+)
+
+type WatcherHandler struct {
+	projectAccess
+	watcherRepo *repository.WatcherRepository
+	taskRepo    *repository.TaskRepository
+}
+
+func NewWatcherHandler(watcherRepo *repository.WatcherRepository, taskRepo *repository.TaskRepository,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *WatcherHandler {
+	return &WatcherHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		watcherRepo: watcherRepo,
+		taskRepo:    taskRepo,
+	}
+}
+
+// GetWatchers lists the users watching a task
+func (h *WatcherHandler) GetWatchers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	task, ok := loadTask(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.hasProjectAccess(userID, task.ProjectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	h.respondWithWatchers(w, task.ID)
+}
+
+// WatchTask subscribes the current user to a task's notifications. Sending
+// {"muted": true} keeps the subscription but silences it; the creator and
+// assignee, who are subscribed automatically, mute a task this way. Anyone
+// who can see the task may watch it, viewers included. Like the rest of an
+// archived project, its watchers can't be changed.
+func (h *WatcherHandler) WatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	task, ok := loadTask(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.hasProjectAccess(userID, task.ProjectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if !h.ensureWritable(w, task.ProjectID) {
+		return
+	}
+
+	// The body is optional
+	var req models.WatchRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.watcherRepo.Watch(task.ID, userID, req.Muted); err != nil {
+		log.Printf("Error watching task %s: %v", task.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to watch task")
+		return
+	}
+
+	log.Printf("[TASK] User %s watches task %s", userID, task.ID)
+	h.respondWithWatchers(w, task.ID)
+}
+
+// UnwatchTask removes the current user's subscription to a task
+func (h *WatcherHandler) UnwatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	task, ok := loadTask(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.hasProjectAccess(userID, task.ProjectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if !h.ensureWritable(w, task.ProjectID) {
+		return
+	}
+
+	if err := h.watcherRepo.Unwatch(task.ID, userID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "You are not watching this task")
+			return
+		}
+		log.Printf("Error unwatching task %s: %v", task.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to unwatch task")
+		return
+	}
+
+	log.Printf("[TASK] User %s stopped watching task %s", userID, task.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondWithWatchers writes the watchers of a task
+func (h *WatcherHandler) respondWithWatchers(w http.ResponseWriter, taskID string) {
+	watchers, err := h.watcherRepo.GetWatchers(taskID)
+	if err != nil {
+		log.Printf("Error getting watchers of task %s: %v", taskID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get watchers")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, watchers)
+}
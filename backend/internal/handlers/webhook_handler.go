@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"task-management/internal/events"
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+	"task-management/internal/webhooks"
+
+	"github.com/gorilla/mux"
+)
+
+type WebhookHandler struct {
+	projectAccess
+	webhookRepo *repository.WebhookRepository
+	dispatcher  *webhooks.Dispatcher
+}
+
+func NewWebhookHandler(webhookRepo *repository.WebhookRepository, dispatcher *webhooks.Dispatcher,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *WebhookHandler {
+	return &WebhookHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		webhookRepo: webhookRepo,
+		dispatcher:  dispatcher,
+	}
+}
+
+// validateWebhookRequest checks the URL and event types of a webhook. URLs
+// on loopback, private or link-local addresses are rejected.
+func validateWebhookRequest(ctx context.Context, req *models.WebhookRequest) error {
+	parsed, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if err := webhooks.CheckURL(ctx, parsed); err != nil {
+		return fmt.Errorf("url is not allowed: %v", err)
+	}
+	req.URL = parsed.String()
+
+	if len(req.Events) == 0 {
+		return fmt.Errorf("events must list at least one event type, or \"*\" for all")
+	}
+	for _, event := range req.Events {
+		if event != "*" && !containsString(events.Types, event) {
+			return fmt.Errorf("unknown event type %q. Must be one of %s or \"*\"", event, strings.Join(events.Types, ", "))
+		}
+	}
+	return nil
+}
+
+// requireWebhookManager returns the caller and project ID, or writes an error
+// response and returns false unless the caller is PO or PM of the project
+func (h *WebhookHandler) requireWebhookManager(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return "", "", false
+	}
+
+	projectID := mux.Vars(r)["id"]
+	if !h.hasProjectRole(userID, projectID, []string{models.RolePO, models.RolePM}) {
+		respondWithError(w, http.StatusForbidden, "Only PO or PM can manage webhooks")
+		return "", "", false
+	}
+	return userID, projectID, true
+}
+
+// getWebhook loads the webhook named in the URL, writing 404 if it doesn't exist
+func (h *WebhookHandler) getWebhook(w http.ResponseWriter, r *http.Request, projectID string) (*models.Webhook, bool) {
+	hook, err := h.webhookRepo.GetWebhookByID(projectID, mux.Vars(r)["webhookId"])
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Webhook not found")
+		} else {
+			log.Printf("Error getting webhook: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get webhook")
+		}
+		return nil, false
+	}
+	return hook, true
+}
+
+// GetWebhooks lists the webhooks of a project (PO/PM). Secrets are not included.
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	_, projectID, ok := h.requireWebhookManager(w, r)
+	if !ok {
+		return
+	}
+
+	hooks, err := h.webhookRepo.GetWebhooksByProjectID(projectID)
+	if err != nil {
+		log.Printf("Error getting webhooks for project %s: %v", projectID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get webhooks")
+		return
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+
+	respondWithJSON(w, http.StatusOK, hooks)
+}
+
+// CreateWebhook registers a webhook (PO/PM). The response is the only time
+// the signing secret is shown.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := h.requireWebhookManager(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := validateWebhookRequest(r.Context(), &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		log.Printf("Error creating webhook secret: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+
+	hook := &models.Webhook{
+		ProjectID: projectID,
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    secret,
+		CreatedBy: &userID,
+	}
+	if err := h.webhookRepo.CreateWebhook(hook); err != nil {
+		log.Printf("Error creating webhook for project %s: %v", projectID, err)
+		statusCode, errorMsg := handleDatabaseError(err)
+		respondWithError(w, statusCode, errorMsg)
+		return
+	}
+
+	log.Printf("[WEBHOOK] Webhook %s created for project %s by user %s", hook.ID, projectID, userID)
+	respondWithJSON(w, http.StatusCreated, hook)
+}
+
+// UpdateWebhook changes a webhook's URL, events or active flag (PO/PM)
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	_, projectID, ok := h.requireWebhookManager(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	hook, ok := h.getWebhook(w, r, projectID)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := validateWebhookRequest(r.Context(), &req); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hook.URL = req.URL
+	hook.Events = req.Events
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	if err := h.webhookRepo.UpdateWebhook(hook); err != nil {
+		log.Printf("Error updating webhook %s: %v", hook.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update webhook")
+		return
+	}
+
+	updated, ok := h.getWebhook(w, r, projectID)
+	if !ok {
+		return
+	}
+	updated.Secret = ""
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// DeleteWebhook removes a webhook and its delivery log (PO/PM)
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	_, projectID, ok := h.requireWebhookManager(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.ensureWritable(w, projectID) {
+		return
+	}
+
+	webhookID := mux.Vars(r)["webhookId"]
+	if err := h.webhookRepo.DeleteWebhook(projectID, webhookID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Webhook not found")
+			return
+		}
+		log.Printf("Error deleting webhook %s: %v", webhookID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// GetDeliveries returns the recent delivery log of a webhook (PO/PM)
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	_, projectID, ok := h.requireWebhookManager(w, r)
+	if !ok {
+		return
+	}
+	hook, ok := h.getWebhook(w, r, projectID)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.webhookRepo.GetDeliveries(hook.ID, 100)
+	if err != nil {
+		log.Printf("Error getting deliveries of webhook %s: %v", hook.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get deliveries")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// TestWebhook queues a ping event to the webhook, even if it is disabled (PO/PM)
+func (h *WebhookHandler) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, projectID, ok := h.requireWebhookManager(w, r)
+	if !ok {
+		return
+	}
+	hook, ok := h.getWebhook(w, r, projectID)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.Queue(hook, events.Event{
+		Type:      events.Ping,
+		ProjectID: projectID,
+		ActorID:   userID,
+		Data:      map[string]string{"message": "Test event from the task manager", "webhook_id": hook.ID},
+	})
+	if err != nil {
+		log.Printf("Error queueing test delivery for webhook %s: %v", hook.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to send test event")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, delivery)
+}
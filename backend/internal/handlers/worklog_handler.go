@@ -0,0 +1,501 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-management/internal/middleware"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type WorkLogHandler struct {
+	projectAccess
+	workLogRepo *repository.WorkLogRepository
+	taskRepo    *repository.TaskRepository
+}
+
+func NewWorkLogHandler(workLogRepo *repository.WorkLogRepository, taskRepo *repository.TaskRepository,
+	projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository) *WorkLogHandler {
+	return &WorkLogHandler{
+		projectAccess: projectAccess{
+			projectRepo: projectRepo,
+			userRepo:    userRepo,
+		},
+		workLogRepo: workLogRepo,
+		taskRepo:    taskRepo,
+	}
+}
+
+// GetWorkLogs lists the work logs of a task, running timers included
+func (h *WorkLogHandler) GetWorkLogs(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	task, ok := loadTask(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.hasProjectAccess(userID, task.ProjectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	workLogs, err := h.workLogRepo.GetWorkLogsByTask(task.ID)
+	if err != nil {
+		log.Printf("Error getting work logs of task %s: %v", task.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get work logs")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, workLogs)
+}
+
+// CreateWorkLog records time the current user spent on a task. Viewers
+// cannot log time.
+func (h *WorkLogHandler) CreateWorkLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	task, ok := loadTask(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.hasProjectRole(userID, task.ProjectID, []string{models.RolePO, models.RolePM, models.RoleMember}) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if !h.ensureWritable(w, task.ProjectID) {
+		return
+	}
+
+	var req models.WorkLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	workLog := &models.WorkLog{TaskID: task.ID, UserID: userID}
+	if msg := applyWorkLogRequest(workLog, &req); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.workLogRepo.CreateWorkLog(workLog); err != nil {
+		log.Printf("Error creating work log on task %s: %v", task.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to create work log")
+		return
+	}
+
+	log.Printf("[WORKLOG] User %s logged %d minutes on task %s", userID, workLog.Minutes, task.ID)
+	h.respondWithWorkLog(w, http.StatusCreated, workLog.ID)
+}
+
+// UpdateWorkLog changes a work log's minutes, date and note. Users edit
+// their own logs; a PO or PM may edit every log in the project.
+func (h *WorkLogHandler) UpdateWorkLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	workLog, ok := h.loadWorkLog(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.canEditWorkLog(userID, workLog) {
+		respondWithError(w, http.StatusForbidden, "You can only edit your own work logs")
+		return
+	}
+	if !h.ensureWritable(w, workLog.ProjectID) {
+		return
+	}
+	if workLog.Running {
+		respondWithError(w, http.StatusConflict, "Stop the timer before editing this work log")
+		return
+	}
+
+	var req models.WorkLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Date == nil {
+		// Keep the logged day unless another is given
+		date := workLog.Date.Format("2006-01-02")
+		req.Date = &date
+	}
+	if msg := applyWorkLogRequest(workLog, &req); msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := h.workLogRepo.UpdateWorkLog(workLog); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Work log not found")
+			return
+		}
+		log.Printf("Error updating work log %s: %v", workLog.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to update work log")
+		return
+	}
+
+	h.respondWithWorkLog(w, http.StatusOK, workLog.ID)
+}
+
+// DeleteWorkLog deletes a work log, with the same rules as UpdateWorkLog.
+// Deleting a running timer discards it.
+func (h *WorkLogHandler) DeleteWorkLog(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	workLog, ok := h.loadWorkLog(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.canEditWorkLog(userID, workLog) {
+		respondWithError(w, http.StatusForbidden, "You can only delete your own work logs")
+		return
+	}
+	if !h.ensureWritable(w, workLog.ProjectID) {
+		return
+	}
+
+	if err := h.workLogRepo.DeleteWorkLog(workLog.ID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Work log not found")
+			return
+		}
+		log.Printf("Error deleting work log %s: %v", workLog.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete work log")
+		return
+	}
+
+	log.Printf("[WORKLOG] User %s deleted work log %s of task %s", userID, workLog.ID, workLog.TaskID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartTimer starts a timer for the current user on a task. A user has at
+// most one running timer, so starting another gets 409.
+func (h *WorkLogHandler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	task, ok := loadTask(w, h.taskRepo, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	if !h.hasProjectRole(userID, task.ProjectID, []string{models.RolePO, models.RolePM, models.RoleMember}) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if !h.ensureWritable(w, task.ProjectID) {
+		return
+	}
+
+	// The note is optional, so an empty body is fine
+	var req models.StartTimerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	timer, err := h.workLogRepo.StartTimer(task.ID, userID, strings.TrimSpace(req.Note))
+	if err != nil {
+		if errors.Is(err, repository.ErrTimerRunning) {
+			respondWithError(w, http.StatusConflict, "A timer is already running. Stop it first")
+			return
+		}
+		log.Printf("Error starting timer on task %s: %v", task.ID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to start timer")
+		return
+	}
+
+	log.Printf("[WORKLOG] User %s started a timer on task %s", userID, task.ID)
+	respondWithJSON(w, http.StatusCreated, timer)
+}
+
+// StopTimer stops the current user's running timer and returns the work log
+// it became. Stopping works in archived projects too, so a timer left
+// running is never stuck.
+func (h *WorkLogHandler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	workLog, err := h.workLogRepo.StopTimer(userID)
+	if err != nil {
+		log.Printf("Error stopping timer of user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to stop timer")
+		return
+	}
+	if workLog == nil {
+		respondWithError(w, http.StatusNotFound, "No timer is running")
+		return
+	}
+
+	log.Printf("[WORKLOG] User %s stopped the timer on task %s after %d minutes", userID, workLog.TaskID, workLog.Minutes)
+	respondWithJSON(w, http.StatusOK, workLog)
+}
+
+// GetTimer returns the current user's running timer, or 404 if none runs
+func (h *WorkLogHandler) GetTimer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	timer, err := h.workLogRepo.GetRunningTimer(userID)
+	if err != nil {
+		log.Printf("Error getting timer of user %s: %v", userID, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get timer")
+		return
+	}
+	if timer == nil {
+		respondWithError(w, http.StatusNotFound, "No timer is running")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, timer)
+}
+
+// GetProjectTimeReport totals estimates and logged time of a project's tasks
+// (?from=&to= dates, ?user_id=, ?format=json|csv). Any project member may
+// see it.
+func (h *WorkLogHandler) GetProjectTimeReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	projectID := mux.Vars(r)["id"]
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if !h.hasProjectAccess(userID, projectID) {
+		respondWithError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	filter, msg := parseTimeReportFilter(r)
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	filter.ProjectID = projectID
+	h.respondWithTimeReport(w, r, filter, strings.ToLower(project.Key))
+}
+
+// GetTimeReport totals the time the current user logged across projects.
+// A system admin may report on another user with ?user_id=.
+func (h *WorkLogHandler) GetTimeReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok || userID == "" {
+		respondWithError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	filter, msg := parseTimeReportFilter(r)
+	if msg != "" {
+		respondWithError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if filter.UserID == "" {
+		filter.UserID = userID
+	} else if filter.UserID != userID && !h.isSystemAdmin(userID) {
+		respondWithError(w, http.StatusForbidden, "Only admins can report on other users")
+		return
+	}
+	h.respondWithTimeReport(w, r, filter, "my")
+}
+
+// respondWithTimeReport writes a time report as JSON or, with ?format=csv,
+// as a CSV download named after prefix
+func (h *WorkLogHandler) respondWithTimeReport(w http.ResponseWriter, r *http.Request, filter repository.TimeReportFilter, prefix string) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = ExportJSON
+	}
+	if format != ExportJSON && format != ExportCSV {
+		respondWithError(w, http.StatusBadRequest, "Invalid format. Use json or csv")
+		return
+	}
+
+	report, err := h.workLogRepo.Report(filter)
+	if err != nil {
+		log.Printf("Error building time report: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to build time report")
+		return
+	}
+	if filter.ProjectID != "" {
+		report.ProjectID = &filter.ProjectID
+	}
+	if filter.UserID != "" {
+		report.UserID = &filter.UserID
+	}
+	if filter.From != nil {
+		from := filter.From.Format("2006-01-02")
+		report.From = &from
+	}
+	if filter.To != nil {
+		to := filter.To.Format("2006-01-02")
+		report.To = &to
+	}
+
+	if format == ExportJSON {
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-time-%s.csv", prefix, time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"key", "title", "status", "estimate_minutes", "logged_minutes"})
+	for _, task := range report.Tasks {
+		estimate := ""
+		if task.EstimateMinutes != nil {
+			estimate = strconv.Itoa(*task.EstimateMinutes)
+		}
+		row := []string{task.Key, task.Title, task.Status, estimate, strconv.Itoa(task.LoggedMinutes)}
+		for i := range row {
+			row[i] = escapeFormula(row[i])
+		}
+		writer.Write(row)
+	}
+	writer.Write([]string{"total", "", "", strconv.Itoa(report.TotalEstimateMinutes), strconv.Itoa(report.TotalLoggedMinutes)})
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("Error writing time report: %v", err)
+	}
+}
+
+// parseTimeReportFilter reads ?from=, ?to= and ?user_id= of a time report.
+// It returns a message describing the first problem, if any.
+func parseTimeReportFilter(r *http.Request) (repository.TimeReportFilter, string) {
+	query := r.URL.Query()
+	var filter repository.TimeReportFilter
+
+	if value := strings.TrimSpace(query.Get("from")); value != "" {
+		from, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return filter, "Invalid from date. Use YYYY-MM-DD"
+		}
+		filter.From = &from
+	}
+	if value := strings.TrimSpace(query.Get("to")); value != "" {
+		to, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return filter, "Invalid to date. Use YYYY-MM-DD"
+		}
+		filter.To = &to
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, "to must not be before from"
+	}
+
+	if value := strings.TrimSpace(query.Get("user_id")); value != "" {
+		if _, err := uuid.Parse(value); err != nil {
+			return filter, "Invalid user_id"
+		}
+		filter.UserID = value
+	}
+	return filter, ""
+}
+
+// canEditWorkLog reports whether a user may change a work log: its author
+// while still in the project, or a PO or PM of the project
+func (h *WorkLogHandler) canEditWorkLog(userID string, workLog *models.WorkLog) bool {
+	if workLog.UserID == userID {
+		return h.hasProjectRole(userID, workLog.ProjectID, []string{models.RolePO, models.RolePM, models.RoleMember})
+	}
+	return h.hasProjectRole(userID, workLog.ProjectID, []string{models.RolePO, models.RolePM})
+}
+
+// loadWorkLog gets a work log. It writes an error response and returns false
+// if there is none.
+func (h *WorkLogHandler) loadWorkLog(w http.ResponseWriter, id string) (*models.WorkLog, bool) {
+	if _, err := uuid.Parse(id); err != nil {
+		respondWithError(w, http.StatusNotFound, "Work log not found")
+		return nil, false
+	}
+	workLog, err := h.workLogRepo.GetWorkLog(id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondWithError(w, http.StatusNotFound, "Work log not found")
+		} else {
+			log.Printf("Error getting work log %s: %v", id, err)
+			respondWithError(w, http.StatusInternalServerError, "Failed to get work log")
+		}
+		return nil, false
+	}
+	return workLog, true
+}
+
+// respondWithWorkLog writes a work log after a change
+func (h *WorkLogHandler) respondWithWorkLog(w http.ResponseWriter, status int, id string) {
+	workLog, err := h.workLogRepo.GetWorkLog(id)
+	if err != nil {
+		log.Printf("Error getting work log %s: %v", id, err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get work log")
+		return
+	}
+	respondWithJSON(w, status, workLog)
+}
+
+// applyWorkLogRequest validates a work log request and copies it onto the
+// work log. It returns a message describing the first problem, if any.
+func applyWorkLogRequest(workLog *models.WorkLog, req *models.WorkLogRequest) string {
+	if req.Minutes <= 0 {
+		return "minutes must be a positive number"
+	}
+	// A day's worth is a generous upper bound and catches unit mix-ups
+	if req.Minutes > 24*60 {
+		return "minutes must not exceed 1440 per entry"
+	}
+	workLog.Minutes = req.Minutes
+	workLog.Note = strings.TrimSpace(req.Note)
+
+	workLog.Date = time.Now()
+	if req.Date != nil && strings.TrimSpace(*req.Date) != "" {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(*req.Date))
+		if err != nil {
+			return "Invalid date format. Use YYYY-MM-DD"
+		}
+		workLog.Date = date
+	}
+	return ""
+}
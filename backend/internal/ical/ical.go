@@ -0,0 +1,88 @@
+// Package ical writes iCalendar (RFC 5545) data: content lines with CRLF
+// endings, folded at 75 octets, and escaped TEXT values.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest a content line may be before it is folded
+const maxLineOctets = 75
+
+// Writer writes the content lines of an iCalendar object. The first write
+// error is kept and returned by Flush.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Begin starts a component such as VCALENDAR or VEVENT
+func (w *Writer) Begin(component string) {
+	w.Line("BEGIN", component)
+}
+
+// End ends a component
+func (w *Writer) End(component string) {
+	w.Line("END", component)
+}
+
+// Line writes a property with a value that is already formatted. name may
+// carry parameters, e.g. "DTSTART;VALUE=DATE".
+func (w *Writer) Line(name, value string) {
+	if w.err != nil {
+		return
+	}
+	line := name + ":" + value
+	for len(line) > maxLineOctets {
+		// Fold without splitting a UTF-8 sequence. Continuation lines start
+		// with a space, which counts towards their length.
+		cut := maxLineOctets
+		for cut > 1 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if _, w.err = w.w.WriteString(line[:cut] + "\r\n"); w.err != nil {
+			return
+		}
+		line = " " + line[cut:]
+	}
+	_, w.err = w.w.WriteString(line + "\r\n")
+}
+
+// Text writes a property with a TEXT value, escaping it
+func (w *Writer) Text(name, value string) {
+	w.Line(name, EscapeText(value))
+}
+
+// Flush writes buffered lines and returns the first error
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// EscapeText escapes a TEXT value: backslashes, semicolons, commas and line
+// breaks
+func EscapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// Date formats a DATE value
+func Date(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// DateTime formats a DATE-TIME value in UTC
+func DateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,434 @@
+// Package importer reads tasks from files exported by other tools.
+//
+// Every format is parsed into Rows holding the source values as text; the
+// caller maps them onto the project (statuses, priorities, assignees) and
+// reports problems per row before anything is written.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"task-management/internal/models"
+)
+
+// Supported formats
+const (
+	FormatCSV    = "csv"
+	FormatTrello = "trello"
+	FormatJira   = "jira"
+)
+
+// Formats lists the supported formats
+var Formats = []string{FormatCSV, FormatTrello, FormatJira}
+
+// Task fields a CSV column can be mapped to
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldStatus      = "status"
+	FieldPriority    = "priority"
+	FieldDueDate     = "due_date"
+	FieldAssignee    = "assignee"
+	FieldLabels      = "labels"
+)
+
+// Fields lists the mappable task fields
+var Fields = []string{FieldTitle, FieldDescription, FieldStatus, FieldPriority, FieldDueDate, FieldAssignee, FieldLabels}
+
+// MaxRows bounds the number of tasks in one import
+const MaxRows = 5000
+
+// Row is one task read from an import file
+type Row struct {
+	// Line is the row's position in the source: the CSV line or the card's index
+	Line        int
+	Title       string
+	Description string
+	Status      string
+	Priority    string
+	DueDate     string
+	// Assignee is an email address; other values can't be matched to members
+	Assignee string
+	Labels   []string
+	// Warnings are problems found while reading the row that don't stop it
+	// from being imported
+	Warnings []string
+}
+
+// Parse reads rows in the given format. mapping (task field -> column
+// header) only applies to CSV; missing entries fall back to a column named
+// like the field.
+func Parse(format string, r io.Reader, mapping map[string]string) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r, mapping)
+	case FormatTrello:
+		return ParseTrello(r)
+	case FormatJira:
+		return ParseJira(r)
+	}
+	return nil, fmt.Errorf("unknown format %q. Must be one of %s", format, strings.Join(Formats, ", "))
+}
+
+// csvAliases are the headers each field is read from when no mapping is given.
+// assignee_email matches the project export so an export can be re-imported.
+var csvAliases = map[string][]string{
+	FieldTitle:       {"title", "name", "summary"},
+	FieldDescription: {"description"},
+	FieldStatus:      {"status"},
+	FieldPriority:    {"priority"},
+	FieldDueDate:     {"due_date", "due date", "due"},
+	FieldAssignee:    {"assignee", "assignee_email", "assignee email"},
+	FieldLabels:      {"labels", "label", "tags"},
+}
+
+// ParseCSV reads a CSV file with a header row
+func ParseCSV(r io.Reader, mapping map[string]string) ([]Row, error) {
+	for field := range mapping {
+		if !contains(Fields, field) {
+			return nil, fmt.Errorf("cannot map to unknown field %q. Must be one of %s", field, strings.Join(Fields, ", "))
+		}
+	}
+
+	return readCSV(r, func(header []string) (map[string][]int, error) {
+		columns := map[string][]int{}
+		for _, field := range Fields {
+			names := csvAliases[field]
+			if column, ok := mapping[field]; ok {
+				names = []string{column}
+			}
+			for _, name := range names {
+				if indexes := findColumns(header, name); len(indexes) > 0 {
+					columns[field] = indexes
+					break
+				}
+			}
+			if _, ok := mapping[field]; ok && len(columns[field]) == 0 {
+				return nil, fmt.Errorf("column %q mapped to %s is not in the file", mapping[field], field)
+			}
+		}
+		return columns, nil
+	})
+}
+
+// jiraColumns are the headers of a Jira issue CSV export. Jira repeats the
+// Labels column once per label.
+var jiraColumns = map[string][]string{
+	FieldTitle:       {"summary"},
+	FieldDescription: {"description"},
+	FieldStatus:      {"status"},
+	FieldPriority:    {"priority"},
+	FieldDueDate:     {"due date", "due"},
+	FieldAssignee:    {"assignee email", "assignee"},
+	FieldLabels:      {"labels"},
+}
+
+// ParseJira reads a Jira issue CSV export
+func ParseJira(r io.Reader) ([]Row, error) {
+	return readCSV(r, func(header []string) (map[string][]int, error) {
+		columns := map[string][]int{}
+		for field, names := range jiraColumns {
+			for _, name := range names {
+				if indexes := findColumns(header, name); len(indexes) > 0 {
+					columns[field] = indexes
+					break
+				}
+			}
+		}
+		return columns, nil
+	})
+}
+
+// readCSV reads the header, resolves the field columns with columnsFor and
+// turns every following record into a Row
+func readCSV(r io.Reader, columnsFor func(header []string) (map[string][]int, error)) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("file is empty")
+		}
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // Excel's byte order mark
+	}
+
+	columns, err := columnsFor(header)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns[FieldTitle]) == 0 {
+		return nil, fmt.Errorf("no title column found. Map one with map.title=<column>")
+	}
+
+	rows := []Row{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if isBlank(record) {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("file has more than %d rows", MaxRows)
+		}
+
+		value := func(field string) string {
+			for _, index := range columns[field] {
+				if index < len(record) && strings.TrimSpace(record[index]) != "" {
+					return unescapeFormula(strings.TrimSpace(record[index]))
+				}
+			}
+			return ""
+		}
+		row := Row{
+			Line:        line,
+			Title:       value(FieldTitle),
+			Description: value(FieldDescription),
+			Status:      value(FieldStatus),
+			Priority:    value(FieldPriority),
+			DueDate:     value(FieldDueDate),
+			Assignee:    value(FieldAssignee),
+		}
+		for _, index := range columns[FieldLabels] {
+			if index < len(record) {
+				row.Labels = append(row.Labels, splitLabels(unescapeFormula(record[index]))...)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// trelloBoard is the part of a Trello board JSON export that is imported
+type trelloBoard struct {
+	Cards []struct {
+		Name      string   `json:"name"`
+		Desc      string   `json:"desc"`
+		Closed    bool     `json:"closed"`
+		IDList    string   `json:"idList"`
+		Due       *string  `json:"due"`
+		IDMembers []string `json:"idMembers"`
+		IDLabels  []string `json:"idLabels"`
+		Pos       float64  `json:"pos"`
+	} `json:"cards"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Labels []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"labels"`
+	Members []struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	} `json:"members"`
+}
+
+// ParseTrello reads a Trello board JSON export. The list a card is in
+// becomes its status; labels named like a priority set the priority.
+// Archived cards and cards in archived lists are skipped.
+func ParseTrello(r io.Reader) ([]Row, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("invalid Trello board JSON: %w", err)
+	}
+
+	lists := map[string]string{}
+	for _, list := range board.Lists {
+		if !list.Closed {
+			lists[list.ID] = list.Name
+		}
+	}
+	labels := map[string]string{}
+	for _, label := range board.Labels {
+		labels[label.ID] = label.Name
+	}
+	members := map[string]string{}
+	for _, member := range board.Members {
+		members[member.ID] = member.Email
+		if member.Email == "" {
+			members[member.ID] = "@" + member.Username
+		}
+	}
+
+	cards := board.Cards
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].Pos < cards[j].Pos })
+
+	rows := []Row{}
+	for i, card := range cards {
+		listName, listOpen := lists[card.IDList]
+		if card.Closed || !listOpen {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("board has more than %d cards", MaxRows)
+		}
+
+		row := Row{
+			Line:        i + 1,
+			Title:       strings.TrimSpace(card.Name),
+			Description: card.Desc,
+			Status:      listName,
+		}
+		if card.Due != nil && *card.Due != "" {
+			row.DueDate = *card.Due
+		}
+		for _, id := range card.IDLabels {
+			name := strings.TrimSpace(labels[id])
+			if name == "" {
+				continue
+			}
+			if _, ok := MapPriority(name, nil); ok && row.Priority == "" {
+				row.Priority = name
+				continue
+			}
+			row.Labels = append(row.Labels, name)
+		}
+		// Trello only has one assignee field here; extra members are noted
+		for n, id := range card.IDMembers {
+			if n == 0 {
+				row.Assignee = members[id]
+			} else {
+				row.Warnings = append(row.Warnings, fmt.Sprintf("member %s not assigned: tasks have a single assignee", members[id]))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// statusAliases maps common status names of other tools onto task statuses
+var statusAliases = map[string]string{
+	"todo": models.StatusTodo, "to do": models.StatusTodo, "open": models.StatusTodo, "new": models.StatusTodo,
+	"backlog": models.StatusTodo, "selected for development": models.StatusTodo, "reopened": models.StatusTodo,
+	"in-progress": models.StatusInProgress, "in progress": models.StatusInProgress, "doing": models.StatusInProgress,
+	"in review": models.StatusInProgress, "review": models.StatusInProgress, "testing": models.StatusInProgress,
+	"done": models.StatusDone, "closed": models.StatusDone, "resolved": models.StatusDone,
+	"complete": models.StatusDone, "completed": models.StatusDone,
+}
+
+// priorityAliases maps common priority names onto task priorities
+var priorityAliases = map[string]string{
+	"lowest": models.PriorityLow, "low": models.PriorityLow, "minor": models.PriorityLow, "trivial": models.PriorityLow,
+	"medium": models.PriorityMedium, "normal": models.PriorityMedium, "major": models.PriorityMedium,
+	"high": models.PriorityHigh, "highest": models.PriorityHigh, "critical": models.PriorityHigh,
+	"blocker": models.PriorityHigh, "urgent": models.PriorityHigh,
+}
+
+// MapStatus maps a source status onto a task status. overrides (source value
+// -> status, case-insensitive) take precedence over the built-in aliases; an
+// empty value is todo.
+func MapStatus(value string, overrides map[string]string) (string, bool) {
+	return mapValue(value, overrides, statusAliases, models.StatusTodo)
+}
+
+// MapPriority maps a source priority onto low, medium or high. An empty
+// value is medium.
+func MapPriority(value string, overrides map[string]string) (string, bool) {
+	return mapValue(value, overrides, priorityAliases, models.PriorityMedium)
+}
+
+func mapValue(value string, overrides, aliases map[string]string, empty string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(value))
+	if key == "" {
+		return empty, true
+	}
+	for source, target := range overrides {
+		if strings.ToLower(strings.TrimSpace(source)) == key {
+			return target, true
+		}
+	}
+	target, ok := aliases[key]
+	return target, ok
+}
+
+// dateLayouts are the due date formats accepted, ours first, then the ones
+// Trello (RFC 3339) and Jira ("02/Jan/06 3:04 PM") export
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"02/Jan/06 3:04 PM",
+	"02/Jan/06",
+	"2/Jan/06 3:04 PM",
+	"2/Jan/06",
+}
+
+// ParseDate parses a due date and returns the calendar day it falls on
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid due date %q. Use YYYY-MM-DD", value)
+}
+
+// findColumns returns the indexes of every column named name (case-insensitive)
+func findColumns(header []string, name string) []int {
+	var indexes []int
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), name) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// splitLabels splits a cell holding several labels separated by ";" or ","
+func splitLabels(cell string) []string {
+	var labels []string
+	for _, label := range strings.FieldsFunc(cell, func(r rune) bool { return r == ';' || r == ',' }) {
+		if label = strings.TrimSpace(label); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// unescapeFormula undoes the quote the CSV export puts in front of cells
+// that look like spreadsheet formulas
+func unescapeFormula(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
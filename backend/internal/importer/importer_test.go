@@ -0,0 +1,197 @@
+package importer
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"task-management/internal/models"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "\ufeffName,Status,Priority,Due,Assignee_Email,Labels,Notes\n" +
+		"Ship it,In Progress,High,2026-11-02,dana@example.com,web; launch,ignored\n" +
+		",,,,,,\n" +
+		"'=SUM(A1),done,,,,\"a,b\",\n" +
+		"Short row\n"
+
+	rows, err := ParseCSV(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	want := []Row{
+		{Line: 2, Title: "Ship it", Status: "In Progress", Priority: "High", DueDate: "2026-11-02",
+			Assignee: "dana@example.com", Labels: []string{"web", "launch"}},
+		// The blank line 3 is skipped, and the export's formula quote removed
+		{Line: 4, Title: "=SUM(A1)", Status: "done", Labels: []string{"a", "b"}},
+		{Line: 5, Title: "Short row"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ParseCSV =\n%+v\nwant\n%+v", rows, want)
+	}
+}
+
+func TestParseCSVMapping(t *testing.T) {
+	input := "Task,State,Title\nFrom Task,open,From Title\n"
+
+	rows, err := ParseCSV(strings.NewReader(input), map[string]string{"title": "task", "status": "State"})
+	if err != nil {
+		t.Fatalf("ParseCSV: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Title != "From Task" || rows[0].Status != "open" {
+		t.Errorf("ParseCSV with a mapping = %+v", rows)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		mapping map[string]string
+	}{
+		{"unknown field", input, map[string]string{"owner": "Task"}},
+		{"mapped column missing", input, map[string]string{"title": "Summary"}},
+		{"no title column", "State\nopen\n", nil},
+		{"empty file", "", nil},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCSV(strings.NewReader(tt.input), tt.mapping); err == nil {
+			t.Errorf("%s: ParseCSV accepted the file", tt.name)
+		}
+	}
+}
+
+func TestParseCSVMaxRows(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("title\n")
+	for i := 0; i <= MaxRows; i++ {
+		b.WriteString("task\n")
+	}
+	if _, err := ParseCSV(strings.NewReader(b.String()), nil); err == nil {
+		t.Errorf("ParseCSV accepted more than %d rows", MaxRows)
+	}
+}
+
+func TestParseJira(t *testing.T) {
+	// Jira repeats the Labels column once per label
+	input := "Summary,Issue key,Status,Priority,Assignee,Labels,Labels,Labels,Due Date\n" +
+		"Fix login,WEB-1,Selected for Development,Blocker,dana@example.com,auth,,urgent,02/Nov/26 5:00 PM\n"
+
+	rows, err := ParseJira(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseJira: %v", err)
+	}
+	want := []Row{{Line: 2, Title: "Fix login", Status: "Selected for Development", Priority: "Blocker",
+		Assignee: "dana@example.com", Labels: []string{"auth", "urgent"}, DueDate: "02/Nov/26 5:00 PM"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ParseJira =\n%+v\nwant\n%+v", rows, want)
+	}
+
+	if _, err := ParseJira(strings.NewReader("Issue key,Status\nWEB-1,Done\n")); err == nil {
+		t.Error("ParseJira accepted a file without a Summary column")
+	}
+}
+
+func TestParseTrello(t *testing.T) {
+	file, err := os.Open("testdata/trello_board.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := ParseTrello(file)
+	if err != nil {
+		t.Fatalf("ParseTrello: %v", err)
+	}
+	// Cards are in board order; archived cards and lists are skipped but
+	// still count for the line
+	want := []Row{
+		{Line: 3, Title: "Write copy", Status: "To Do"},
+		{Line: 4, Title: "Ship the landing page", Description: "Hero and pricing", Status: "Doing",
+			Priority: "High", DueDate: "2026-11-02T17:00:00.000Z", Assignee: "dana@example.com", Labels: []string{"web"},
+			Warnings: []string{"member @sam not assigned: tasks have a single assignee"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("ParseTrello =\n%+v\nwant\n%+v", rows, want)
+	}
+
+	if _, err := ParseTrello(strings.NewReader(`{"cards": {}}`)); err == nil {
+		t.Error("ParseTrello accepted an invalid board")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("asana", strings.NewReader(""), nil); err == nil {
+		t.Error("Parse accepted an unknown format")
+	}
+	rows, err := Parse(FormatCSV, strings.NewReader("title\nA\n"), nil)
+	if err != nil || len(rows) != 1 {
+		t.Errorf("Parse(csv) = %+v, %v", rows, err)
+	}
+}
+
+func TestMapStatusAndPriority(t *testing.T) {
+	overrides := map[string]string{" QA ": models.StatusInProgress, "Done": models.StatusTodo}
+	statuses := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", models.StatusTodo, true},
+		{"To Do", models.StatusTodo, true},
+		{" in progress ", models.StatusInProgress, true},
+		{"Resolved", models.StatusDone, true},
+		{"qa", models.StatusInProgress, true},
+		// Overrides win over the aliases
+		{"done", models.StatusTodo, true},
+		{"Someday", "", false},
+	}
+	for _, tt := range statuses {
+		if got, ok := MapStatus(tt.value, overrides); got != tt.want || ok != tt.ok {
+			t.Errorf("MapStatus(%q) = %q, %v; want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	priorities := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{"", models.PriorityMedium, true},
+		{"Lowest", models.PriorityLow, true},
+		{"Major", models.PriorityMedium, true},
+		{"BLOCKER", models.PriorityHigh, true},
+		{"P1", "", false},
+	}
+	for _, tt := range priorities {
+		if got, ok := MapPriority(tt.value, nil); got != tt.want || ok != tt.ok {
+			t.Errorf("MapPriority(%q) = %q, %v; want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	for _, value := range []string{
+		"2026-11-02",
+		" 2026-11-02 ",
+		"2026-11-02T17:00:00Z",
+		"2026-11-02T23:30:00-05:00",
+		"2026-11-02 17:00",
+		"2026-11-02 17:00:05",
+		"02/Nov/26 5:00 PM",
+		"02/Nov/26",
+		"2/Nov/26 5:00 PM",
+		"2/Nov/26",
+	} {
+		if got, err := ParseDate(value); err != nil || !got.Equal(want) {
+			t.Errorf("ParseDate(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "11/02/2026", "tomorrow", "2026-02-30"} {
+		if _, err := ParseDate(value); err == nil {
+			t.Errorf("ParseDate(%q) accepted an invalid date", value)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"task-management/internal/jobs"
+	"task-management/internal/repository"
+)
+
+// JobTypeImport is the job that imports the tasks of a large file
+const JobTypeImport = "import-tasks"
+
+// NewImportJob returns the job that runs a queued import. A failed import is
+// recorded on the import so it can be shown to the user.
+func NewImportJob(importRepo *repository.ImportRepository) jobs.JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p struct {
+			ImportID string `json:"import_id"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return fmt.Errorf("invalid import payload: %w", err)
+		}
+
+		if err := importRepo.RunImport(p.ImportID); err != nil {
+			log.Printf("[IMPORT] Import %s failed: %v", p.ImportID, err)
+			if failErr := importRepo.FailImport(p.ImportID, "Import failed; no tasks were created"); failErr != nil {
+				log.Printf("[IMPORT] Error recording failure of import %s: %v", p.ImportID, failErr)
+			}
+			return err
+		}
+		log.Printf("[IMPORT] Import %s finished", p.ImportID)
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+// bits is the bit set of the given values
+func bits(values ...int) uint64 {
+	var set uint64
+	for _, v := range values {
+		set |= 1 << uint(v)
+	}
+	return set
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     uint64
+	}{
+		{"*", 0, 6, bits(0, 1, 2, 3, 4, 5, 6)},
+		{"5", 0, 59, bits(5)},
+		{"1-5", 0, 6, bits(1, 2, 3, 4, 5)},
+		{"1,15", 1, 31, bits(1, 15)},
+		{"*/15", 0, 59, bits(0, 15, 30, 45)},
+		{"0-30/10", 0, 59, bits(0, 10, 20, 30)},
+		{"1-5/2", 0, 6, bits(1, 3, 5)},
+		// A start with a step runs to the end of the range
+		{"10/15", 0, 59, bits(10, 25, 40, 55)},
+		{"1-3,8,20-22", 0, 23, bits(1, 2, 3, 8, 20, 21, 22)},
+		{"*/5", 1, 12, bits(1, 6, 11)},
+		{"0,59", 0, 59, bits(0, 59)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parseCronField(tt.field, tt.min, tt.max)
+			if err != nil {
+				t.Fatalf("parseCronField(%q): %v", tt.field, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseCronField(%q) = %b, want %b", tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronFieldErrors(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+	}{
+		{"60", 0, 59},
+		{"24", 0, 23},
+		{"0", 1, 31},
+		{"32", 1, 31},
+		{"13", 1, 12},
+		{"7", 0, 6},
+		{"-1", 0, 59},
+		{"5-1", 0, 59},
+		{"50-60", 0, 59},
+		{"*/0", 0, 59},
+		{"*/x", 0, 59},
+		{"1-", 0, 59},
+		{"a", 0, 59},
+		{"1,,2", 0, 59},
+		{"", 0, 59},
+	}
+	for _, tt := range tests {
+		if got, err := parseCronField(tt.field, tt.min, tt.max); err == nil {
+			t.Errorf("parseCronField(%q, %d, %d) = %b, want an error", tt.field, tt.min, tt.max, got)
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	c, err := parseCron("0 8 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.minute != bits(0) || c.hour != bits(8) || c.dow != bits(1, 2, 3, 4, 5) || !c.anyDOM || c.anyDOW {
+		t.Errorf("parseCron = %+v", c)
+	}
+
+	for _, spec := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 7"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q) accepted an invalid expression", spec)
+		}
+	}
+}
+
+func date(day string, hour, minute int) time.Time {
+	t, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func TestDayMatches(t *testing.T) {
+	// 2026-10-13 is a Tuesday, 2026-11-13 a Friday
+	tests := []struct {
+		spec string
+		day  string
+		want bool
+	}{
+		{"0 0 13 * 5", "2026-11-13", true},
+		{"0 0 13 * 5", "2026-10-23", true},
+		{"0 0 13 * 5", "2026-10-13", true},
+		{"0 0 13 * 5", "2026-10-12", false},
+		{"0 0 13 * *", "2026-10-13", true},
+		{"0 0 13 * *", "2026-10-23", false},
+		{"0 0 * * 5", "2026-10-23", true},
+		{"0 0 * * 5", "2026-10-13", false},
+		// Not "the first Monday": either the 1st to 7th or any Monday
+		{"0 0 1-7 * 1", "2026-10-07", true},
+		{"0 0 1-7 * 1", "2026-10-26", true},
+		{"0 0 1-7 * 1", "2026-10-27", false},
+		{"0 0 * * *", "2026-10-27", true},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.dayMatches(date(tt.day, 0, 0)); got != tt.want {
+			t.Errorf("%q dayMatches %s = %v, want %v", tt.spec, tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"next step", "*/15 * * * *", date("2026-10-18", 10, 7), date("2026-10-18", 10, 15)},
+		{"strictly after", "*/15 * * * *", date("2026-10-18", 10, 15), date("2026-10-18", 10, 30)},
+		{"seconds are dropped", "*/15 * * * *", date("2026-10-18", 10, 14).Add(59 * time.Second), date("2026-10-18", 10, 15)},
+		{"next hour", "0 * * * *", date("2026-10-18", 23, 0), date("2026-10-19", 0, 0)},
+		{"next day", "0 8 * * *", date("2026-10-18", 9, 0), date("2026-10-19", 8, 0)},
+		{"across a month", "0 8 * * *", date("2026-01-31", 9, 0), date("2026-02-01", 8, 0)},
+		{"end of february", "59 23 * * *", date("2026-02-28", 23, 59), date("2026-03-01", 23, 59)},
+		{"across a year", "0 0 1 * *", date("2026-12-15", 0, 0), date("2027-01-01", 0, 0)},
+		{"once a year", "30 23 31 12 *", date("2026-12-31", 23, 30), date("2027-12-31", 23, 30)},
+		{"skips short months", "0 0 31 * *", date("2026-04-01", 0, 0), date("2026-05-31", 0, 0)},
+		{"leap day", "0 0 29 2 *", date("2026-03-01", 0, 0), date("2028-02-29", 0, 0)},
+		{"weekdays", "0 9 * * 1-5", date("2026-10-23", 10, 0), date("2026-10-26", 9, 0)},
+		{"day of month or week", "0 9 13 * 5", date("2026-10-18", 0, 0), date("2026-10-23", 9, 0)},
+		{"never", "0 0 31 2 *", date("2026-01-01", 0, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := parseCron(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("%q next(%v) = %v, want %v", tt.spec, tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"task-management/internal/repository"
+)
+
+// NewPurgeJob returns a job that permanently removes users, projects and
+// tasks that have been in the trash for longer than retention
+func NewPurgeJob(userRepo *repository.UserRepository, projectRepo *repository.ProjectRepository, taskRepo *repository.TaskRepository, retention time.Duration) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		before := time.Now().Add(-retention)
+
+		tasks, err := taskRepo.PurgeDeletedTasks(before)
+		if err != nil {
+			return err
+		}
+
+		projects, err := projectRepo.PurgeDeletedProjects(before)
+		if err != nil {
+			return err
+		}
+
+		users, err := userRepo.PurgeDeletedUsers(before)
+		if err != nil {
+			return err
+		}
+
+		if tasks+projects+users > 0 {
+			log.Printf("[JOBS] Purged %d task(s), %d project(s) and %d user(s) deleted before %s",
+				tasks, projects, users, before.Format(time.RFC3339))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"task-management/internal/repository"
+)
+
+// NewRankRebalanceJob returns a job that renumbers board columns whose task
+// ranks have been split so often that they are about to run out of room
+func NewRankRebalanceJob(taskRepo *repository.TaskRepository) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		columns, err := taskRepo.RebalanceRanks()
+		if columns > 0 {
+			log.Printf("[JOBS] Rebalanced task ranks in %d column(s)", columns)
+		}
+		return err
+	}
+}
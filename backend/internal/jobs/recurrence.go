@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"task-management/internal/repository"
+)
+
+// NewRecurrenceJob returns a job that creates the next occurrence of every
+// recurring task once its due date is within window
+func NewRecurrenceJob(recurrenceRepo *repository.RecurrenceRepository, window time.Duration) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		created, err := recurrenceRepo.CreateDueOccurrences(window)
+		if err != nil {
+			return err
+		}
+		if created > 0 {
+			log.Printf("[JOBS] Created %d recurring task occurrence(s)", created)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"task-management/internal/due"
+	"task-management/internal/repository"
+)
+
+// Job types enqueued by other jobs
+const (
+	JobTypeSendDigest = "send-digest"
+)
+
+// NewDueSoonJob returns a job that reminds watchers about open tasks that
+// become due within window. Each watcher is reminded once per due date.
+func NewDueSoonJob(notificationRepo *repository.NotificationRepository, window time.Duration) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		created, err := notificationRepo.CreateDueSoonReminders(window)
+		if err != nil {
+			return err
+		}
+		if created > 0 {
+			log.Printf("[JOBS] Sent %d due date reminder(s)", created)
+		}
+		return nil
+	}
+}
+
+// NewOverdueJob returns a job that alerts watchers once about each open task
+// that is past due
+func NewOverdueJob(notificationRepo *repository.NotificationRepository) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		created, err := notificationRepo.CreateOverdueAlerts()
+		if err != nil {
+			return err
+		}
+		if created > 0 {
+			log.Printf("[JOBS] Sent %d overdue alert(s)", created)
+		}
+		return nil
+	}
+}
+
+type digestPayload struct {
+	UserID string `json:"user_id"`
+	Day    string `json:"day"` // YYYY-MM-DD in the user's timezone
+	// Timezone is the user's timezone; the server's if empty
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// NewDigestJob returns a job that queues one send-digest job per user with
+// open assigned tasks, so a failure for one user is retried on its own. It
+// runs hourly and picks the users for whom it is the given hour, so each
+// digest arrives in the user's own morning and is for their day.
+func NewDigestJob(notificationRepo *repository.NotificationRepository, scheduler *Scheduler, hour int) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		recipients, err := notificationRepo.GetDigestRecipients()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, recipient := range recipients {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			local := now.In(due.Location(recipient.Timezone))
+			if local.Hour() != hour {
+				continue
+			}
+			day := local.Format(due.DateLayout)
+			key := fmt.Sprintf("%s:%s:%s", JobTypeSendDigest, recipient.UserID, day)
+			p := digestPayload{UserID: recipient.UserID, Day: day, Timezone: recipient.Timezone}
+			if err := scheduler.Enqueue(JobTypeSendDigest, p, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// NewSendDigestJob returns the job that creates one user's daily digest
+func NewSendDigestJob(notificationRepo *repository.NotificationRepository) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p digestPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return fmt.Errorf("invalid digest payload: %w", err)
+		}
+		loc := time.Local
+		if p.Timezone != "" {
+			loc = due.Location(p.Timezone)
+		}
+		day, err := time.ParseInLocation(due.DateLayout, p.Day, loc)
+		if err != nil {
+			return fmt.Errorf("invalid digest day: %w", err)
+		}
+		_, err = notificationRepo.CreateDigest(p.UserID, day)
+		return err
+	}
+}
+
+// NewJobCleanupJob returns a job that deletes finished jobs older than retention
+func NewJobCleanupJob(jobRepo *repository.JobRepository, retention time.Duration) JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		_, err := jobRepo.DeleteFinished(time.Now().Add(-retention))
+		return err
+	}
+}
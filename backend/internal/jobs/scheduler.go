@@ -0,0 +1,247 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"task-management/internal/repository"
+)
+
+// JobFunc is a unit of background work. payload is the JSON the job was
+// enqueued with. It should return promptly once ctx is cancelled.
+type JobFunc func(ctx context.Context, payload json.RawMessage) error
+
+const (
+	// defaultMaxAttempts is how often a job runs before it is dead-lettered
+	defaultMaxAttempts = 5
+	// lockTimeout bounds a single run; jobs running longer are presumed lost
+	// and handed to another worker
+	lockTimeout = 10 * time.Minute
+	// pollInterval is how long an idle worker waits before looking for work
+	pollInterval = 2 * time.Second
+	// cronInterval is how often periodic jobs are checked for a due run
+	cronInterval = 15 * time.Second
+	maxBackoff   = time.Hour
+)
+
+type cronJob struct {
+	jobType  string
+	schedule *cronSchedule
+	next     time.Time
+}
+
+// Scheduler runs background jobs from the jobs table. Any number of server
+// replicas can run one: workers claim jobs with SKIP LOCKED, and periodic
+// runs are enqueued under a key derived from their scheduled time, so each
+// run is queued once however many replicas see it.
+type Scheduler struct {
+	jobRepo  *repository.JobRepository
+	workerID string
+	workers  int
+	handlers map[string]JobFunc
+	cron     []*cronJob
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler with the given number of workers
+func NewScheduler(jobRepo *repository.JobRepository, workers int) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		jobRepo:  jobRepo,
+		workerID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		workers:  workers,
+		handlers: map[string]JobFunc{},
+	}
+}
+
+// Handle registers the function that runs jobs of jobType
+func (s *Scheduler) Handle(jobType string, run JobFunc) {
+	s.handlers[jobType] = run
+}
+
+// Cron registers run as jobType and enqueues it on the cron schedule spec,
+// evaluated in the server's local time
+func (s *Scheduler) Cron(jobType, spec string, run JobFunc) error {
+	schedule, err := parseCron(spec)
+	if err != nil {
+		return err
+	}
+	s.Handle(jobType, run)
+	s.cron = append(s.cron, &cronJob{jobType: jobType, schedule: schedule})
+	return nil
+}
+
+// Enqueue queues a job to run as soon as a worker is free. See
+// JobRepository.Enqueue for dedupeKey.
+func (s *Scheduler) Enqueue(jobType string, payload interface{}, dedupeKey string) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	_, err = s.jobRepo.Enqueue(jobType, data, time.Now(), defaultMaxAttempts, dedupeKey)
+	return err
+}
+
+// Start launches the workers and the cron and maintenance loops
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	// A run that was due shortly before start (e.g. during a deploy) is still queued
+	startedAt := time.Now().Add(-time.Minute)
+	for _, job := range s.cron {
+		job.next = job.schedule.next(startedAt)
+	}
+
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go func(worker int) {
+			defer s.wg.Done()
+			s.work(ctx, fmt.Sprintf("%s/%d", s.workerID, worker))
+		}(i)
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.tick(ctx)
+	}()
+
+	log.Printf("[JOBS] Scheduler started with %d worker(s) and %d periodic job(s)", s.workers, len(s.cron))
+}
+
+// tick enqueues due periodic jobs and requeues jobs abandoned by crashed workers
+func (s *Scheduler) tick(ctx context.Context) {
+	ticker := time.NewTicker(cronInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		for _, job := range s.cron {
+			if job.next.IsZero() || job.next.After(now) {
+				continue
+			}
+			key := fmt.Sprintf("cron:%s:%s", job.jobType, job.next.UTC().Format(time.RFC3339))
+			if _, err := s.jobRepo.Enqueue(job.jobType, nil, job.next, defaultMaxAttempts, key); err != nil {
+				log.Printf("[JOBS] Failed to schedule %s: %v", job.jobType, err)
+				continue
+			}
+			// Runs missed while the server was down are not made up; the jobs catch up themselves
+			job.next = job.schedule.next(now)
+		}
+
+		if n, err := s.jobRepo.RequeueStale(lockTimeout); err != nil {
+			log.Printf("[JOBS] %v", err)
+		} else if n > 0 {
+			log.Printf("[JOBS] Requeued %d job(s) from unresponsive workers", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work claims and runs jobs until ctx is cancelled
+func (s *Scheduler) work(ctx context.Context, workerID string) {
+	for {
+		job, err := s.jobRepo.Claim(workerID)
+		if err != nil {
+			log.Printf("[JOBS] %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		err = s.run(ctx, job.Type, job.Payload)
+		switch {
+		case err == nil:
+			err = s.jobRepo.Complete(job.ID)
+		case ctx.Err() != nil:
+			// Interrupted by shutdown: another worker picks it up again
+			err = s.jobRepo.Release(job.ID)
+		default:
+			if job.Attempts >= job.MaxAttempts {
+				log.Printf("[JOBS] Job %s (%s) failed for good after %d attempt(s): %v", job.ID, job.Type, job.Attempts, err)
+			} else {
+				log.Printf("[JOBS] Job %s (%s) failed on attempt %d: %v", job.ID, job.Type, job.Attempts, err)
+			}
+			err = s.jobRepo.Fail(job.ID, err, time.Now().Add(backoff(job.Attempts)))
+		}
+		if err != nil {
+			log.Printf("[JOBS] %v", err)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// run calls the handler for jobType, turning a panic into an error
+func (s *Scheduler) run(ctx context.Context, jobType string, payload json.RawMessage) (err error) {
+	handler, ok := s.handlers[jobType]
+	if !ok {
+		return fmt.Errorf("no handler registered for job type %q", jobType)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, lockTimeout)
+	defer cancel()
+	return handler(ctx, payload)
+}
+
+// backoff returns the delay before retrying a job that failed attempt times:
+// 30s, 1m, 2m, ... up to maxBackoff, with some jitter so failed jobs don't
+// retry in lockstep
+func backoff(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay/5)+1))
+}
+
+// Stop cancels all running jobs and waits for them to return or for ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("[JOBS] Scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
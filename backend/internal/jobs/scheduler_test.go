@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		// 64 minutes is over the cap
+		{8, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		// Jitter adds up to a fifth of the delay
+		for i := 0; i < 20; i++ {
+			if got := backoff(tt.attempt); got < tt.base || got > tt.base+tt.base/5 {
+				t.Fatalf("backoff(%d) = %v, want %v to %v", tt.attempt, got, tt.base, tt.base+tt.base/5)
+			}
+		}
+	}
+}
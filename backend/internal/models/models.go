@@ -1,15 +1,24 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
 
 //User model
 type User struct {
-	ID           string    `json:"id" db:"id"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	Name         string    `json:"name" db:"name"`
-	SystemRole   string    `json:"system_role" db:"system_role"` // 'admin' or 'user'
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	ID           string     `json:"id" db:"id"`
+	Email        string     `json:"email" db:"email"`
+	PasswordHash string     `json:"-" db:"password_hash"`
+	Name         string     `json:"name" db:"name"`
+	SystemRole   string     `json:"system_role" db:"system_role"` // 'admin' or 'user'
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	// Timezone is an IANA name such as "Asia/Bangkok" that due dates are seen in
+	Timezone string `json:"timezone" db:"timezone"`
 }
 
 //Project model
@@ -17,8 +26,78 @@ type Project struct {
 	ID          string     `json:"id" db:"id"`
 	Name        string     `json:"name" db:"name"`
 	Description string     `json:"description" db:"description"`
+	Key         string     `json:"key" db:"key"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   *time.Time `json:"updated_at" db:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	// ArchivedAt is set while the project is archived and read-only
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	// Version increases with every change and is the project's ETag
+	Version int `json:"version" db:"version"`
+}
+
+var (
+	projectKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}$`)
+	taskKeyPattern    = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]{1,9})-([1-9][0-9]{0,9})$`)
+)
+
+//NormalizeProjectKey uppercases a project key and reports whether it is a letter followed by 1-9 letters or digits
+func NormalizeProjectKey(key string) (string, bool) {
+	key = strings.ToUpper(strings.TrimSpace(key))
+	return key, projectKeyPattern.MatchString(key)
+}
+
+//ProjectKeyFromName suggests a project key: the initials of a multi-word name ("Web Shop" -> "WS") or the start of one word
+func ProjectKeyFromName(name string) string {
+	words := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	key := ""
+	if len(words) > 1 {
+		for _, word := range words {
+			key += word[:1]
+		}
+	} else if len(words) == 1 {
+		key = words[0]
+	}
+	if len(key) > 4 {
+		key = key[:4]
+	}
+	if !projectKeyPattern.MatchString(key) {
+		return "PRJ"
+	}
+	return key
+}
+
+//ParseTaskKey splits a task key such as "WEB-42" into the project key and task number
+func ParseTaskKey(ref string) (string, int, bool) {
+	match := taskKeyPattern.FindStringSubmatch(strings.TrimSpace(ref))
+	if match == nil {
+		return "", 0, false
+	}
+	number, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.ToUpper(match[1]), number, true
+}
+
+//Project roles, stored in lowercase
+const (
+	RolePO     = "po"
+	RolePM     = "pm"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+//NormalizeRole returns the canonical form of a project role and whether it is valid
+func NormalizeRole(role string) (string, bool) {
+	role = strings.ToLower(strings.TrimSpace(role))
+	switch role {
+	case RolePO, RolePM, RoleMember, RoleViewer:
+		return role, true
+	}
+	return role, false
 }
 
 //ProjectMember model
@@ -34,8 +113,13 @@ type ProjectMember struct {
 
 //Task model
 type Task struct {
-	ID            string     `json:"id" db:"id"`
-	ProjectID     string     `json:"project_id" db:"project_id"`
+	ID        string `json:"id" db:"id"`
+	ProjectID string `json:"project_id" db:"project_id"`
+	// ProjectName is the name of the task's project
+	ProjectName string `json:"project_name,omitempty"`
+	// Key is the project key and the task's number in the project, e.g. WEB-42
+	Key           string     `json:"key" db:"key"`
+	Number        int        `json:"number" db:"number"`
 	UserID        string     `json:"user_id" db:"user_id"`
 	Title         string     `json:"title" db:"title"`
 	Description   string     `json:"description" db:"description"`
@@ -47,6 +131,216 @@ type Task struct {
 	AssigneeEmail *string    `json:"assignee_email,omitempty" db:"assignee_email"`
 	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt     *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	// CustomFields holds values of the project's custom fields keyed by field ID
+	CustomFields map[string]interface{} `json:"custom_fields" db:"custom_fields"`
+	Labels       []string               `json:"labels" db:"labels"`
+	// Recurring tasks belong to a series; RecurrenceIndex counts occurrences from 0
+	RecurrenceID    *string `json:"recurrence_id,omitempty" db:"recurrence_id"`
+	RecurrenceIndex *int    `json:"recurrence_index,omitempty" db:"recurrence_index"`
+	RecurrenceRule  *string `json:"recurrence_rule,omitempty" db:"recurrence_rule"`
+	// SprintID is the task's sprint; tasks without one are in the backlog
+	SprintID *string `json:"sprint_id,omitempty" db:"sprint_id"`
+	// MilestoneID is the milestone the task counts towards, if any
+	MilestoneID *string `json:"milestone_id,omitempty" db:"milestone_id"`
+	// Rank orders the task within its status column, lowest first
+	Rank float64 `json:"rank" db:"rank"`
+	// Version increases with every change and is the task's ETag
+	Version int `json:"version" db:"version"`
+	// EstimateMinutes is the estimated effort; LoggedMinutes totals the work logs
+	EstimateMinutes *int `json:"estimate_minutes,omitempty" db:"estimate_minutes"`
+	LoggedMinutes   int  `json:"logged_minutes"`
+	// DueAllDay means DueDate is a calendar day (midnight UTC) rather than a moment
+	DueAllDay bool `json:"due_all_day" db:"due_all_day"`
+}
+
+//Task statuses
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in-progress"
+	StatusDone       = "done"
+)
+
+//IsValidStatus reports whether status is one of the task statuses
+func IsValidStatus(status string) bool {
+	return status == StatusTodo || status == StatusInProgress || status == StatusDone
+}
+
+//Task priorities
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
+
+//IsValidPriority reports whether priority is one of the task priorities
+func IsValidPriority(priority string) bool {
+	return priority == PriorityLow || priority == PriorityMedium || priority == PriorityHigh
+}
+
+//TaskActivity is an entry in a task's activity log
+type TaskActivity struct {
+	ID        string                 `json:"id" db:"id"`
+	TaskID    string                 `json:"task_id" db:"task_id"`
+	ActorID   *string                `json:"actor_id,omitempty" db:"actor_id"`
+	Type      string                 `json:"type" db:"type"`
+	Message   string                 `json:"message" db:"message"`
+	Data      map[string]interface{} `json:"data" db:"data"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+}
+
+//Task activity types
+const (
+	ActivityCommit = "commit"
+	ActivityMoved  = "moved"
+	ActivityCopied = "copied"
+)
+
+//Custom field types
+const (
+	FieldTypeText         = "text"
+	FieldTypeNumber       = "number"
+	FieldTypeDate         = "date"
+	FieldTypeSingleSelect = "single_select"
+	FieldTypeMultiSelect  = "multi_select"
+	FieldTypeUser         = "user"
+)
+
+//CustomField is a project-defined field stored on every task of the project
+type CustomField struct {
+	ID         string                `json:"id" db:"id"`
+	ProjectID  string                `json:"project_id" db:"project_id"`
+	Name       string                `json:"name" db:"name"`
+	Type       string                `json:"type" db:"field_type"`
+	Options    []string              `json:"options" db:"options"` // choices for select types
+	Required   bool                  `json:"required" db:"required"`
+	Validation CustomFieldValidation `json:"validation" db:"validation"`
+	Position   int                   `json:"position" db:"position"`
+	CreatedAt  time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt  *time.Time            `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+//CustomFieldValidation holds optional rules applied to custom field values
+type CustomFieldValidation struct {
+	Min       *float64 `json:"min,omitempty"`        // number: lowest allowed value
+	Max       *float64 `json:"max,omitempty"`        // number: highest allowed value
+	MaxLength *int     `json:"max_length,omitempty"` // text: longest allowed value
+	Pattern   string   `json:"pattern,omitempty"`    // text: regular expression the value must match
+}
+
+//Background job states
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead" // gave up after MaxAttempts
+)
+
+//Job is a unit of background work queued in the jobs table
+type Job struct {
+	ID          string          `json:"id" db:"id"`
+	Type        string          `json:"type" db:"type"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"`
+	LockedBy    *string         `json:"locked_by,omitempty" db:"locked_by"`
+	LastError   *string         `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+//Notification types
+const (
+	NotificationDueSoon = "due_soon"
+	NotificationOverdue = "overdue"
+	NotificationDigest  = "digest"
+	// Sent to a task's watchers when someone else changes it
+	NotificationTaskCreated  = "task_created"
+	NotificationTaskUpdated  = "task_updated"
+	NotificationTaskDeleted  = "task_deleted"
+	NotificationTaskRestored = "task_restored"
+	NotificationTaskMoved    = "task_moved"
+)
+
+//TaskWatcher is a user subscribed to a task's notifications
+type TaskWatcher struct {
+	TaskID    string    `json:"task_id" db:"task_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Muted     bool      `json:"muted" db:"muted"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type WatchRequest struct {
+	// Muted keeps the subscription without notifications; omitted keeps the current setting
+	Muted *bool `json:"muted"`
+}
+
+//Notification is an in-app message for a user
+type Notification struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"`
+	TaskID    *string    `json:"task_id,omitempty" db:"task_id"`
+	ProjectID *string    `json:"project_id,omitempty" db:"project_id"`
+	Type      string     `json:"type" db:"type"`
+	Title     string     `json:"title" db:"title"`
+	Body      string     `json:"body" db:"body"`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+//Webhook is an outgoing HTTP endpoint notified about project events
+type Webhook struct {
+	ID        string   `json:"id" db:"id"`
+	ProjectID string   `json:"project_id" db:"project_id"`
+	URL       string   `json:"url" db:"url"`
+	Events    []string `json:"events" db:"events"` // event types, or "*" for all
+	// Secret signs payloads; it is only returned when the webhook is created
+	Secret              string     `json:"secret,omitempty" db:"secret"`
+	Active              bool       `json:"active" db:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures" db:"consecutive_failures"`
+	DisabledReason      *string    `json:"disabled_reason,omitempty" db:"disabled_reason"`
+	CreatedBy           *string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+//Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliveryRetrying  = "retrying"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+//WebhookDelivery is one event sent to a webhook, with the result of its latest attempt
+type WebhookDelivery struct {
+	ID           string          `json:"id" db:"id"`
+	WebhookID    string          `json:"webhook_id" db:"webhook_id"`
+	EventType    string          `json:"event" db:"event_type"`
+	Payload      json.RawMessage `json:"payload" db:"payload"`
+	Status       string          `json:"status" db:"status"`
+	Attempts     int             `json:"attempts" db:"attempts"`
+	ResponseCode *int            `json:"response_code,omitempty" db:"response_code"`
+	Error        *string         `json:"error,omitempty" db:"error"`
+	DurationMs   *int            `json:"duration_ms,omitempty" db:"duration_ms"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt  *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+}
+
+//GitIntegration lets a Git host report pushes to a project
+type GitIntegration struct {
+	ProjectID string `json:"project_id" db:"project_id"`
+	// Secret verifies hook requests; it is only returned when created or rotated
+	Secret string `json:"secret,omitempty" db:"secret"`
+	// Keywords map commit message words such as "fixes" to the status they set
+	Keywords  map[string]string `json:"keywords" db:"keywords"`
+	Active    bool              `json:"active" db:"active"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt *time.Time        `json:"updated_at,omitempty" db:"updated_at"`
 }
 
 //Request DTOs
@@ -54,6 +348,13 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Name     string `json:"name"`
+	// Timezone is optional and defaults to UTC
+	Timezone string `json:"timezone,omitempty"`
+}
+
+//UpdateTimezoneRequest sets the current user's timezone
+type UpdateTimezoneRequest struct {
+	Timezone string `json:"timezone"`
 }
 
 type LoginRequest struct {
@@ -67,8 +368,17 @@ type CreateTaskRequest struct {
 	Description string  `json:"description"`
 	Status      string  `json:"status"`
 	Priority    string  `json:"priority"`
-	DueDate     *string `json:"due_date"`
+	DueDate     *string `json:"due_date"` // YYYY-MM-DD (all day) or RFC 3339
 	AssignedTo  *string `json:"assigned_to,omitempty"`
+	// CustomFields maps custom field IDs to values
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	Labels       []string               `json:"labels,omitempty"`
+	// RecurrenceRule makes the task recurring, e.g. "FREQ=WEEKLY;BYDAY=MO". Requires due_date.
+	RecurrenceRule  *string `json:"recurrence_rule,omitempty"`
+	MilestoneID     *string `json:"milestone_id,omitempty"`
+	EstimateMinutes *int    `json:"estimate_minutes,omitempty"`
+	// DueAllDay with an RFC 3339 due_date keeps only its day
+	DueAllDay *bool `json:"due_all_day,omitempty"`
 }
 
 type UpdateTaskRequest struct {
@@ -76,29 +386,582 @@ type UpdateTaskRequest struct {
 	Description string  `json:"description"`
 	Status      string  `json:"status"`
 	Priority    string  `json:"priority"`
-	DueDate     *string `json:"due_date"`
+	DueDate     *string `json:"due_date"` // YYYY-MM-DD (all day) or RFC 3339
 	AssignedTo  *string `json:"assigned_to,omitempty"`
+	// CustomFields is merged into the task's values; a null value clears a field
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	// Labels replaces the task's labels when sent
+	Labels []string `json:"labels,omitempty"`
+	// RecurrenceRule changes the series rule; an empty string stops the recurrence.
+	// Only applied with scope=future.
+	RecurrenceRule *string `json:"recurrence_rule,omitempty"`
+	// MilestoneID links the task to a milestone when sent; an empty string unlinks it
+	MilestoneID *string `json:"milestone_id,omitempty"`
+	// EstimateMinutes sets the estimate when sent; 0 clears it
+	EstimateMinutes *int `json:"estimate_minutes,omitempty"`
+	// DueAllDay with an RFC 3339 due_date keeps only its day
+	DueAllDay *bool `json:"due_all_day,omitempty"`
+}
+
+//TaskPositionRequest places a task in a status column between two neighbours.
+//Either neighbour may be omitted at the ends of the column.
+type TaskPositionRequest struct {
+	// Status is the column; the task's current status if empty
+	Status string `json:"status"`
+	// BeforeID is the task directly above, AfterID the task directly below
+	BeforeID string `json:"before_id"`
+	AfterID  string `json:"after_id"`
+}
+
+//Recurrence edit scopes for UpdateTask
+const (
+	ScopeThis   = "this"
+	ScopeFuture = "future"
+)
+
+type CustomFieldRequest struct {
+	Name       string                `json:"name"`
+	Type       string                `json:"type"`
+	Options    []string              `json:"options"`
+	Required   bool                  `json:"required"`
+	Validation CustomFieldValidation `json:"validation"`
+	Position   int                   `json:"position"`
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active,omitempty"` // update only; re-enabling resets the failure count
+}
+
+type GitIntegrationRequest struct {
+	Keywords     map[string]string `json:"keywords,omitempty"` // default: fix/close/resolve variants set "done"
+	Active       *bool             `json:"active,omitempty"`
+	RotateSecret bool              `json:"rotate_secret,omitempty"`
+}
+
+//GitPushResult summarises what an incoming push changed
+type GitPushResult struct {
+	Commits        int      `json:"commits"`
+	TasksUpdated   []string `json:"tasks_updated"`
+	TasksLinked    []string `json:"tasks_linked"`
+	UnresolvedRefs []string `json:"unresolved_refs"`
+}
+
+//TransferTaskRequest moves or copies a task to another project
+type TransferTaskRequest struct {
+	ProjectID string `json:"project_id"`
+	// AssignedTo sets the assignee in the target project ("" unassigns). If
+	// omitted the assignee is kept when they are a member of the target.
+	AssignedTo *string `json:"assigned_to,omitempty"`
+	// CustomFields sets values of the target project's custom fields. Values
+	// of fields with the same name and type are carried over.
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+//WorkLog is time a user spent on a task. A running timer is a log without
+// minutes until it is stopped.
+type WorkLog struct {
+	ID        string `json:"id" db:"id"`
+	TaskID    string `json:"task_id" db:"task_id"`
+	TaskKey   string `json:"task_key,omitempty"`
+	ProjectID string `json:"project_id"`
+	UserID    string `json:"user_id" db:"user_id"`
+	UserName  string `json:"user_name,omitempty"`
+	Minutes   int    `json:"minutes" db:"minutes"`
+	// Date is the day the work was done
+	Date      time.Time  `json:"date" db:"log_date"`
+	Note      string     `json:"note" db:"note"`
+	Running   bool       `json:"running"`
+	StartedAt *time.Time `json:"started_at,omitempty" db:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+type WorkLogRequest struct {
+	Minutes int     `json:"minutes"`
+	Date    *string `json:"date"` // YYYY-MM-DD, today if empty
+	Note    string  `json:"note"`
+}
+
+type StartTimerRequest struct {
+	Note string `json:"note"`
+}
+
+//TimeReport totals estimates and logged time of tasks over a date range
+type TimeReport struct {
+	From      *string `json:"from,omitempty"`
+	To        *string `json:"to,omitempty"`
+	ProjectID *string `json:"project_id,omitempty"`
+	UserID    *string `json:"user_id,omitempty"`
+	// Tasks are the tasks with time logged in the range, plus for a project
+	// report its open tasks with an estimate
+	Tasks []TimeReportTask `json:"tasks"`
+	Users []TimeReportUser `json:"users"`
+	// TotalEstimateMinutes sums the estimates of the listed tasks
+	TotalEstimateMinutes int `json:"total_estimate_minutes"`
+	TotalLoggedMinutes   int `json:"total_logged_minutes"`
+}
+
+type TimeReportTask struct {
+	TaskID          string `json:"task_id"`
+	Key             string `json:"key"`
+	Title           string `json:"title"`
+	ProjectID       string `json:"project_id"`
+	Status          string `json:"status"`
+	EstimateMinutes *int   `json:"estimate_minutes,omitempty"`
+	LoggedMinutes   int    `json:"logged_minutes"`
+}
+
+type TimeReportUser struct {
+	UserID        string `json:"user_id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	LoggedMinutes int    `json:"logged_minutes"`
+}
+
+//MyTasksResponse is a user's task list across projects, grouped by when
+// the tasks are due
+type MyTasksResponse struct {
+	// Overdue holds open tasks due before today
+	Overdue  []*Task `json:"overdue"`
+	DueToday []*Task `json:"due_today"`
+	// ThisWeek holds tasks due after today until Sunday
+	ThisWeek []*Task `json:"this_week"`
+	// Later holds tasks due after this week, undated tasks and completed
+	// tasks that were due before today
+	Later []*Task `json:"later"`
+}
+
+//CalendarFeed is a secret iCalendar URL of a user's assigned tasks or,
+// with a project, of the project's tasks. The token is only returned when
+// the feed is created.
+type CalendarFeed struct {
+	ID          string     `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	ProjectID   *string    `json:"project_id,omitempty" db:"project_id"`
+	ProjectName *string    `json:"project_name,omitempty"`
+	Token       string     `json:"token,omitempty"`
+	URL         string     `json:"url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
+
+type CreateCalendarFeedRequest struct {
+	ProjectID *string `json:"project_id"` // empty for the feed of assigned tasks
+}
+
+//Milestone is a release or deliverable of a project that tasks link to.
+//Progress and risk are computed from the linked tasks when it is read.
+type Milestone struct {
+	ID          string     `json:"id" db:"id"`
+	ProjectID   string     `json:"project_id" db:"project_id"`
+	Title       string     `json:"title" db:"title"`
+	Description string     `json:"description" db:"description"`
+	DueDate     *time.Time `json:"due_date,omitempty" db:"due_date"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	TaskCount   int        `json:"task_count"`
+	DoneCount   int        `json:"done_count"`
+	// Progress is the percentage of linked tasks that are done
+	Progress float64 `json:"progress"`
+	// OverdueCount counts linked tasks past their due date and not done
+	OverdueCount int `json:"overdue_count"`
+	// Overdue is set when the milestone's due date has passed with tasks open
+	Overdue bool `json:"overdue"`
+	// AtRisk is set when open work threatens the due date: the milestone or
+	// one of its tasks is overdue, a task is due after the milestone, or it is
+	// due within a week with less than half the tasks done
+	AtRisk bool `json:"at_risk"`
+}
+
+type MilestoneRequest struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	DueDate     *string `json:"due_date"` // YYYY-MM-DD
+}
+
+//TaskGroup is one group of a task list grouped by milestone
+type TaskGroup struct {
+	// Milestone is nil for the group of tasks without a milestone
+	Milestone *Milestone `json:"milestone"`
+	Tasks     []*Task    `json:"tasks"`
+}
+
+//Sprint states
+const (
+	SprintPlanned = "planned"
+	SprintActive  = "active"
+	SprintClosed  = "closed"
+)
+
+//Sprint is a time box of a project's tasks
+type Sprint struct {
+	ID        string     `json:"id" db:"id"`
+	ProjectID string     `json:"project_id" db:"project_id"`
+	Name      string     `json:"name" db:"name"`
+	Goal      string     `json:"goal" db:"goal"`
+	StartDate *time.Time `json:"start_date,omitempty" db:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty" db:"end_date"`
+	State     string     `json:"state" db:"state"`
+	// TaskCount and DoneCount count the sprint's tasks; CarriedOver is the
+	// number of unfinished tasks moved out when it closed
+	TaskCount   int        `json:"task_count"`
+	DoneCount   int        `json:"done_count"`
+	CarriedOver int        `json:"carried_over" db:"carried_over"`
+	StartedAt   *time.Time `json:"started_at,omitempty" db:"started_at"`
+	ClosedAt    *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+type SprintRequest struct {
+	Name      string  `json:"name"`
+	Goal      string  `json:"goal"`
+	StartDate *string `json:"start_date"` // YYYY-MM-DD
+	EndDate   *string `json:"end_date"`   // YYYY-MM-DD
+}
+
+//CloseSprintRequest says where a closing sprint's unfinished tasks go
+type CloseSprintRequest struct {
+	// CarryOverTo is a planned sprint's ID, "next" (the next planned sprint, or the backlog if there is none) or "backlog"
+	CarryOverTo string `json:"carry_over_to"`
+}
+
+type SprintTasksRequest struct {
+	// TaskIDs are task UUIDs or keys such as WEB-42
+	TaskIDs []string `json:"task_ids"`
+}
+
+//SprintVelocity is what a sprint completed
+type SprintVelocity struct {
+	SprintID       string     `json:"sprint_id"`
+	Name           string     `json:"name"`
+	State          string     `json:"state"`
+	StartDate      *time.Time `json:"start_date,omitempty"`
+	EndDate        *time.Time `json:"end_date,omitempty"`
+	CompletedTasks int        `json:"completed_tasks"`
+	// CompletedPoints sums the points field of completed tasks, if one was chosen
+	CompletedPoints *float64 `json:"completed_points,omitempty"`
+	CarriedOver     int      `json:"carried_over"`
+}
+
+//VelocityReport lists the velocity of a project's started sprints, oldest first
+type VelocityReport struct {
+	PointsField *string          `json:"points_field,omitempty"`
+	Sprints     []SprintVelocity `json:"sprints"`
+	// AverageTasks and AveragePoints are over closed sprints
+	AverageTasks  float64  `json:"average_tasks"`
+	AveragePoints *float64 `json:"average_points,omitempty"`
+}
+
+//ProjectAnalytics summarises a project's tasks over a date range
+type ProjectAnalytics struct {
+	ProjectID   string    `json:"project_id"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Counts are of the project's current tasks
+	ByStatus   map[string]int  `json:"by_status"`
+	ByPriority map[string]int  `json:"by_priority"`
+	ByAssignee []AssigneeCount `json:"by_assignee"`
+	// Throughput has one entry per day of the range
+	Throughput     []DailyThroughput `json:"throughput"`
+	Burndown       []BurndownPoint   `json:"burndown"`
+	CumulativeFlow []FlowPoint       `json:"cumulative_flow"`
+	// LeadTime and CycleTime are over tasks completed in the range
+	LeadTime  FlowTime `json:"lead_time"`
+	CycleTime FlowTime `json:"cycle_time"`
+}
+
+//AssigneeCount counts the tasks of an assignee; an unassigned bucket has no user
+type AssigneeCount struct {
+	UserID *string `json:"user_id"`
+	Name   string  `json:"name"`
+	Total  int     `json:"total"`
+	Open   int     `json:"open"`
+}
+
+//DailyThroughput is how many tasks were created and completed on a day
+type DailyThroughput struct {
+	Date      string `json:"date"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+//BurndownPoint is the work left at the end of a day next to a straight line to zero
+type BurndownPoint struct {
+	Date      string  `json:"date"`
+	Remaining int     `json:"remaining"`
+	Ideal     float64 `json:"ideal"`
+}
+
+//FlowPoint counts tasks per status at the end of a day
+type FlowPoint struct {
+	Date     string         `json:"date"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+//FlowTime is an average duration in hours; it is null without samples.
+//Lead time runs from creation to completion, cycle time from the start of
+// work to completion.
+type FlowTime struct {
+	AverageHours *float64 `json:"average_hours"`
+	Tasks        int      `json:"tasks"`
+}
+
+//Bulk task operations
+const (
+	BulkSetStatus   = "set_status"
+	BulkSetPriority = "set_priority"
+	BulkSetAssignee = "set_assignee"
+	BulkMove        = "move"
+	BulkAddLabel    = "add_label"
+	BulkRemoveLabel = "remove_label"
+	BulkDelete      = "delete"
+)
+
+//Bulk result statuses
+const (
+	BulkResultOK        = "ok"
+	BulkResultNotFound  = "not_found"
+	BulkResultForbidden = "forbidden"
+	BulkResultFailed    = "failed"
+)
+
+//BulkTaskRequest applies one operation to many tasks
+type BulkTaskRequest struct {
+	// TaskIDs are task UUIDs or keys such as WEB-42
+	TaskIDs   []string `json:"task_ids"`
+	Operation string   `json:"operation"`
+	// Value is the status, priority, assignee (empty to unassign), target
+	// project ID or label, depending on the operation
+	Value string `json:"value"`
+}
+
+//BulkTaskResult is the outcome of a bulk operation for one task
+type BulkTaskResult struct {
+	TaskID string `json:"task_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Task   *Task  `json:"task,omitempty"`
+}
+
+//BulkTaskResponse reports a bulk operation item by item
+type BulkTaskResponse struct {
+	Operation string           `json:"operation"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkTaskResult `json:"results"`
+}
+
+//Import states
+const (
+	ImportPending = "pending"
+	ImportRunning = "running"
+	ImportDone    = "done"
+	ImportFailed  = "failed"
+)
+
+//TaskImport tracks an import that runs as a background job
+type TaskImport struct {
+	ID         string     `json:"id" db:"id"`
+	ProjectID  string     `json:"project_id" db:"project_id"`
+	UserID     *string    `json:"user_id,omitempty" db:"user_id"`
+	Format     string     `json:"format" db:"format"`
+	Status     string     `json:"status" db:"status"`
+	Total      int        `json:"total" db:"total"`
+	Processed  int        `json:"processed" db:"processed"`
+	Error      *string    `json:"error,omitempty" db:"error"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+//ImportRowResult reports the problems found in one row of an import file
+type ImportRowResult struct {
+	Line     int      `json:"line"`
+	Title    string   `json:"title"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+//ImportReport is the result of validating, and unless it is a dry run, importing a file
+type ImportReport struct {
+	Format  string `json:"format"`
+	DryRun  bool   `json:"dry_run"`
+	Total   int    `json:"total"`
+	Valid   int    `json:"valid"`
+	Invalid int    `json:"invalid"`
+	// Rows lists the rows with errors or warnings
+	Rows []ImportRowResult `json:"rows"`
+	// Created is set when the tasks were imported right away
+	Created *int `json:"created,omitempty"`
+	// Import is set when the tasks are imported by a background job
+	Import *TaskImport `json:"import,omitempty"`
+}
+
+//ProjectTemplate is a saved starting point for new projects
+type ProjectTemplate struct {
+	ID              string          `json:"id" db:"id"`
+	Name            string          `json:"name" db:"name"`
+	Description     string          `json:"description" db:"description"`
+	SourceProjectID *string         `json:"source_project_id,omitempty" db:"source_project_id"`
+	CreatedBy       *string         `json:"created_by,omitempty" db:"created_by"`
+	Content         TemplateContent `json:"content" db:"content"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+}
+
+//TemplateContent is what a template or a clone copies into a new project
+type TemplateContent struct {
+	// ProjectDescription is the new project's description unless one is given
+	ProjectDescription string          `json:"project_description"`
+	CustomFields       []TemplateField `json:"custom_fields"`
+	Tasks              []TemplateTask  `json:"tasks"`
+	// Members are only set when cloning with members
+	Members []ProjectMember `json:"members,omitempty"`
+}
+
+//TemplateField is a custom field of a template
+type TemplateField struct {
+	Name       string                `json:"name"`
+	Type       string                `json:"type"`
+	Options    []string              `json:"options"`
+	Required   bool                  `json:"required"`
+	Validation CustomFieldValidation `json:"validation"`
+}
+
+//TemplateTask is a starter task of a template
+type TemplateTask struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Priority    string   `json:"priority"`
+	Labels      []string `json:"labels"`
+	// DueDate is an absolute due date; DueInDays is one relative to the day the project is created
+	DueDate   *time.Time `json:"due_date,omitempty"`
+	DueInDays *int       `json:"due_in_days,omitempty"`
+	// DueTimed marks DueDate as a moment rather than a day; relative due dates
+	// are always days. Templates saved before due times have only days.
+	DueTimed bool `json:"due_timed,omitempty"`
+	// AssignedTo is only kept when cloning with members
+	AssignedTo *string `json:"assigned_to,omitempty"`
+	// CustomFields maps custom field names to values
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+//Due date handling when saving a template or cloning
+const (
+	DueDatesKeep     = "keep"
+	DueDatesRelative = "relative"
+	DueDatesClear    = "clear"
+)
+
+//SnapshotOptions selects what a template or a clone copies from a project
+type SnapshotOptions struct {
+	CustomFields   bool   `json:"custom_fields"`
+	Tasks          bool   `json:"tasks"`
+	CompletedTasks bool   `json:"completed_tasks"`
+	Members        bool   `json:"members"`
+	DueDates       string `json:"due_dates"`
+}
+
+//CreateTemplateRequest saves a project as a template
+type CreateTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// IncludeTasks saves the project's open tasks as starter tasks (default true)
+	IncludeTasks *bool `json:"include_tasks,omitempty"`
+	// RelativeDueDates saves due dates as days after the project's start instead of dropping them
+	RelativeDueDates bool `json:"relative_due_dates"`
+}
+
+//CloneProjectRequest copies a project into a new one
+type CloneProjectRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Key         string  `json:"key"`
+	// Options default to custom fields and open tasks with due dates kept
+	Options *SnapshotOptions `json:"options,omitempty"`
 }
 
 type CreateProjectRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// Key prefixes the project's task keys; derived from the name if empty
+	Key string `json:"key"`
+	// TemplateID starts the project from a template's custom fields and tasks
+	TemplateID string `json:"template_id,omitempty"`
 }
 
 type UpdateProjectRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// Key changes the project key (PO only); nil keeps it
+	Key *string `json:"key,omitempty"`
 }
 
 type InviteMemberRequest struct {
 	Email string `json:"email"`
-	Role  string `json:"role"` // 'PM', 'Member', 'Viewer'
+	Role  string `json:"role"` // 'pm', 'member', 'viewer' (case-insensitive)
 }
 
 type UpdateMemberRoleRequest struct {
 	Role string `json:"role"`
 }
 
+type TransferOwnershipRequest struct {
+	UserID string `json:"user_id"`
+	// PreviousOwnerRole is the caller's role after the transfer (default 'pm')
+	PreviousOwnerRole string `json:"previous_owner_role,omitempty"`
+}
+
+//DeleteUserRequest controls what happens to a deleted user's work
+type DeleteUserRequest struct {
+	// ReassignTo receives the tasks the user created. Empty means the
+	// "former member" placeholder account.
+	ReassignTo *string `json:"reassign_to,omitempty"`
+	// Assignments is "clear" (default) or "reassign" to ReassignTo
+	Assignments string `json:"assignments,omitempty"`
+	// SuccessorID becomes PO of every project where the user is the last PO
+	SuccessorID *string `json:"successor_id,omitempty"`
+	// Successors overrides SuccessorID per project ID
+	Successors map[string]string `json:"successors,omitempty"`
+}
+
+//ProjectSuccession describes a project that loses its last PO
+type ProjectSuccession struct {
+	ProjectID   string  `json:"project_id"`
+	ProjectName string  `json:"project_name"`
+	SuccessorID *string `json:"successor_id"`
+}
+
+//UserDeletionPreview summarises the changes a user deletion makes
+type UserDeletionPreview struct {
+	UserID             string               `json:"user_id"`
+	DryRun             bool                 `json:"dry_run"`
+	Blocked            bool                 `json:"blocked"`
+	CreatedTasks       int                  `json:"created_tasks"`
+	CreatedTasksTo     string               `json:"created_tasks_reassigned_to"`
+	AssignedTasks      int                  `json:"assigned_tasks"`
+	AssignmentsAction  string               `json:"assignments_action"`
+	MembershipsRemoved int                  `json:"memberships_removed"`
+	OwnedProjects      []*ProjectSuccession `json:"owned_projects"`
+}
+
+//TrashResponse lists soft-deleted users and projects (admin trash view)
+type TrashResponse struct {
+	Users    []*User    `json:"users"`
+	Projects []*Project `json:"projects"`
+}
+
+//RestoredUser is the account brought back from the trash, with a warning
+// about what restoring does not bring back
+type RestoredUser struct {
+	*User
+	Warning string `json:"warning"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
@@ -0,0 +1,159 @@
+// Package notify turns task events into in-app notifications for the
+// task's watchers.
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"task-management/internal/due"
+	"task-management/internal/events"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+)
+
+// maxTitleLength is the length of the notifications.title column
+const maxTitleLength = 255
+
+// watcherStore is the part of the watcher repository the notifier uses
+type watcherStore interface {
+	NotifyWatchers(taskID, actorID, notificationType, title, body string) (int64, error)
+}
+
+// WatcherNotifier notifies watchers about changes to their tasks
+type WatcherNotifier struct {
+	watcherRepo watcherStore
+}
+
+func NewWatcherNotifier(watcherRepo *repository.WatcherRepository) *WatcherNotifier {
+	return &WatcherNotifier{watcherRepo: watcherRepo}
+}
+
+// Handle notifies the watchers of the tasks in event, except the user who
+// caused it. Subscribe it to the event bus. Each task takes one insert, so
+// it is quick enough to run on the publisher's goroutine.
+func (n *WatcherNotifier) Handle(event events.Event) {
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if event.Type == events.TaskBulkUpdated {
+		tasks, _ := data["tasks"].([]*models.Task)
+		previous, _ := data["previous"].([]*models.Task)
+		for i, task := range tasks {
+			// A bulk move is published to both projects but notified once,
+			// from the task's new project
+			if task.ProjectID != event.ProjectID {
+				continue
+			}
+			var before *models.Task
+			if i < len(previous) {
+				before = previous[i]
+			}
+			n.notify(event, task, before)
+		}
+		return
+	}
+
+	task, _ := data["task"].(*models.Task)
+	if task == nil {
+		return
+	}
+	previous, _ := data["previous"].(*models.Task)
+	// A move is published to both projects but notified once
+	if event.Type == events.TaskMoved && event.ProjectID != task.ProjectID {
+		return
+	}
+	n.notify(event, task, previous)
+}
+
+// notify creates the notifications about one task
+func (n *WatcherNotifier) notify(event events.Event, task, previous *models.Task) {
+	var notificationType, title, body string
+	switch event.Type {
+	case events.TaskCreated:
+		notificationType = models.NotificationTaskCreated
+		title = fmt.Sprintf("New task %s: %s", task.Key, task.Title)
+		if task.AssigneeName != nil {
+			body = "Assigned to " + *task.AssigneeName
+		}
+	case events.TaskUpdated, events.TaskBulkUpdated:
+		// Saving a task unchanged doesn't bump its version
+		if previous != nil && previous.Version == task.Version {
+			return
+		}
+		notificationType = models.NotificationTaskUpdated
+		title = fmt.Sprintf("%s updated: %s", task.Key, task.Title)
+		body = strings.Join(describeChanges(previous, task), "\n")
+	case events.TaskDeleted:
+		notificationType = models.NotificationTaskDeleted
+		title = fmt.Sprintf("%s deleted: %s", task.Key, task.Title)
+	case events.TaskRestored:
+		notificationType = models.NotificationTaskRestored
+		title = fmt.Sprintf("%s restored: %s", task.Key, task.Title)
+	case events.TaskMoved:
+		notificationType = models.NotificationTaskMoved
+		title = fmt.Sprintf("%s moved: %s", task.Key, task.Title)
+		if previous != nil {
+			body = fmt.Sprintf("Moved from %s to %s", previous.Key, task.ProjectName)
+		}
+	default:
+		return
+	}
+
+	if _, err := n.watcherRepo.NotifyWatchers(task.ID, event.ActorID, notificationType, truncate(title, maxTitleLength), body); err != nil {
+		log.Printf("[NOTIFY] Error notifying watchers of task %s about %s: %v", task.ID, event.Type, err)
+	}
+}
+
+// describeChanges lists the visible differences between two versions of a
+// task, one line each
+func describeChanges(previous, task *models.Task) []string {
+	if previous == nil {
+		return nil
+	}
+	var changes []string
+	change := func(name, from, to string) {
+		if from != to {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", name, from, to))
+		}
+	}
+
+	change("Title", previous.Title, task.Title)
+	change("Status", previous.Status, task.Status)
+	change("Priority", previous.Priority, task.Priority)
+	change("Assignee", assigneeOf(previous), assigneeOf(task))
+	change("Due date", dueDateOf(previous), dueDateOf(task))
+	if previous.Description != task.Description {
+		changes = append(changes, "Description changed")
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "Details changed")
+	}
+	return changes
+}
+
+func assigneeOf(task *models.Task) string {
+	if task.AssigneeName == nil {
+		return "unassigned"
+	}
+	return *task.AssigneeName
+}
+
+func dueDateOf(task *models.Task) string {
+	if task.DueDate == nil {
+		return "none"
+	}
+	return due.Format(*task.DueDate, task.DueAllDay)
+}
+
+// truncate shortens s to at most max characters
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
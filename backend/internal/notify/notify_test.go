@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"testing"
+
+	"task-management/internal/events"
+	"task-management/internal/models"
+)
+
+// recordingStore records the notifications sent instead of storing them
+type recordingStore struct {
+	sent []string // task ID and type of each call
+}
+
+func (s *recordingStore) NotifyWatchers(taskID, actorID, notificationType, title, body string) (int64, error) {
+	s.sent = append(s.sent, taskID+" "+notificationType)
+	return 1, nil
+}
+
+func TestHandleNotifiesMovedTasksOnce(t *testing.T) {
+	before := []*models.Task{
+		{ID: "a", ProjectID: "old", Key: "OLD-1", Version: 1},
+		{ID: "b", ProjectID: "old", Key: "OLD-2", Version: 1},
+	}
+	after := []*models.Task{
+		{ID: "a", ProjectID: "new", Key: "NEW-7", Version: 2},
+		{ID: "b", ProjectID: "new", Key: "NEW-8", Version: 2},
+	}
+
+	store := &recordingStore{}
+	notifier := &WatcherNotifier{watcherRepo: store}
+	// The bulk handler publishes a move to the old and the new project
+	for _, projectID := range []string{"old", "new"} {
+		notifier.Handle(events.Event{
+			Type:      events.TaskBulkUpdated,
+			ProjectID: projectID,
+			ActorID:   "actor",
+			Data: map[string]interface{}{
+				"operation": models.BulkMove,
+				"tasks":     after,
+				"previous":  before,
+			},
+		})
+	}
+
+	want := []string{"a " + models.NotificationTaskUpdated, "b " + models.NotificationTaskUpdated}
+	if len(store.sent) != len(want) {
+		t.Fatalf("sent %v, want %v", store.sent, want)
+	}
+	for i := range want {
+		if store.sent[i] != want[i] {
+			t.Errorf("sent %v, want %v", store.sent, want)
+		}
+	}
+}
+
+func TestHandleNotifiesBulkUpdate(t *testing.T) {
+	store := &recordingStore{}
+	notifier := &WatcherNotifier{watcherRepo: store}
+	notifier.Handle(events.Event{
+		Type:      events.TaskBulkUpdated,
+		ProjectID: "p",
+		ActorID:   "actor",
+		Data: map[string]interface{}{
+			"operation": models.BulkSetStatus,
+			"tasks": []*models.Task{
+				{ID: "changed", ProjectID: "p", Status: "done", Version: 2},
+				{ID: "unchanged", ProjectID: "p", Status: "done", Version: 4},
+			},
+			"previous": []*models.Task{
+				{ID: "changed", ProjectID: "p", Status: "todo", Version: 1},
+				{ID: "unchanged", ProjectID: "p", Status: "done", Version: 4},
+			},
+		},
+	})
+
+	if len(store.sent) != 1 || store.sent[0] != "changed "+models.NotificationTaskUpdated {
+		t.Errorf("sent %v, want only the changed task", store.sent)
+	}
+}
+
+func TestHandleNotifiesSingleMoveOnce(t *testing.T) {
+	store := &recordingStore{}
+	notifier := &WatcherNotifier{watcherRepo: store}
+	task := &models.Task{ID: "a", ProjectID: "new", Key: "NEW-7"}
+	previous := &models.Task{ID: "a", ProjectID: "old", Key: "OLD-1"}
+	for _, projectID := range []string{"old", "new"} {
+		notifier.Handle(events.Event{
+			Type:      events.TaskMoved,
+			ProjectID: projectID,
+			Data:      map[string]interface{}{"task": task, "previous": previous},
+		})
+	}
+
+	if len(store.sent) != 1 || store.sent[0] != "a "+models.NotificationTaskMoved {
+		t.Errorf("sent %v, want one moved notification", store.sent)
+	}
+}
@@ -0,0 +1,261 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used
+// for recurring tasks: FREQ=DAILY, WEEKLY (optionally BYDAY) and MONTHLY
+// (optionally BYMONTHDAY), with INTERVAL and either UNTIL or COUNT.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// maxIterations bounds the search for the next occurrence so a rule that can
+// never match (e.g. BYMONTHDAY=31 with INTERVAL=2 from an odd month) ends
+const maxIterations = 1000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday // WEEKLY only
+	ByMonthDay int            // MONTHLY only; -1 is the last day of the month
+	Until      *time.Time
+	Count      int // 0 means unlimited
+}
+
+// Parse parses an RRULE such as "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=10".
+// A leading "RRULE:" is accepted.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("recurrence rule is empty")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+
+		switch key {
+		case "FREQ":
+			if val != Daily && val != Weekly && val != Monthly {
+				return nil, fmt.Errorf("unsupported FREQ %q: use DAILY, WEEKLY or MONTHLY", val)
+			}
+			rule.Freq = val
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 366 {
+				return nil, fmt.Errorf("INTERVAL must be between 1 and 366")
+			}
+			rule.Interval = n
+		case "BYDAY":
+			seen := map[time.Weekday]bool{}
+			for _, day := range strings.Split(val, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY value %q", day)
+				}
+				if !seen[weekday] {
+					seen[weekday] = true
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+			sort.Slice(rule.ByDay, func(i, j int) bool { return weekdayIndex(rule.ByDay[i]) < weekdayIndex(rule.ByDay[j]) })
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n == 0 || n < -1 || n > 31 {
+				return nil, fmt.Errorf("BYMONTHDAY must be between 1 and 31, or -1")
+			}
+			rule.ByMonthDay = n
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+			rule.Count = n
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Until != nil && rule.Count > 0 {
+		return nil, fmt.Errorf("UNTIL and COUNT cannot be combined")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if rule.ByMonthDay != 0 && rule.Freq != Monthly {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q: use YYYYMMDD or YYYYMMDDTHHMMSSZ", value)
+}
+
+// String formats the rule in canonical RRULE form
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, weekday := range r.ByDay {
+			days[i] = strings.ToUpper(weekday.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrence returns the date of the index-th occurrence of a series that
+// starts at start (index 0 is start itself). ok is false once the series has
+// ended through COUNT or UNTIL.
+func (r *Rule) Occurrence(start time.Time, index int) (time.Time, bool) {
+	if index < 0 || (r.Count > 0 && index >= r.Count) {
+		return time.Time{}, false
+	}
+
+	current := start
+	for i := 0; i < index; i++ {
+		next, ok := r.next(start, current)
+		if !ok {
+			return time.Time{}, false
+		}
+		current = next
+	}
+
+	if r.Until != nil && current.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return current, true
+}
+
+// next returns the first occurrence strictly after prev
+func (r *Rule) next(start, prev time.Time) (time.Time, bool) {
+	switch r.Freq {
+	case Daily:
+		return atClockOf(prev.AddDate(0, 0, r.Interval), start), true
+
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		startWeek := weekStart(start)
+		candidate := prev
+		for i := 0; i < maxIterations; i++ {
+			candidate = atClockOf(candidate.AddDate(0, 0, 1), start)
+			weeks := int(weekStart(candidate).Sub(startWeek).Hours()+12) / (24 * 7)
+			if weeks%r.Interval != 0 {
+				continue
+			}
+			for _, day := range days {
+				if candidate.Weekday() == day {
+					return candidate, true
+				}
+			}
+		}
+
+	case Monthly:
+		day := r.ByMonthDay
+		if day == 0 {
+			day = start.Day()
+		}
+		for i := 1; i < maxIterations; i++ {
+			months := monthsBetween(start, prev) + i
+			if months%r.Interval != 0 {
+				continue
+			}
+			year, month := start.Year(), start.Month()+time.Month(months)
+			first := time.Date(year, month, 1, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+			last := first.AddDate(0, 1, -1).Day()
+			target := day
+			if day == -1 {
+				target = last
+			}
+			if target > last {
+				// Months without that day are skipped, as in RFC 5545
+				continue
+			}
+			return atClockOf(first.AddDate(0, 0, target-1), start), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// atClockOf returns day at start's time of day. A time that falls into a
+// daylight saving gap is moved by it for that day only, so the series
+// returns to its wall clock time afterwards.
+func atClockOf(day, start time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+}
+
+// weekStart returns midnight of the Monday that starts t's week
+func weekStart(t time.Time) time.Time {
+	offset := weekdayIndex(t.Weekday())
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// weekdayIndex orders weekdays from Monday (0) to Sunday (6)
+func weekdayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+}
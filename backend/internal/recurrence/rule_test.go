@@ -0,0 +1,177 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	// Timezone cases must not depend on the machine's zoneinfo
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string // canonical String() of the parsed rule
+	}{
+		{"daily", "FREQ=DAILY", "FREQ=DAILY"},
+		{"prefix and lowercase", "RRULE:freq=daily;interval=3", "FREQ=DAILY;INTERVAL=3"},
+		{"byday sorted from monday and deduplicated", "FREQ=WEEKLY;BYDAY=FR,MO,MO,WE;COUNT=5", "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5"},
+		{"sunday ends the week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU,TU", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU"},
+		{"last day of month", "FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"date-only until includes the day", "FREQ=MONTHLY;UNTIL=20261231", "FREQ=MONTHLY;UNTIL=20261231T235959Z"},
+		{"utc until", "FREQ=DAILY;UNTIL=20260301T120000Z", "FREQ=DAILY;UNTIL=20260301T120000Z"},
+		{"interval 1 is implied", "FREQ=DAILY;INTERVAL=1", "FREQ=DAILY"},
+		{"empty parts ignored", "FREQ=DAILY;;COUNT=2;", "FREQ=DAILY;COUNT=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.value, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Errorf("Parse(%q).String() = %q, want %q", tt.value, got, tt.want)
+			}
+			// The canonical form parses back to itself
+			again, err := Parse(rule.String())
+			if err != nil || again.String() != tt.want {
+				t.Errorf("Parse(%q) did not round-trip: %v, %v", rule.String(), again, err)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"RRULE:",
+		"FREQ",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;INTERVAL=367",
+		"FREQ=DAILY;INTERVAL=x",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-2",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=2026-01-01",
+		"FREQ=DAILY;WKST=MO",
+	} {
+		if rule, err := Parse(value); err == nil {
+			t.Errorf("Parse(%q) = %q, want an error", value, rule.String())
+		}
+	}
+}
+
+func TestOccurrence(t *testing.T) {
+	utc := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		index int
+		want  time.Time // zero if the series has ended
+	}{
+		{"index 0 is the start", "FREQ=DAILY", utc(2026, 1, 30), 0, utc(2026, 1, 30)},
+		{"daily interval crosses a month", "FREQ=DAILY;INTERVAL=3", utc(2026, 1, 30), 1, utc(2026, 2, 2)},
+		{"weekly defaults to the start weekday", "FREQ=WEEKLY", utc(2026, 1, 1), 1, utc(2026, 1, 8)},
+		{"byday next day in week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", utc(2026, 1, 5), 1, utc(2026, 1, 7)},
+		{"byday last day in week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", utc(2026, 1, 5), 2, utc(2026, 1, 9)},
+		{"byday wraps to next week", "FREQ=WEEKLY;BYDAY=MO,WE,FR", utc(2026, 1, 5), 3, utc(2026, 1, 12)},
+		{"byday with interval skips weeks", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH", utc(2026, 1, 6), 2, utc(2026, 1, 20)},
+		{"byday sunday is end of week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU", utc(2026, 1, 5), 1, utc(2026, 1, 11)},
+		{"monthly skips months without the day", "FREQ=MONTHLY", utc(2026, 1, 31), 1, utc(2026, 3, 31)},
+		{"monthly keeps skipping short months", "FREQ=MONTHLY", utc(2026, 1, 31), 2, utc(2026, 5, 31)},
+		{"last day of february", "FREQ=MONTHLY;BYMONTHDAY=-1", utc(2026, 1, 31), 1, utc(2026, 2, 28)},
+		{"last day of a leap february", "FREQ=MONTHLY;BYMONTHDAY=-1", utc(2028, 1, 31), 1, utc(2028, 2, 29)},
+		{"last day after february", "FREQ=MONTHLY;BYMONTHDAY=-1", utc(2026, 1, 31), 2, utc(2026, 3, 31)},
+		{"bymonthday after an off-day start", "FREQ=MONTHLY;BYMONTHDAY=15", utc(2026, 1, 20), 1, utc(2026, 2, 15)},
+		{"monthly interval", "FREQ=MONTHLY;INTERVAL=2", utc(2026, 11, 15), 1, utc(2027, 1, 15)},
+		{"last counted occurrence", "FREQ=DAILY;COUNT=3", utc(2026, 1, 1), 2, utc(2026, 1, 3)},
+		{"count exhausted", "FREQ=DAILY;COUNT=3", utc(2026, 1, 1), 3, time.Time{}},
+		{"until is inclusive", "FREQ=DAILY;UNTIL=20260110", utc(2026, 1, 8), 2, utc(2026, 1, 10)},
+		{"past until", "FREQ=DAILY;UNTIL=20260110", utc(2026, 1, 8), 3, time.Time{}},
+		{"negative index", "FREQ=DAILY", utc(2026, 1, 1), -1, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got, ok := rule.Occurrence(tt.start, tt.index)
+			if tt.want.IsZero() {
+				if ok {
+					t.Errorf("Occurrence(%d) = %v, want the series to have ended", tt.index, got)
+				}
+				return
+			}
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("Occurrence(%d) = %v, %v; want %v", tt.index, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+// Timed series are counted in their creator's timezone and keep their wall
+// clock time across daylight saving changes. New York moves to EDT on
+// 2026-03-08 at 02:00 and back to EST on 2026-11-01 at 02:00.
+func TestOccurrenceDaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, ny)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		index int
+		want  time.Time
+	}{
+		{"daily across spring forward", "FREQ=DAILY", at(3, 7, 9, 0), 1, at(3, 8, 9, 0)},
+		{"daily after spring forward", "FREQ=DAILY", at(3, 7, 9, 0), 2, at(3, 9, 9, 0)},
+		{"weekly across spring forward", "FREQ=WEEKLY", at(3, 2, 9, 0), 1, at(3, 9, 9, 0)},
+		{"byday across spring forward", "FREQ=WEEKLY;BYDAY=MO,FR", at(3, 6, 17, 0), 1, at(3, 9, 17, 0)},
+		{"weekly with interval across fall back", "FREQ=WEEKLY;INTERVAL=2", at(10, 26, 9, 0), 1, at(11, 9, 9, 0)},
+		{"monthly across fall back", "FREQ=MONTHLY", at(10, 15, 9, 0), 1, at(11, 15, 9, 0)},
+		{"last day of month across fall back", "FREQ=MONTHLY;BYMONTHDAY=-1", at(10, 31, 23, 30), 1, at(11, 30, 23, 30)},
+		// 02:30 doesn't exist on 2026-03-08; the days after return to 02:30
+		{"daily after a skipped time", "FREQ=DAILY", at(3, 7, 2, 30), 2, at(3, 9, 2, 30)},
+		{"weekly after a skipped time", "FREQ=WEEKLY;BYDAY=SU", at(3, 1, 2, 30), 2, at(3, 15, 2, 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got, ok := rule.Occurrence(tt.start, tt.index)
+			if !ok || !got.Equal(tt.want) {
+				t.Errorf("Occurrence(%d) = %v, %v; want %v", tt.index, got, ok, tt.want)
+			}
+		})
+	}
+
+	// The occurrence in the gap still falls on its own day
+	rule, _ := Parse("FREQ=DAILY")
+	got, ok := rule.Occurrence(at(3, 7, 2, 30), 1)
+	if y, m, d := got.Date(); !ok || y != 2026 || m != time.March || d != 8 {
+		t.Errorf("Occurrence in the gap = %v, %v; want a time on 2026-03-08", got, ok)
+	}
+}
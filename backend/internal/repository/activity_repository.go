@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ActivityRepository stores the activity log of tasks
+type ActivityRepository struct {
+	db *sql.DB
+}
+
+func NewActivityRepository(db *sql.DB) *ActivityRepository {
+	return &ActivityRepository{db: db}
+}
+
+// AddActivity appends an entry to a task's log. A non-empty ref makes the
+// entry unique per task and type, e.g. a commit SHA, so replayed events are
+// only logged once; AddActivity then reports false.
+func (r *ActivityRepository) AddActivity(entry *models.TaskActivity, ref string) (bool, error) {
+	return insertActivity(r.db, entry, ref)
+}
+
+// insertActivity assigns an ID and timestamp and inserts the entry
+func insertActivity(db execer, entry *models.TaskActivity, ref string) (bool, error) {
+	entry.ID = uuid.New().String()
+	entry.CreatedAt = time.Now()
+	if entry.Data == nil {
+		entry.Data = map[string]interface{}{}
+	}
+	data, err := json.Marshal(entry.Data)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode activity data: %w", err)
+	}
+	var refValue interface{}
+	if ref != "" {
+		refValue = ref
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO task_activity (id, task_id, actor_id, type, message, data, ref, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (task_id, type, ref) DO NOTHING
+	`, entry.ID, entry.TaskID, entry.ActorID, entry.Type, entry.Message, data, refValue, entry.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to add activity: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// GetActivity returns a task's activity log, oldest first
+func (r *ActivityRepository) GetActivity(taskID string) ([]*models.TaskActivity, error) {
+	rows, err := r.db.Query(`
+		SELECT id, task_id, actor_id, type, message, data, created_at
+		FROM task_activity
+		WHERE task_id = $1
+		ORDER BY created_at ASC
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.TaskActivity{}
+	for rows.Next() {
+		entry := &models.TaskActivity{}
+		var actorID sql.NullString
+		var data []byte
+		if err := rows.Scan(&entry.ID, &entry.TaskID, &actorID, &entry.Type, &entry.Message, &data, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		if actorID.Valid {
+			entry.ActorID = &actorID.String
+		}
+		if err := json.Unmarshal(data, &entry.Data); err != nil {
+			return nil, fmt.Errorf("failed to decode activity data: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
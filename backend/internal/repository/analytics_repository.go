@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"task-management/internal/models"
+)
+
+// AnalyticsRepository computes project analytics from tasks and their
+// status history
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// analyticsDays generates the days of the range $2 to $3
+const analyticsDays = `days AS (SELECT generate_series($2::date, $3::date, INTERVAL '1 day')::date AS day)`
+
+// ProjectAnalytics computes the analytics of a project for the days from and
+// to, both inclusive. All queries read the same snapshot, so the figures add
+// up even while tasks change.
+func (r *AnalyticsRepository) ProjectAnalytics(projectID string, from, to time.Time) (*models.ProjectAnalytics, error) {
+	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	analytics := &models.ProjectAnalytics{
+		ProjectID:      projectID,
+		From:           from.Format("2006-01-02"),
+		To:             to.Format("2006-01-02"),
+		GeneratedAt:    time.Now(),
+		ByStatus:       map[string]int{models.StatusTodo: 0, models.StatusInProgress: 0, models.StatusDone: 0},
+		ByPriority:     map[string]int{models.PriorityLow: 0, models.PriorityMedium: 0, models.PriorityHigh: 0},
+		ByAssignee:     []models.AssigneeCount{},
+		Throughput:     []models.DailyThroughput{},
+		Burndown:       []models.BurndownPoint{},
+		CumulativeFlow: []models.FlowPoint{},
+	}
+
+	if err := countTasks(tx, projectID, analytics); err != nil {
+		return nil, err
+	}
+	if err := throughput(tx, projectID, from, to, analytics); err != nil {
+		return nil, err
+	}
+	if err := cumulativeFlow(tx, projectID, from, to, analytics); err != nil {
+		return nil, err
+	}
+	if err := flowTimes(tx, projectID, from, to, analytics); err != nil {
+		return nil, err
+	}
+	burndown(analytics)
+	return analytics, nil
+}
+
+// countTasks counts the project's tasks by status, priority and assignee
+func countTasks(tx *sql.Tx, projectID string, analytics *models.ProjectAnalytics) error {
+	rows, err := tx.Query(`
+		SELECT status, priority, COUNT(*)
+		FROM tasks
+		WHERE project_id = $1 AND deleted_at IS NULL
+		GROUP BY status, priority
+	`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to count tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status, priority string
+		var count int
+		if err := rows.Scan(&status, &priority, &count); err != nil {
+			return fmt.Errorf("failed to scan task counts: %w", err)
+		}
+		analytics.ByStatus[status] += count
+		analytics.ByPriority[priority] += count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate task counts: %w", err)
+	}
+
+	assigneeRows, err := tx.Query(`
+		SELECT t.assigned_to, COALESCE(u.name, ''), COUNT(*), COUNT(*) FILTER (WHERE t.status <> 'done')
+		FROM tasks t
+		LEFT JOIN users u ON u.id = t.assigned_to
+		WHERE t.project_id = $1 AND t.deleted_at IS NULL
+		GROUP BY t.assigned_to, u.name
+		ORDER BY COUNT(*) DESC, u.name ASC NULLS LAST
+	`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to count tasks by assignee: %w", err)
+	}
+	defer assigneeRows.Close()
+
+	for assigneeRows.Next() {
+		var count models.AssigneeCount
+		var userID sql.NullString
+		if err := assigneeRows.Scan(&userID, &count.Name, &count.Total, &count.Open); err != nil {
+			return fmt.Errorf("failed to scan assignee counts: %w", err)
+		}
+		if userID.Valid {
+			count.UserID = &userID.String
+		} else {
+			count.Name = "Unassigned"
+		}
+		analytics.ByAssignee = append(analytics.ByAssignee, count)
+	}
+	if err := assigneeRows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate assignee counts: %w", err)
+	}
+	return nil
+}
+
+// throughput counts the tasks created and completed on each day of the range
+func throughput(tx *sql.Tx, projectID string, from, to time.Time, analytics *models.ProjectAnalytics) error {
+	rows, err := tx.Query(`
+		WITH `+analyticsDays+`,
+		created AS (
+			SELECT created_at::date AS day, COUNT(*) AS count
+			FROM tasks
+			WHERE project_id = $1 AND deleted_at IS NULL
+			  AND created_at >= $2::date AND created_at < $3::date + 1
+			GROUP BY 1
+		),
+		completed AS (
+			SELECT h.changed_at::date AS day, COUNT(*) AS count
+			FROM task_status_history h
+			INNER JOIN tasks t ON t.id = h.task_id
+			WHERE t.project_id = $1 AND t.deleted_at IS NULL AND h.status = 'done'
+			  AND h.changed_at >= $2::date AND h.changed_at < $3::date + 1
+			GROUP BY 1
+		)
+		SELECT d.day, COALESCE(c.count, 0), COALESCE(x.count, 0)
+		FROM days d
+		LEFT JOIN created c ON c.day = d.day
+		LEFT JOIN completed x ON x.day = d.day
+		ORDER BY d.day
+	`, projectID, analytics.From, analytics.To)
+	if err != nil {
+		return fmt.Errorf("failed to get throughput: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day time.Time
+		var point models.DailyThroughput
+		if err := rows.Scan(&day, &point.Created, &point.Completed); err != nil {
+			return fmt.Errorf("failed to scan throughput: %w", err)
+		}
+		point.Date = day.Format("2006-01-02")
+		analytics.Throughput = append(analytics.Throughput, point)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate throughput: %w", err)
+	}
+	return nil
+}
+
+// cumulativeFlow counts the tasks in each status at the end of every day of
+// the range, from the last status each task had entered by then
+func cumulativeFlow(tx *sql.Tx, projectID string, from, to time.Time, analytics *models.ProjectAnalytics) error {
+	rows, err := tx.Query(`
+		WITH `+analyticsDays+`
+		SELECT d.day, s.status, COUNT(*)
+		FROM days d
+		INNER JOIN tasks t ON t.project_id = $1 AND t.deleted_at IS NULL AND t.created_at < d.day + 1
+		CROSS JOIN LATERAL (
+			SELECT h.status
+			FROM task_status_history h
+			WHERE h.task_id = t.id AND h.changed_at < d.day + 1
+			ORDER BY h.changed_at DESC, h.id DESC
+			LIMIT 1
+		) s
+		GROUP BY d.day, s.status
+		ORDER BY d.day
+	`, projectID, analytics.From, analytics.To)
+	if err != nil {
+		return fmt.Errorf("failed to get cumulative flow: %w", err)
+	}
+	defer rows.Close()
+
+	// Days without tasks have no rows but still get a point
+	points := map[string]map[string]int{}
+	for rows.Next() {
+		var day time.Time
+		var status string
+		var count int
+		if err := rows.Scan(&day, &status, &count); err != nil {
+			return fmt.Errorf("failed to scan cumulative flow: %w", err)
+		}
+		date := day.Format("2006-01-02")
+		if points[date] == nil {
+			points[date] = map[string]int{}
+		}
+		points[date][status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate cumulative flow: %w", err)
+	}
+
+	for day := dateOf(from); !day.After(dateOf(to)); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		byStatus := map[string]int{models.StatusTodo: 0, models.StatusInProgress: 0, models.StatusDone: 0}
+		for status, count := range points[date] {
+			byStatus[status] = count
+		}
+		analytics.CumulativeFlow = append(analytics.CumulativeFlow, models.FlowPoint{Date: date, ByStatus: byStatus})
+	}
+	return nil
+}
+
+// flowTimes averages lead and cycle time over the tasks completed in the
+// range. A task reopened and completed again counts from its last
+// completion; cycle time starts when work on it first started.
+func flowTimes(tx *sql.Tx, projectID string, from, to time.Time, analytics *models.ProjectAnalytics) error {
+	var leadHours, cycleHours sql.NullFloat64
+	err := tx.QueryRow(`
+		WITH completed AS (
+			SELECT t.created_at,
+			       (SELECT MAX(h.changed_at) FROM task_status_history h
+			        WHERE h.task_id = t.id AND h.status = 'done') AS done_at,
+			       (SELECT MIN(h.changed_at) FROM task_status_history h
+			        WHERE h.task_id = t.id AND h.status = 'in-progress') AS started_at
+			FROM tasks t
+			WHERE t.project_id = $1 AND t.deleted_at IS NULL AND t.status = 'done'
+		)
+		SELECT AVG(EXTRACT(EPOCH FROM done_at - created_at)) / 3600,
+		       COUNT(*),
+		       AVG(EXTRACT(EPOCH FROM done_at - started_at)) FILTER (WHERE started_at < done_at) / 3600,
+		       COUNT(*) FILTER (WHERE started_at < done_at)
+		FROM completed
+		WHERE done_at >= $2::date AND done_at < $3::date + 1
+	`, projectID, analytics.From, analytics.To).Scan(&leadHours, &analytics.LeadTime.Tasks, &cycleHours, &analytics.CycleTime.Tasks)
+	if err != nil {
+		return fmt.Errorf("failed to get lead and cycle time: %w", err)
+	}
+	if leadHours.Valid {
+		hours := math.Round(leadHours.Float64*10) / 10
+		analytics.LeadTime.AverageHours = &hours
+	}
+	if cycleHours.Valid {
+		hours := math.Round(cycleHours.Float64*10) / 10
+		analytics.CycleTime.AverageHours = &hours
+	}
+	return nil
+}
+
+// burndown derives the open tasks left each day from the cumulative flow,
+// with an ideal line from the first day's count down to zero on the last
+func burndown(analytics *models.ProjectAnalytics) {
+	points := analytics.CumulativeFlow
+	if len(points) == 0 {
+		return
+	}
+	remaining := func(point models.FlowPoint) int {
+		open := 0
+		for status, count := range point.ByStatus {
+			if status != models.StatusDone {
+				open += count
+			}
+		}
+		return open
+	}
+
+	start := float64(remaining(points[0]))
+	for i, point := range points {
+		ideal := 0.0
+		if len(points) > 1 {
+			ideal = math.Round(start*(1-float64(i)/float64(len(points)-1))*10) / 10
+		}
+		analytics.Burndown = append(analytics.Burndown, models.BurndownPoint{
+			Date:      point.Date,
+			Remaining: remaining(point),
+			Ideal:     ideal,
+		})
+	}
+}
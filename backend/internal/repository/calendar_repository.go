@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// CalendarFeedRepository stores the secret iCalendar feeds of users
+type CalendarFeedRepository struct {
+	db *sql.DB
+}
+
+func NewCalendarFeedRepository(db *sql.DB) *CalendarFeedRepository {
+	return &CalendarFeedRepository{db: db}
+}
+
+const calendarFeedColumns = `f.id, f.user_id, f.project_id, p.name, f.created_at, f.last_used_at`
+
+const calendarFeedFrom = `
+	FROM calendar_feeds f
+	LEFT JOIN projects p ON p.id = f.project_id`
+
+// scanCalendarFeed scans a row selected with calendarFeedColumns
+func scanCalendarFeed(row rowScanner) (*models.CalendarFeed, error) {
+	feed := &models.CalendarFeed{}
+	var projectID, projectName sql.NullString
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(&feed.ID, &feed.UserID, &projectID, &projectName, &feed.CreatedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	if projectID.Valid {
+		feed.ProjectID = &projectID.String
+		feed.ProjectName = &projectName.String
+	}
+	if lastUsedAt.Valid {
+		feed.LastUsedAt = &lastUsedAt.Time
+	}
+	return feed, nil
+}
+
+// CreateFeed creates a user's feed of their assigned tasks, or of a project
+// if projectID is set. An existing feed of the same kind is replaced, which
+// revokes its token.
+func (r *CalendarFeedRepository) CreateFeed(feed *models.CalendarFeed, tokenHash string) error {
+	feed.ID = uuid.New().String()
+	feed.CreatedAt = time.Now()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		DELETE FROM calendar_feeds WHERE user_id = $1 AND project_id IS NOT DISTINCT FROM $2
+	`, feed.UserID, feed.ProjectID); err != nil {
+		return fmt.Errorf("failed to replace calendar feed: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO calendar_feeds (id, user_id, project_id, token_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, feed.ID, feed.UserID, feed.ProjectID, tokenHash, feed.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create calendar feed: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit calendar feed: %w", err)
+	}
+	return nil
+}
+
+// GetFeeds lists a user's feeds, the one of assigned tasks first
+func (r *CalendarFeedRepository) GetFeeds(userID string) ([]*models.CalendarFeed, error) {
+	rows, err := r.db.Query(`SELECT `+calendarFeedColumns+calendarFeedFrom+`
+		WHERE f.user_id = $1
+		ORDER BY f.project_id IS NOT NULL, p.name
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar feeds: %w", err)
+	}
+	defer rows.Close()
+
+	feeds := []*models.CalendarFeed{}
+	for rows.Next() {
+		feed, err := scanCalendarFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan calendar feed: %w", err)
+		}
+		feeds = append(feeds, feed)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate calendar feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+// GetFeedByTokenHash finds the feed a token opens and records that it was
+// used. Feeds of deleted users and projects are not found.
+func (r *CalendarFeedRepository) GetFeedByTokenHash(tokenHash string) (*models.CalendarFeed, error) {
+	feed, err := scanCalendarFeed(r.db.QueryRow(`
+		WITH used AS (
+			UPDATE calendar_feeds SET last_used_at = NOW() WHERE token_hash = $1
+			RETURNING id, user_id, project_id, created_at, last_used_at
+		)
+		SELECT f.id, f.user_id, f.project_id, p.name, f.created_at, f.last_used_at
+		FROM used f
+		INNER JOIN users u ON u.id = f.user_id AND u.deleted_at IS NULL
+		LEFT JOIN projects p ON p.id = f.project_id
+		WHERE f.project_id IS NULL OR p.deleted_at IS NULL
+	`, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("calendar feed not found")
+		}
+		return nil, fmt.Errorf("failed to get calendar feed: %w", err)
+	}
+	return feed, nil
+}
+
+// DeleteFeed revokes one of the user's feeds
+func (r *CalendarFeedRepository) DeleteFeed(userID, id string) error {
+	result, err := r.db.Exec(`DELETE FROM calendar_feeds WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar feed: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("calendar feed not found")
+	}
+	return nil
+}
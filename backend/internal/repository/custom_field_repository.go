@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type CustomFieldRepository struct {
+	db *sql.DB
+}
+
+func NewCustomFieldRepository(db *sql.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+const customFieldColumns = `id, project_id, name, field_type, options, required, validation, position, created_at, updated_at`
+
+// scanCustomField scans a row selected with customFieldColumns
+func scanCustomField(row rowScanner) (*models.CustomField, error) {
+	field := &models.CustomField{}
+	var options, validation []byte
+	var updatedAt sql.NullTime
+
+	err := row.Scan(
+		&field.ID,
+		&field.ProjectID,
+		&field.Name,
+		&field.Type,
+		&options,
+		&field.Required,
+		&validation,
+		&field.Position,
+		&field.CreatedAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(options, &field.Options); err != nil {
+		return nil, fmt.Errorf("failed to decode options: %w", err)
+	}
+	if err := json.Unmarshal(validation, &field.Validation); err != nil {
+		return nil, fmt.Errorf("failed to decode validation: %w", err)
+	}
+	if updatedAt.Valid {
+		field.UpdatedAt = &updatedAt.Time
+	}
+	if field.Options == nil {
+		field.Options = []string{}
+	}
+	return field, nil
+}
+
+// CreateField creates a custom field for a project
+func (r *CustomFieldRepository) CreateField(field *models.CustomField) error {
+	field.ID = uuid.New().String()
+	now := time.Now()
+	field.CreatedAt = now
+	field.UpdatedAt = &now
+
+	options, err := json.Marshal(field.Options)
+	if err != nil {
+		return fmt.Errorf("failed to encode options: %w", err)
+	}
+	validation, err := json.Marshal(field.Validation)
+	if err != nil {
+		return fmt.Errorf("failed to encode validation: %w", err)
+	}
+
+	query := `
+		INSERT INTO project_custom_fields (id, project_id, name, field_type, options, required, validation, position, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err = r.db.Exec(query, field.ID, field.ProjectID, field.Name, field.Type, options, field.Required,
+		validation, field.Position, field.CreatedAt, field.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create custom field: %w", err)
+	}
+	return nil
+}
+
+// GetFieldsByProjectID retrieves a project's custom fields in display order
+func (r *CustomFieldRepository) GetFieldsByProjectID(projectID string) ([]*models.CustomField, error) {
+	query := `SELECT ` + customFieldColumns + ` FROM project_custom_fields WHERE project_id = $1 ORDER BY position, created_at`
+	rows, err := r.db.Query(query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := []*models.CustomField{}
+	for rows.Next() {
+		field, err := scanCustomField(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		fields = append(fields, field)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate custom fields: %w", err)
+	}
+	return fields, nil
+}
+
+// GetFieldByID retrieves a custom field of a project
+func (r *CustomFieldRepository) GetFieldByID(projectID, fieldID string) (*models.CustomField, error) {
+	query := `SELECT ` + customFieldColumns + ` FROM project_custom_fields WHERE id = $1 AND project_id = $2`
+	field, err := scanCustomField(r.db.QueryRow(query, fieldID, projectID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("custom field not found")
+		}
+		return nil, fmt.Errorf("failed to get custom field: %w", err)
+	}
+	return field, nil
+}
+
+// UpdateField updates a custom field. The type of a field cannot change.
+func (r *CustomFieldRepository) UpdateField(field *models.CustomField) error {
+	now := time.Now()
+	field.UpdatedAt = &now
+
+	options, err := json.Marshal(field.Options)
+	if err != nil {
+		return fmt.Errorf("failed to encode options: %w", err)
+	}
+	validation, err := json.Marshal(field.Validation)
+	if err != nil {
+		return fmt.Errorf("failed to encode validation: %w", err)
+	}
+
+	query := `
+		UPDATE project_custom_fields
+		SET name = $1, options = $2, required = $3, validation = $4, position = $5, updated_at = $6
+		WHERE id = $7 AND project_id = $8
+	`
+	_, err = r.db.Exec(query, field.Name, options, field.Required, validation, field.Position, field.UpdatedAt, field.ID, field.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to update custom field: %w", err)
+	}
+	return nil
+}
+
+// DeleteField deletes a custom field and removes its values from the project's tasks
+func (r *CustomFieldRepository) DeleteField(projectID, fieldID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM project_custom_fields WHERE id = $1 AND project_id = $2`, fieldID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete custom field: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("custom field not found")
+	}
+
+	_, err = tx.Exec(`UPDATE tasks SET custom_fields = custom_fields - $1::text WHERE project_id = $2 AND custom_fields ? $1::text`, fieldID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to remove custom field values: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit custom field deletion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"task-management/internal/models"
+)
+
+type GitIntegrationRepository struct {
+	db *sql.DB
+}
+
+func NewGitIntegrationRepository(db *sql.DB) *GitIntegrationRepository {
+	return &GitIntegrationRepository{db: db}
+}
+
+// GetIntegration returns the Git integration of a project
+func (r *GitIntegrationRepository) GetIntegration(projectID string) (*models.GitIntegration, error) {
+	integration := &models.GitIntegration{}
+	var keywords []byte
+	var updatedAt sql.NullTime
+
+	err := r.db.QueryRow(`
+		SELECT project_id, secret, keywords, active, created_at, updated_at
+		FROM git_integrations
+		WHERE project_id = $1
+	`, projectID).Scan(&integration.ProjectID, &integration.Secret, &keywords, &integration.Active, &integration.CreatedAt, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("git integration not found")
+		}
+		return nil, fmt.Errorf("failed to get git integration: %w", err)
+	}
+
+	if err := json.Unmarshal(keywords, &integration.Keywords); err != nil {
+		return nil, fmt.Errorf("failed to decode keywords: %w", err)
+	}
+	if updatedAt.Valid {
+		integration.UpdatedAt = &updatedAt.Time
+	}
+	return integration, nil
+}
+
+// SaveIntegration creates or updates the Git integration of a project
+func (r *GitIntegrationRepository) SaveIntegration(integration *models.GitIntegration) error {
+	keywords, err := json.Marshal(integration.Keywords)
+	if err != nil {
+		return fmt.Errorf("failed to encode keywords: %w", err)
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO git_integrations (project_id, secret, keywords, active)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id) DO UPDATE
+		SET secret = EXCLUDED.secret, keywords = EXCLUDED.keywords, active = EXCLUDED.active, updated_at = NOW()
+	`, integration.ProjectID, integration.Secret, keywords, integration.Active)
+	if err != nil {
+		return fmt.Errorf("failed to save git integration: %w", err)
+	}
+	return nil
+}
+
+// DeleteIntegration removes the Git integration of a project
+func (r *GitIntegrationRepository) DeleteIntegration(projectID string) error {
+	result, err := r.db.Exec(`DELETE FROM git_integrations WHERE project_id = $1`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete git integration: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("git integration not found")
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// importProgressEvery is how many tasks are inserted between progress updates
+const importProgressEvery = 50
+
+type ImportRepository struct {
+	db *sql.DB
+}
+
+func NewImportRepository(db *sql.DB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+// ImportTasks inserts the tasks in a single transaction: either all of them
+// are created or none
+func (r *ImportRepository) ImportTasks(tasks []*models.Task) error {
+	numbers, err := reserveImportNumbers(r.db, tasks)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, task := range tasks {
+		if _, err := insertNumberedTask(tx, task, numbers.next(task.ProjectID)); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// CreateImport stores validated tasks and queues the job that imports them
+func (r *ImportRepository) CreateImport(imp *models.TaskImport, tasks []*models.Task, jobType string) error {
+	imp.ID = uuid.New().String()
+	imp.Status = models.ImportPending
+	imp.Total = len(tasks)
+	imp.CreatedAt = time.Now()
+
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		return fmt.Errorf("failed to encode tasks: %w", err)
+	}
+	jobPayload, err := json.Marshal(map[string]string{"import_id": imp.ID})
+	if err != nil {
+		return fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO task_imports (id, project_id, user_id, format, status, total, tasks, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, imp.ID, imp.ProjectID, imp.UserID, imp.Format, imp.Status, imp.Total, data, imp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create import: %w", err)
+	}
+
+	// A failed import is reported on the import, not retried
+	if _, err := enqueueJob(tx, jobType, jobPayload, imp.CreatedAt, 1, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// GetImport returns an import of a project
+func (r *ImportRepository) GetImport(projectID, id string) (*models.TaskImport, error) {
+	imp := &models.TaskImport{}
+	var userID, importError sql.NullString
+	var finishedAt sql.NullTime
+
+	err := r.db.QueryRow(`
+		SELECT id, project_id, user_id, format, status, total, processed, error, created_at, finished_at
+		FROM task_imports
+		WHERE id = $1 AND project_id = $2
+	`, id, projectID).Scan(&imp.ID, &imp.ProjectID, &userID, &imp.Format, &imp.Status, &imp.Total, &imp.Processed,
+		&importError, &imp.CreatedAt, &finishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("import not found")
+		}
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+
+	if userID.Valid {
+		imp.UserID = &userID.String
+	}
+	if importError.Valid {
+		imp.Error = &importError.String
+	}
+	if finishedAt.Valid {
+		imp.FinishedAt = &finishedAt.Time
+	}
+	return imp, nil
+}
+
+// RunImport inserts the tasks of a pending import in one transaction. The
+// processed count is updated outside the transaction as it goes so the
+// import can be watched; the import is marked done in the same transaction
+// as the inserts, so a repeated run does nothing.
+//
+// Task numbers are reserved before the transaction opens: inserting with the
+// project's counter would keep the project locked, and every task created
+// in it waiting, until a large import finishes. Numbers reserved by an
+// import that fails are not reused.
+func (r *ImportRepository) RunImport(id string) error {
+	var status string
+	var data []byte
+	err := r.db.QueryRow(`SELECT status, tasks FROM task_imports WHERE id = $1`, id).Scan(&status, &data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("import not found")
+		}
+		return fmt.Errorf("failed to get import: %w", err)
+	}
+	if status == models.ImportDone {
+		return nil
+	}
+
+	var tasks []*models.Task
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("failed to decode tasks: %w", err)
+	}
+
+	if _, err := r.db.Exec(`UPDATE task_imports SET status = $1, processed = 0, started_at = NOW() WHERE id = $2`,
+		models.ImportRunning, id); err != nil {
+		return fmt.Errorf("failed to start import: %w", err)
+	}
+
+	numbers, err := reserveImportNumbers(r.db, tasks)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, task := range tasks {
+		if _, err := insertNumberedTask(tx, task, numbers.next(task.ProjectID)); err != nil {
+			return err
+		}
+		if (i+1)%importProgressEvery == 0 {
+			if _, err := r.db.Exec(`UPDATE task_imports SET processed = $1 WHERE id = $2`, i+1, id); err != nil {
+				return fmt.Errorf("failed to update import progress: %w", err)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE task_imports SET status = $1, processed = total, error = NULL, finished_at = NOW(), tasks = '[]'
+		WHERE id = $2
+	`, models.ImportDone, id)
+	if err != nil {
+		return fmt.Errorf("failed to finish import: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// importNumbers hands out task numbers reserved for an import, per project
+type importNumbers map[string]int
+
+// reserveImportNumbers reserves a block of numbers in each project the tasks
+// belong to
+func reserveImportNumbers(db queryer, tasks []*models.Task) (importNumbers, error) {
+	counts := map[string]int{}
+	for _, task := range tasks {
+		counts[task.ProjectID]++
+	}
+	numbers := importNumbers{}
+	for projectID, count := range counts {
+		first, err := reserveTaskNumbers(db, projectID, count)
+		if err != nil {
+			return nil, err
+		}
+		numbers[projectID] = first
+	}
+	return numbers, nil
+}
+
+// next returns the project's next reserved number
+func (n importNumbers) next(projectID string) int {
+	number := n[projectID]
+	n[projectID]++
+	return number
+}
+
+// FailImport marks an import failed; none of its tasks were created
+func (r *ImportRepository) FailImport(id, message string) error {
+	_, err := r.db.Exec(`
+		UPDATE task_imports SET status = $1, processed = 0, error = $2, finished_at = NOW() WHERE id = $3
+	`, models.ImportFailed, message, id)
+	if err != nil {
+		return fmt.Errorf("failed to update import: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// JobRepository stores the background job queue. Workers on any number of
+// server replicas claim jobs with SELECT ... FOR UPDATE SKIP LOCKED, so each
+// job runs on one worker at a time.
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+const jobColumns = `id, type, payload, status, attempts, max_attempts, run_at, locked_by, last_error, created_at, updated_at`
+
+// scanJob scans a row selected with jobColumns
+func scanJob(row rowScanner) (*models.Job, error) {
+	job := &models.Job{}
+	var lockedBy, lastError sql.NullString
+	var updatedAt sql.NullTime
+
+	err := row.Scan(&job.ID, &job.Type, &job.Payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt,
+		&lockedBy, &lastError, &job.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lockedBy.Valid {
+		job.LockedBy = &lockedBy.String
+	}
+	if lastError.Valid {
+		job.LastError = &lastError.String
+	}
+	if updatedAt.Valid {
+		job.UpdatedAt = &updatedAt.Time
+	}
+	return job, nil
+}
+
+// Enqueue adds a job that becomes runnable at runAt. A non-empty dedupeKey
+// makes the call idempotent: a second job with the same key is not added and
+// Enqueue reports false.
+func (r *JobRepository) Enqueue(jobType string, payload []byte, runAt time.Time, maxAttempts int, dedupeKey string) (bool, error) {
+	return enqueueJob(r.db, jobType, payload, runAt, maxAttempts, dedupeKey)
+}
+
+// enqueueJob inserts a job with db, which may be a transaction so the job is
+// only queued if the surrounding change commits
+func enqueueJob(db execer, jobType string, payload []byte, runAt time.Time, maxAttempts int, dedupeKey string) (bool, error) {
+	if payload == nil {
+		payload = []byte("{}")
+	}
+	var key interface{}
+	if dedupeKey != "" {
+		key = dedupeKey
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO jobs (id, type, payload, status, max_attempts, run_at, dedupe_key)
+		VALUES ($1, $2, $3, 'pending', $4, $5, $6)
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`, uuid.New().String(), jobType, payload, maxAttempts, runAt, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// Claim locks the next runnable job for workerID and marks it running. It
+// returns nil when no job is due.
+func (r *JobRepository) Claim(workerID string) (*models.Job, error) {
+	row := r.db.QueryRow(`
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_by = $1, locked_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'pending' AND run_at <= NOW()
+			ORDER BY run_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+jobColumns, workerID)
+
+	job, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	return job, nil
+}
+
+// Complete marks a claimed job as done
+func (r *JobRepository) Complete(id string) error {
+	_, err := r.db.Exec(`
+		UPDATE jobs SET status = 'done', locked_by = NULL, locked_at = NULL, last_error = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return nil
+}
+
+// Fail records a failed attempt. The job is retried at retryAt, or moved to
+// the dead letter state once it has used all of its attempts.
+func (r *JobRepository) Fail(id string, jobErr error, retryAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+		    run_at = CASE WHEN attempts >= max_attempts THEN run_at ELSE $2 END,
+		    last_error = $3, locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, retryAt, jobErr.Error())
+	if err != nil {
+		return fmt.Errorf("failed to record job failure: %w", err)
+	}
+	return nil
+}
+
+// Release puts a claimed job back in the queue without counting the
+// attempt, e.g. when the worker is shutting down
+func (r *JobRepository) Release(id string) error {
+	_, err := r.db.Exec(`
+		UPDATE jobs SET status = 'pending', attempts = GREATEST(attempts - 1, 0), locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	return nil
+}
+
+// RequeueStale returns jobs that have been running for longer than timeout
+// to the queue. Their worker is assumed to have crashed.
+func (r *JobRepository) RequeueStale(timeout time.Duration) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+		    last_error = 'worker stopped responding', locked_by = NULL, locked_at = NULL, updated_at = NOW()
+		WHERE status = 'running' AND locked_at < $1
+	`, time.Now().Add(-timeout))
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteFinished removes done jobs last updated before before. Dead jobs are
+// kept until they are retried or deleted by an admin.
+func (r *JobRepository) DeleteFinished(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM jobs WHERE status = 'done' AND updated_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetJobs lists jobs, optionally filtered by status, newest first
+func (r *JobRepository) GetJobs(status string, limit int) ([]*models.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs`
+	args := []interface{}{}
+	if status != "" {
+		query += ` WHERE status = $1`
+		args = append(args, status)
+	}
+	query += fmt.Sprintf(` ORDER BY created_at DESC LIMIT %d`, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// Retry gives a dead job a fresh set of attempts
+func (r *JobRepository) Retry(id string) error {
+	result, err := r.db.Exec(`
+		UPDATE jobs SET status = 'pending', attempts = 0, run_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to retry job: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("dead job not found")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type MilestoneRepository struct {
+	db *sql.DB
+}
+
+func NewMilestoneRepository(db *sql.DB) *MilestoneRepository {
+	return &MilestoneRepository{db: db}
+}
+
+// milestoneStats selects a milestone with its progress roll-up. Only open
+// tasks count towards overdue and at-risk; a milestone without open tasks is
+// never at risk. Due dates are judged in the timezone $1 with the rule of
+// due.End, so callers' own parameters start at $2. Callers fill in the
+// WHERE clause of the roll-up.
+var milestoneStats = `
+	WITH stats AS (
+		SELECT m.id, m.project_id, m.title, m.description, m.due_date, m.created_at, m.updated_at,
+		       COUNT(t.id) AS total,
+		       COUNT(t.id) FILTER (WHERE t.status = 'done') AS done,
+		       COUNT(t.id) FILTER (WHERE t.status <> 'done' AND ` + dueEnd("$1") + ` <= NOW()) AS overdue,
+		       COUNT(t.id) FILTER (WHERE t.status <> 'done' AND ` + dueEnd("$1") + ` > (m.due_date + 1)::timestamp AT TIME ZONE $1) AS late
+		FROM milestones m
+		LEFT JOIN tasks t ON t.milestone_id = m.id AND t.deleted_at IS NULL
+		WHERE %s
+		GROUP BY m.id
+	), today AS (
+		SELECT (NOW() AT TIME ZONE $1)::date AS day
+	)
+	SELECT id, project_id, title, description, due_date, created_at, updated_at, total, done,
+	       CASE WHEN total = 0 THEN 0 ELSE ROUND(100.0 * done / total, 1) END,
+	       overdue,
+	       done < total AND due_date < today.day,
+	       done < total AND (
+	           due_date < today.day OR overdue > 0 OR late > 0
+	           OR (due_date <= today.day + 7 AND done * 2 < total)
+	       )
+	FROM stats, today`
+
+// scanMilestone scans a row selected with milestoneStats
+func scanMilestone(row rowScanner) (*models.Milestone, error) {
+	milestone := &models.Milestone{}
+	var dueDate, updatedAt sql.NullTime
+	var overdue, atRisk sql.NullBool
+
+	err := row.Scan(&milestone.ID, &milestone.ProjectID, &milestone.Title, &milestone.Description, &dueDate,
+		&milestone.CreatedAt, &updatedAt, &milestone.TaskCount, &milestone.DoneCount, &milestone.Progress,
+		&milestone.OverdueCount, &overdue, &atRisk)
+	if err != nil {
+		return nil, err
+	}
+	if dueDate.Valid {
+		milestone.DueDate = &dueDate.Time
+	}
+	if updatedAt.Valid {
+		milestone.UpdatedAt = &updatedAt.Time
+	}
+	// A milestone without a due date compares as NULL
+	milestone.Overdue = overdue.Valid && overdue.Bool
+	milestone.AtRisk = atRisk.Valid && atRisk.Bool
+	return milestone, nil
+}
+
+// CreateMilestone creates a milestone
+func (r *MilestoneRepository) CreateMilestone(milestone *models.Milestone) error {
+	milestone.ID = uuid.New().String()
+	now := time.Now()
+	milestone.CreatedAt = now
+	milestone.UpdatedAt = &now
+
+	_, err := r.db.Exec(`
+		INSERT INTO milestones (id, project_id, title, description, due_date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, milestone.ID, milestone.ProjectID, milestone.Title, milestone.Description, milestone.DueDate,
+		milestone.CreatedAt, milestone.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create milestone: %w", err)
+	}
+	return nil
+}
+
+// GetMilestones lists a project's milestones with their progress in loc, by
+// due date with undated milestones last
+func (r *MilestoneRepository) GetMilestones(projectID string, loc *time.Location) ([]*models.Milestone, error) {
+	rows, err := r.db.Query(fmt.Sprintf(milestoneStats, "m.project_id = $2")+`
+		ORDER BY due_date ASC NULLS LAST, created_at ASC
+	`, loc.String(), projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get milestones: %w", err)
+	}
+	defer rows.Close()
+
+	milestones := []*models.Milestone{}
+	for rows.Next() {
+		milestone, err := scanMilestone(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan milestone: %w", err)
+		}
+		milestones = append(milestones, milestone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate milestones: %w", err)
+	}
+	return milestones, nil
+}
+
+// GetMilestone retrieves a milestone of a project with its progress in loc
+func (r *MilestoneRepository) GetMilestone(projectID, id string, loc *time.Location) (*models.Milestone, error) {
+	milestone, err := scanMilestone(r.db.QueryRow(fmt.Sprintf(milestoneStats, "m.id = $2 AND m.project_id = $3"), loc.String(), id, projectID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("milestone not found")
+		}
+		return nil, fmt.Errorf("failed to get milestone: %w", err)
+	}
+	return milestone, nil
+}
+
+// UpdateMilestone changes a milestone's title, description and due date
+func (r *MilestoneRepository) UpdateMilestone(milestone *models.Milestone) error {
+	now := time.Now()
+	milestone.UpdatedAt = &now
+
+	result, err := r.db.Exec(`
+		UPDATE milestones SET title = $1, description = $2, due_date = $3, updated_at = $4
+		WHERE id = $5 AND project_id = $6
+	`, milestone.Title, milestone.Description, milestone.DueDate, milestone.UpdatedAt, milestone.ID, milestone.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to update milestone: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("milestone not found")
+	}
+	return nil
+}
+
+// DeleteMilestone deletes a milestone; its tasks are unlinked, not deleted
+func (r *MilestoneRepository) DeleteMilestone(projectID, id string) error {
+	result, err := r.db.Exec(`DELETE FROM milestones WHERE id = $1 AND project_id = $2`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete milestone: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("milestone not found")
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"task-management/internal/due"
+	"task-management/internal/models"
+)
+
+// NotificationRepository stores in-app notifications. Notifications created
+// by background jobs carry a dedupe key so re-running a job never notifies
+// a user twice about the same thing.
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// dueEnd is the moment a task t is due in the timezone tz, as due.End: a
+// task due all day is due at the end of its day in that timezone
+func dueEnd(tz string) string {
+	return `(CASE WHEN t.due_all_day
+	THEN ((t.due_date AT TIME ZONE 'UTC')::date + 1)::timestamp AT TIME ZONE ` + tz + `
+	ELSE t.due_date END)`
+}
+
+// dueAt is the moment a task is due for the user u
+var dueAt = dueEnd("u.timezone")
+
+// dueText shows a task's due date to the user u, in their timezone
+const dueText = `(CASE WHEN t.due_all_day
+	THEN TO_CHAR(t.due_date AT TIME ZONE 'UTC', 'YYYY-MM-DD')
+	ELSE TO_CHAR(t.due_date AT TIME ZONE u.timezone, 'YYYY-MM-DD HH24:MI') END)`
+
+// dueKey identifies a task's due date in dedupe keys, so moving the date
+// reminds again
+const dueKey = `(CASE WHEN t.due_all_day
+	THEN TO_CHAR(t.due_date AT TIME ZONE 'UTC', 'YYYY-MM-DD')
+	ELSE TO_CHAR(t.due_date AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI"Z"') END)`
+
+// openTasksFrom selects open, dated tasks in live, unarchived projects
+// together with the users to remind: the task's watchers that haven't muted
+// it and are still in the project
+const openTasksFrom = `
+	FROM tasks t
+	INNER JOIN projects p ON p.id = t.project_id AND p.deleted_at IS NULL AND p.archived_at IS NULL
+	INNER JOIN task_watchers w ON w.task_id = t.id AND NOT w.muted
+	INNER JOIN users u ON u.id = w.user_id AND u.deleted_at IS NULL
+	INNER JOIN project_members pm ON pm.project_id = t.project_id AND pm.user_id = u.id
+	WHERE t.deleted_at IS NULL AND t.status <> 'done' AND t.due_date IS NOT NULL`
+
+// CreateDueSoonReminders notifies watchers about open tasks that become due
+// within window. It returns the number of notifications created.
+func (r *NotificationRepository) CreateDueSoonReminders(window time.Duration) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO notifications (user_id, task_id, project_id, type, title, body, dedupe_key)
+		SELECT u.id, t.id, t.project_id, 'due_soon',
+		       'Task due soon: ' || t.title,
+		       'Due ' || `+dueText+` || ' in ' || p.name,
+		       'due_soon:' || t.id || ':' || u.id || ':' || `+dueKey+`
+		`+openTasksFrom+`
+		  AND `+dueAt+` > NOW() AND `+dueAt+` <= NOW() + MAKE_INTERVAL(secs => $1)
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`, window.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create due reminders: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreateOverdueAlerts notifies watchers once about each open task that is
+// past its due date
+func (r *NotificationRepository) CreateOverdueAlerts() (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO notifications (user_id, task_id, project_id, type, title, body, dedupe_key)
+		SELECT u.id, t.id, t.project_id, 'overdue',
+		       'Task overdue: ' || t.title,
+		       'Was due ' || ` + dueText + ` || ' in ' || p.name,
+		       'overdue:' || t.id || ':' || u.id || ':' || ` + dueKey + `
+		` + openTasksFrom + `
+		  AND ` + dueAt + ` <= NOW()
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create overdue alerts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DigestRecipient is a user who gets a daily digest
+type DigestRecipient struct {
+	UserID string
+	// Timezone is the user's timezone, which decides what is due today
+	Timezone string
+}
+
+// GetDigestRecipients returns the active users with at least one open task
+// assigned to them
+func (r *NotificationRepository) GetDigestRecipients() ([]DigestRecipient, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT u.id, u.timezone
+		FROM tasks t
+		INNER JOIN projects p ON p.id = t.project_id AND p.deleted_at IS NULL AND p.archived_at IS NULL
+		INNER JOIN users u ON u.id = t.assigned_to AND u.deleted_at IS NULL
+		WHERE t.deleted_at IS NULL AND t.status <> 'done'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []DigestRecipient
+	for rows.Next() {
+		var recipient DigestRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Timezone); err != nil {
+			return nil, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// maxDigestTasks bounds how many tasks a digest lists by name
+const maxDigestTasks = 10
+
+// CreateDigest creates the digest of open tasks assigned to the user for
+// day, whose location is the user's timezone. It reports false if the user
+// has no open tasks or already has a digest for that day.
+func (r *NotificationRepository) CreateDigest(userID string, day time.Time) (bool, error) {
+	rows, err := r.db.Query(`
+		SELECT t.title, p.name, t.due_date, t.due_all_day
+		FROM tasks t
+		INNER JOIN projects p ON p.id = t.project_id AND p.deleted_at IS NULL AND p.archived_at IS NULL
+		WHERE t.assigned_to = $1 AND t.deleted_at IS NULL AND t.status <> 'done'
+		ORDER BY t.due_date ASC NULLS LAST, t.created_at ASC
+	`, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get digest tasks: %w", err)
+	}
+	defer rows.Close()
+
+	today := day.Format(due.DateLayout)
+	now := time.Now()
+	var total, overdue, dueToday int
+	var lines []string
+	for rows.Next() {
+		var title, projectName string
+		var dueDate sql.NullTime
+		var allDay bool
+		if err := rows.Scan(&title, &projectName, &dueDate, &allDay); err != nil {
+			return false, fmt.Errorf("failed to scan digest task: %w", err)
+		}
+		total++
+
+		line := fmt.Sprintf("- %s (%s)", title, projectName)
+		if dueDate.Valid {
+			loc := day.Location()
+			switch {
+			case due.End(dueDate.Time, allDay, loc).Before(now):
+				overdue++
+				line += ", overdue since " + due.Display(dueDate.Time, allDay, loc)
+			case due.Day(dueDate.Time, allDay, loc) == today:
+				dueToday++
+				line += ", due today"
+			default:
+				line += ", due " + due.Display(dueDate.Time, allDay, loc)
+			}
+		}
+		if len(lines) < maxDigestTasks {
+			lines = append(lines, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to iterate digest tasks: %w", err)
+	}
+	if total == 0 {
+		return false, nil
+	}
+	if total > len(lines) {
+		lines = append(lines, fmt.Sprintf("...and %d more", total-len(lines)))
+	}
+
+	title := fmt.Sprintf("You have %d open task(s): %d overdue, %d due today", total, overdue, dueToday)
+	result, err := r.db.Exec(`
+		INSERT INTO notifications (user_id, type, title, body, dedupe_key)
+		VALUES ($1, 'digest', $2, $3, $4)
+		ON CONFLICT (dedupe_key) DO NOTHING
+	`, userID, title, strings.Join(lines, "\n"), "digest:"+userID+":"+today)
+	if err != nil {
+		return false, fmt.Errorf("failed to create digest: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// GetNotifications returns the user's most recent notifications
+func (r *NotificationRepository) GetNotifications(userID string, unreadOnly bool, limit int) ([]*models.Notification, error) {
+	query := `
+		SELECT id, user_id, task_id, project_id, type, title, body, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1`
+	if unreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := r.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*models.Notification{}
+	for rows.Next() {
+		n := &models.Notification{}
+		var taskID, projectID sql.NullString
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &taskID, &projectID, &n.Type, &n.Title, &n.Body, &readAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if taskID.Valid {
+			n.TaskID = &taskID.String
+		}
+		if projectID.Valid {
+			n.ProjectID = &projectID.String
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkRead marks one of the user's notifications as read
+func (r *NotificationRepository) MarkRead(id, userID string) error {
+	result, err := r.db.Exec(`
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("notification not found")
+	}
+	return nil
+}
+
+// MarkAllRead marks every notification of the user as read
+func (r *NotificationRepository) MarkAllRead(userID string) error {
+	_, err := r.db.Exec(`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return nil
+}
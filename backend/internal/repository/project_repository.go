@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"task-management/internal/models"
 	"time"
@@ -23,42 +24,53 @@ func (r *ProjectRepository) CreateProject(project *models.Project) error {
 	now := time.Now()
 	project.CreatedAt = now
 	project.UpdatedAt = &now
+	project.Version = 1
 
-	query := `INSERT INTO projects (id, name, description, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := r.db.Exec(query, project.ID, project.Name, project.Description, project.CreatedAt, project.UpdatedAt)
+	query := `INSERT INTO projects (id, name, description, key, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.Exec(query, project.ID, project.Name, project.Description, project.Key, project.CreatedAt, project.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
 	return nil
 }
 
-// GetProjectByID retrieves a project by ID
-func (r *ProjectRepository) GetProjectByID(projectID string) (*models.Project, error) {
-	query := `SELECT id, name, description, created_at, updated_at FROM projects WHERE id = $1`
+// projectColumns lists the columns selected for every project read. It must
+// be scanned with scanProject.
+const projectColumns = `p.id, p.name, p.description, p.key, p.created_at, p.updated_at, p.deleted_at, p.archived_at, p.version`
+
+// scanProject scans a row selected with projectColumns into a project
+func scanProject(row rowScanner) (*models.Project, error) {
 	project := &models.Project{}
-	err := r.db.QueryRow(query, projectID).Scan(
+	var description sql.NullString
+	var deletedAt sql.NullTime
+	var archivedAt sql.NullTime
+	err := row.Scan(
 		&project.ID,
 		&project.Name,
-		&project.Description,
+		&description,
+		&project.Key,
 		&project.CreatedAt,
 		&project.UpdatedAt,
+		&deletedAt,
+		&archivedAt,
+		&project.Version,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get project: %w", err)
+		return nil, err
+	}
+	project.Description = description.String
+	if deletedAt.Valid {
+		project.DeletedAt = &deletedAt.Time
+	}
+	if archivedAt.Valid {
+		project.ArchivedAt = &archivedAt.Time
 	}
 	return project, nil
 }
 
-// GetProjectsByUserID retrieves all projects a user is a member of
-func (r *ProjectRepository) GetProjectsByUserID(userID string) ([]*models.Project, error) {
-	query := `
-		SELECT p.id, p.name, p.description, p.created_at, p.updated_at 
-		FROM projects p
-		INNER JOIN project_members pm ON p.id = pm.project_id
-		WHERE pm.user_id = $1
-		ORDER BY p.created_at DESC
-	`
-	rows, err := r.db.Query(query, userID)
+// queryProjects runs a project query and scans all rows
+func (r *ProjectRepository) queryProjects(query string, args ...interface{}) ([]*models.Project, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get projects: %w", err)
 	}
@@ -66,83 +78,199 @@ func (r *ProjectRepository) GetProjectsByUserID(userID string) ([]*models.Projec
 
 	var projects []*models.Project
 	for rows.Next() {
-		project := &models.Project{}
-		err := rows.Scan(
-			&project.ID,
-			&project.Name,
-			&project.Description,
-			&project.CreatedAt,
-			&project.UpdatedAt,
-		)
+		project, err := scanProject(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan project: %w", err)
 		}
 		projects = append(projects, project)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate projects: %w", err)
+	}
 	return projects, nil
 }
 
-// GetAllProjects retrieves all projects (for system admin)
-func (r *ProjectRepository) GetAllProjects() ([]*models.Project, error) {
-	query := `SELECT id, name, description, created_at, updated_at FROM projects ORDER BY created_at DESC`
-	rows, err := r.db.Query(query)
+// GetProjectByID retrieves a project by ID
+func (r *ProjectRepository) GetProjectByID(projectID string) (*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects p WHERE p.id = $1 AND p.deleted_at IS NULL`
+	project, err := scanProject(r.db.QueryRow(query, projectID))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all projects: %w", err)
+		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
-	defer rows.Close()
+	return project, nil
+}
 
-	var projects []*models.Project
-	for rows.Next() {
-		project := &models.Project{}
-		err := rows.Scan(
-			&project.ID,
-			&project.Name,
-			&project.Description,
-			&project.CreatedAt,
-			&project.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan project: %w", err)
-		}
-		projects = append(projects, project)
+// GetDeletedProjectByID retrieves a soft-deleted project by ID
+func (r *ProjectRepository) GetDeletedProjectByID(projectID string) (*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects p WHERE p.id = $1 AND p.deleted_at IS NOT NULL`
+	project, err := scanProject(r.db.QueryRow(query, projectID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
-	return projects, nil
+	return project, nil
+}
+
+// GetProjectsByUserID retrieves all projects a user is a member of.
+// Archived projects are left out unless includeArchived is set.
+func (r *ProjectRepository) GetProjectsByUserID(userID string, includeArchived bool) ([]*models.Project, error) {
+	query := `
+		SELECT ` + projectColumns + `
+		FROM projects p
+		INNER JOIN project_members pm ON p.id = pm.project_id
+		WHERE pm.user_id = $1 AND p.deleted_at IS NULL AND ($2 OR p.archived_at IS NULL)
+		ORDER BY p.created_at DESC
+	`
+	return r.queryProjects(query, userID, includeArchived)
+}
+
+// GetAllProjects retrieves all projects (for system admin)
+func (r *ProjectRepository) GetAllProjects(includeArchived bool) ([]*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects p WHERE p.deleted_at IS NULL AND ($1 OR p.archived_at IS NULL) ORDER BY p.created_at DESC`
+	return r.queryProjects(query, includeArchived)
+}
+
+// GetDeletedProjects retrieves all soft-deleted projects (admin trash)
+func (r *ProjectRepository) GetDeletedProjects() ([]*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects p WHERE p.deleted_at IS NOT NULL ORDER BY p.deleted_at DESC`
+	return r.queryProjects(query)
 }
 
-// UpdateProject updates a project
+// UpdateProject updates a project if it is still at project.Version, and
+// returns ErrVersionConflict otherwise. project.Version is set to the new
+// version.
 func (r *ProjectRepository) UpdateProject(project *models.Project) error {
 	now := time.Now()
 	project.UpdatedAt = &now
 
-	query := `UPDATE projects SET name = $1, description = $2, updated_at = $3 WHERE id = $4`
-	_, err := r.db.Exec(query, project.Name, project.Description, project.UpdatedAt, project.ID)
+	query := `
+		UPDATE projects SET name = $1, description = $2, key = $3, updated_at = $4
+		WHERE id = $5 AND deleted_at IS NULL AND version = $6
+		RETURNING version
+	`
+	err := r.db.QueryRow(query, project.Name, project.Description, project.Key, project.UpdatedAt, project.ID, project.Version).Scan(&project.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		var version int
+		err := r.db.QueryRow(`SELECT version FROM projects WHERE id = $1 AND deleted_at IS NULL`, project.ID).Scan(&version)
+		if err == nil && version != project.Version {
+			return ErrVersionConflict
+		}
+		return fmt.Errorf("project not found")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update project: %w", err)
 	}
 	return nil
 }
 
-// DeleteProject deletes a project
+// SetArchived archives or unarchives a project. It reports false if the
+// project was already in that state.
+func (r *ProjectRepository) SetArchived(projectID string, archived bool) (bool, error) {
+	query := `UPDATE projects SET archived_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL AND archived_at IS NULL`
+	if !archived {
+		query = `UPDATE projects SET archived_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL AND archived_at IS NOT NULL`
+	}
+	result, err := r.db.Exec(query, projectID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update project: %w", err)
+	}
+	return rowsAffected(result) > 0, nil
+}
+
+// IsArchived reports whether a project is archived
+func (r *ProjectRepository) IsArchived(projectID string) (bool, error) {
+	var archived bool
+	err := r.db.QueryRow(`SELECT archived_at IS NOT NULL FROM projects WHERE id = $1 AND deleted_at IS NULL`, projectID).Scan(&archived)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("project not found")
+		}
+		return false, fmt.Errorf("failed to get project: %w", err)
+	}
+	return archived, nil
+}
+
+// AvailableProjectKey returns base, or base followed by the lowest number
+// that makes it unused (WEB, WEB2, WEB3, ...)
+func (r *ProjectRepository) AvailableProjectKey(base string) (string, error) {
+	rows, err := r.db.Query(`SELECT key FROM projects WHERE key = $1 OR key ~ ('^' || $1 || '[0-9]+$')`, base)
+	if err != nil {
+		return "", fmt.Errorf("failed to check project keys: %w", err)
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return "", fmt.Errorf("failed to scan project key: %w", err)
+		}
+		taken[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to check project keys: %w", err)
+	}
+
+	key := base
+	for n := 2; taken[key]; n++ {
+		key = fmt.Sprintf("%s%d", base, n)
+	}
+	return key, nil
+}
+
+// DeleteProject soft-deletes a project. Its tasks and members are kept so the
+// project can be restored until the purge job removes it.
 func (r *ProjectRepository) DeleteProject(projectID string) error {
-	query := `DELETE FROM projects WHERE id = $1`
-	_, err := r.db.Exec(query, projectID)
+	query := `UPDATE projects SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.db.Exec(query, projectID)
 	if err != nil {
 		return fmt.Errorf("failed to delete project: %w", err)
 	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("project not found")
+	}
 	return nil
 }
 
-// AddMember adds a user to a project with a specific role
-func (r *ProjectRepository) AddMember(projectID, userID, role string) error {
-	id := uuid.New().String()
-	query := `INSERT INTO project_members (id, project_id, user_id, role) VALUES ($1, $2, $3, $4)`
-	_, err := r.db.Exec(query, id, projectID, userID, role)
+// RestoreProject brings a soft-deleted project back from the trash
+func (r *ProjectRepository) RestoreProject(projectID string) error {
+	query := `UPDATE projects SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL`
+	result, err := r.db.Exec(query, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to restore project: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to add member: %w", err)
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("project not found in trash")
 	}
 	return nil
 }
 
+// PurgeDeletedProjects permanently removes projects that were soft-deleted
+// before the given time. Tasks and members are removed by the cascade.
+func (r *ProjectRepository) PurgeDeletedProjects(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge projects: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected, nil
+}
+
+// AddMember adds a user to a project with a specific role
+func (r *ProjectRepository) AddMember(projectID, userID, role string) error {
+	return addMember(r.db, projectID, userID, role)
+}
+
 // GetMemberRole retrieves a user's role in a project
 func (r *ProjectRepository) GetMemberRole(projectID, userID string) (string, error) {
 	query := `SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2`
@@ -160,7 +288,7 @@ func (r *ProjectRepository) GetProjectMembers(projectID string) ([]*models.Proje
 		SELECT pm.id, pm.project_id, pm.user_id, pm.role, pm.joined_at, u.name, u.email
 		FROM project_members pm
 		INNER JOIN users u ON pm.user_id = u.id
-		WHERE pm.project_id = $1
+		WHERE pm.project_id = $1 AND u.deleted_at IS NULL
 		ORDER BY pm.joined_at ASC
 	`
 	rows, err := r.db.Query(query, projectID)
@@ -189,22 +317,124 @@ func (r *ProjectRepository) GetProjectMembers(projectID string) ([]*models.Proje
 	return members, nil
 }
 
-// UpdateMemberRole updates a member's role in a project
+// ErrLastOwner is returned when a change would leave a project without a PO
+var ErrLastOwner = errors.New("project must keep at least one PO")
+
+// ErrMemberNotFound is returned when the user is not a member of the project
+var ErrMemberNotFound = errors.New("member not found")
+
+// lockMemberRole locks the project's PO rows and returns the member's current
+// role. Locking the PO rows serializes concurrent role changes, so two POs
+// cannot demote each other at the same time.
+func lockMemberRole(tx *sql.Tx, projectID, userID string) (string, int, error) {
+	rows, err := tx.Query(`SELECT user_id FROM project_members WHERE project_id = $1 AND role = $2 FOR UPDATE`, projectID, models.RolePO)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to lock owners: %w", err)
+	}
+	owners := 0
+	for rows.Next() {
+		owners++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", 0, fmt.Errorf("failed to iterate owners: %w", err)
+	}
+
+	var role string
+	err = tx.QueryRow(`SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2 FOR UPDATE`, projectID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", owners, ErrMemberNotFound
+		}
+		return "", owners, fmt.Errorf("failed to get member role: %w", err)
+	}
+	return role, owners, nil
+}
+
+// UpdateMemberRole updates a member's role in a project. Demoting the last PO is refused.
 func (r *ProjectRepository) UpdateMemberRole(projectID, userID, role string) error {
-	query := `UPDATE project_members SET role = $1 WHERE project_id = $2 AND user_id = $3`
-	_, err := r.db.Exec(query, role, projectID, userID)
+	tx, err := r.db.Begin()
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, owners, err := lockMemberRole(tx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if current == models.RolePO && role != models.RolePO && owners <= 1 {
+		return ErrLastOwner
+	}
+
+	query := `UPDATE project_members SET role = $1 WHERE project_id = $2 AND user_id = $3`
+	if _, err := tx.Exec(query, role, projectID, userID); err != nil {
 		return fmt.Errorf("failed to update member role: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role change: %w", err)
+	}
 	return nil
 }
 
-// RemoveMember removes a user from a project
+// RemoveMember removes a user from a project. Removing the last PO is refused.
 func (r *ProjectRepository) RemoveMember(projectID, userID string) error {
-	query := `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`
-	_, err := r.db.Exec(query, projectID, userID)
+	tx, err := r.db.Begin()
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, owners, err := lockMemberRole(tx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if current == models.RolePO && owners <= 1 {
+		return ErrLastOwner
+	}
+
+	query := `DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`
+	if _, err := tx.Exec(query, projectID, userID); err != nil {
 		return fmt.Errorf("failed to remove member: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit member removal: %w", err)
+	}
+	return nil
+}
+
+// TransferOwnership makes toUserID a PO and gives fromUserID the role
+// previousRole. Both users must already be members of the project.
+func (r *ProjectRepository) TransferOwnership(projectID, fromUserID, toUserID, previousRole string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	fromRole, _, err := lockMemberRole(tx, projectID, fromUserID)
+	if err != nil {
+		return err
+	}
+	if fromRole != models.RolePO {
+		return fmt.Errorf("only a PO can transfer ownership")
+	}
+	if _, _, err := lockMemberRole(tx, projectID, toUserID); err != nil {
+		return err
+	}
+
+	query := `UPDATE project_members SET role = $1 WHERE project_id = $2 AND user_id = $3`
+	if _, err := tx.Exec(query, models.RolePO, projectID, toUserID); err != nil {
+		return fmt.Errorf("failed to promote new owner: %w", err)
+	}
+	if _, err := tx.Exec(query, previousRole, projectID, fromUserID); err != nil {
+		return fmt.Errorf("failed to update previous owner: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit ownership transfer: %w", err)
+	}
 	return nil
 }
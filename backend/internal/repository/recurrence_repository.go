@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"task-management/internal/due"
+	"task-management/internal/models"
+	"task-management/internal/recurrence"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxCatchUpOccurrences bounds how many missed occurrences of one series a
+// single CreateDueOccurrences run creates, e.g. after a long outage
+const maxCatchUpOccurrences = 50
+
+// RecurrenceRepository manages recurring task series. A series stores the
+// rule, its anchor date and a template that every new occurrence copies.
+// Occurrences are ordinary tasks numbered by recurrence_index; the unique
+// (recurrence_id, recurrence_index) pair makes creating one idempotent across
+// restarts and server replicas.
+type RecurrenceRepository struct {
+	db *sql.DB
+}
+
+func NewRecurrenceRepository(db *sql.DB) *RecurrenceRepository {
+	return &RecurrenceRepository{db: db}
+}
+
+// series is a row of task_recurrences
+type series struct {
+	ID           string
+	ProjectID    string
+	CreatedBy    sql.NullString
+	Rule         string
+	StartsAt     time.Time
+	DueAllDay    bool
+	Title        string
+	Description  string
+	Priority     string
+	AssignedTo   sql.NullString
+	Labels       []string
+	CustomFields []byte
+}
+
+// insertSeries stores a new series using task as the template
+func insertSeries(tx *sql.Tx, task *models.Task, rule string) (string, error) {
+	if task.DueDate == nil {
+		return "", fmt.Errorf("recurring tasks need a due date")
+	}
+	customFields, err := json.Marshal(task.CustomFields)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode custom fields: %w", err)
+	}
+	labels := task.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	id := uuid.New().String()
+	_, err = tx.Exec(`
+		INSERT INTO task_recurrences (id, project_id, created_by, rule, starts_at, title, description, priority, assigned_to, labels, custom_fields,
+		                              due_all_day, timezone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, COALESCE((SELECT timezone FROM users WHERE id = $3), 'UTC'))
+	`, id, task.ProjectID, task.UserID, rule, *task.DueDate, task.Title, task.Description, task.Priority,
+		task.AssignedTo, pq.Array(labels), customFields, task.DueAllDay)
+	if err != nil {
+		return "", fmt.Errorf("failed to create recurrence: %w", err)
+	}
+	return id, nil
+}
+
+// seriesStart is the first due date of a series in the location its
+// occurrences are counted in. All-day series count calendar days; timed ones
+// follow the wall clock of the timezone they were created in, so a weekly
+// 09:00 deadline stays at 09:00 across daylight saving changes.
+func seriesStart(startsAt time.Time, allDay bool, timezone string) time.Time {
+	if allDay {
+		return startsAt.UTC()
+	}
+	return startsAt.In(due.Location(timezone))
+}
+
+// CreateSeries creates a recurring task: the series and its first occurrence
+func (r *RecurrenceRepository) CreateSeries(task *models.Task, rule string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	seriesID, err := insertSeries(tx, task, rule)
+	if err != nil {
+		return err
+	}
+
+	index := 0
+	task.RecurrenceID = &seriesID
+	task.RecurrenceIndex = &index
+	task.RecurrenceRule = &rule
+	if _, err := insertTask(tx, task); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recurring task: %w", err)
+	}
+	return nil
+}
+
+// Reschedule ends the task's current series from this occurrence on and, if
+// rule is not empty, starts a new series anchored at the task's due date
+// with the task as its first occurrence. Undone occurrences after the task
+// that were already created are moved to the trash.
+func (r *RecurrenceRepository) Reschedule(task *models.Task, rule string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if task.RecurrenceID != nil {
+		if _, err := tx.Exec(`UPDATE task_recurrences SET active = FALSE, updated_at = NOW() WHERE id = $1`, *task.RecurrenceID); err != nil {
+			return fmt.Errorf("failed to end recurrence: %w", err)
+		}
+		_, err := tx.Exec(`
+			UPDATE tasks SET deleted_at = NOW()
+			WHERE recurrence_id = $1 AND recurrence_index > $2 AND status <> 'done' AND deleted_at IS NULL
+		`, *task.RecurrenceID, *task.RecurrenceIndex)
+		if err != nil {
+			return fmt.Errorf("failed to remove future occurrences: %w", err)
+		}
+	}
+
+	if rule != "" {
+		seriesID, err := insertSeries(tx, task, rule)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE tasks SET recurrence_id = $1, recurrence_index = 0 WHERE id = $2`, seriesID, task.ID)
+		if err != nil {
+			return fmt.Errorf("failed to attach task to recurrence: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recurrence change: %w", err)
+	}
+	return nil
+}
+
+// UpdateFuture copies the task's title, description, priority, assignee,
+// labels and custom fields to its series template and to every later
+// occurrence that is not done yet
+func (r *RecurrenceRepository) UpdateFuture(task *models.Task) error {
+	if task.RecurrenceID == nil {
+		return fmt.Errorf("task is not recurring")
+	}
+
+	customFields, err := json.Marshal(task.CustomFields)
+	if err != nil {
+		return fmt.Errorf("failed to encode custom fields: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE task_recurrences
+		SET title = $1, description = $2, priority = $3, assigned_to = $4, labels = $5, custom_fields = $6, updated_at = NOW()
+		WHERE id = $7
+	`, task.Title, task.Description, task.Priority, task.AssignedTo, pq.Array(task.Labels), customFields, *task.RecurrenceID)
+	if err != nil {
+		return fmt.Errorf("failed to update recurrence: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE tasks
+		SET title = $1, description = $2, priority = $3, assigned_to = $4, labels = $5, custom_fields = $6, updated_at = NOW()
+		WHERE recurrence_id = $7 AND recurrence_index > $8 AND status <> 'done' AND deleted_at IS NULL
+	`, task.Title, task.Description, task.Priority, task.AssignedTo, pq.Array(task.Labels), customFields,
+		*task.RecurrenceID, *task.RecurrenceIndex)
+	if err != nil {
+		return fmt.Errorf("failed to update future occurrences: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recurrence update: %w", err)
+	}
+	return nil
+}
+
+// EnsureOccurrence creates the index-th occurrence of a series unless it
+// already exists (including in the trash). It reports whether a task was
+// created. A series whose rule has run out is deactivated.
+func (r *RecurrenceRepository) EnsureOccurrence(seriesID string, index int) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the series serializes concurrent creators
+	s := &series{}
+	var timezone string
+	err = tx.QueryRow(`
+		SELECT rs.id, rs.project_id, rs.created_by, rs.rule, rs.starts_at, rs.due_all_day, rs.timezone, rs.title,
+		       COALESCE(rs.description, ''), rs.priority, rs.assigned_to, rs.labels, rs.custom_fields
+		FROM task_recurrences rs
+		INNER JOIN projects p ON p.id = rs.project_id
+		WHERE rs.id = $1 AND rs.active AND p.deleted_at IS NULL AND p.archived_at IS NULL
+		FOR UPDATE OF rs
+	`, seriesID).Scan(&s.ID, &s.ProjectID, &s.CreatedBy, &s.Rule, &s.StartsAt, &s.DueAllDay, &timezone, &s.Title,
+		&s.Description, &s.Priority, &s.AssignedTo, pq.Array(&s.Labels), &s.CustomFields)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get recurrence: %w", err)
+	}
+
+	rule, err := recurrence.Parse(s.Rule)
+	if err != nil {
+		return false, fmt.Errorf("invalid rule on recurrence %s: %w", s.ID, err)
+	}
+
+	dueDate, ok := rule.Occurrence(seriesStart(s.StartsAt, s.DueAllDay, timezone), index)
+	if !ok {
+		if _, err := tx.Exec(`UPDATE task_recurrences SET active = FALSE, updated_at = NOW() WHERE id = $1`, s.ID); err != nil {
+			return false, fmt.Errorf("failed to end recurrence: %w", err)
+		}
+		return false, tx.Commit()
+	}
+
+	// New occurrences belong to whoever owns the latest one
+	var ownerID sql.NullString
+	err = tx.QueryRow(`
+		SELECT user_id FROM tasks WHERE recurrence_id = $1 ORDER BY recurrence_index DESC LIMIT 1
+	`, s.ID).Scan(&ownerID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to get recurrence owner: %w", err)
+	}
+	if !ownerID.Valid {
+		ownerID = s.CreatedBy
+	}
+	if !ownerID.Valid {
+		return false, nil
+	}
+
+	task := &models.Task{
+		ProjectID:       s.ProjectID,
+		UserID:          ownerID.String,
+		Title:           s.Title,
+		Description:     s.Description,
+		Status:          "todo",
+		Priority:        s.Priority,
+		DueDate:         &dueDate,
+		DueAllDay:       s.DueAllDay,
+		Labels:          s.Labels,
+		RecurrenceID:    &s.ID,
+		RecurrenceIndex: &index,
+	}
+	if err := json.Unmarshal(s.CustomFields, &task.CustomFields); err != nil {
+		return false, fmt.Errorf("failed to decode custom fields: %w", err)
+	}
+
+	// Only keep the assignee while they are still a member of the project
+	if s.AssignedTo.Valid {
+		var member bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2)`,
+			s.ProjectID, s.AssignedTo.String).Scan(&member)
+		if err != nil {
+			return false, fmt.Errorf("failed to check assignee: %w", err)
+		}
+		if member {
+			task.AssignedTo = &s.AssignedTo.String
+		}
+	}
+
+	created, err := insertTask(tx, task)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit occurrence: %w", err)
+	}
+	return created, nil
+}
+
+// CreateDueOccurrences creates the next occurrence of every active series
+// whose due date falls within window from now, catching up on missed ones
+func (r *RecurrenceRepository) CreateDueOccurrences(window time.Duration) (int, error) {
+	rows, err := r.db.Query(`
+		SELECT rs.id, rs.rule, rs.starts_at, rs.due_all_day, rs.timezone, COALESCE(MAX(t.recurrence_index), -1)
+		FROM task_recurrences rs
+		INNER JOIN projects p ON p.id = rs.project_id
+		LEFT JOIN tasks t ON t.recurrence_id = rs.id
+		WHERE rs.active AND p.deleted_at IS NULL AND p.archived_at IS NULL
+		GROUP BY rs.id, rs.rule, rs.starts_at, rs.due_all_day, rs.timezone
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recurrences: %w", err)
+	}
+
+	type pending struct {
+		id       string
+		rule     *recurrence.Rule
+		startsAt time.Time
+		last     int
+	}
+	var due []pending
+	for rows.Next() {
+		var p pending
+		var rule, timezone string
+		var allDay bool
+		if err := rows.Scan(&p.id, &rule, &p.startsAt, &allDay, &timezone, &p.last); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan recurrence: %w", err)
+		}
+		p.startsAt = seriesStart(p.startsAt, allDay, timezone)
+		p.rule, err = recurrence.Parse(rule)
+		if err != nil {
+			log.Printf("Skipping recurrence %s with invalid rule %q: %v", p.id, rule, err)
+			continue
+		}
+		due = append(due, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate recurrences: %w", err)
+	}
+
+	horizon := time.Now().Add(window)
+	created := 0
+	for _, p := range due {
+		for i := 1; i <= maxCatchUpOccurrences; i++ {
+			next := p.last + i
+			dueDate, ok := p.rule.Occurrence(p.startsAt, next)
+			if ok && dueDate.After(horizon) {
+				break
+			}
+			// A finished rule is handled by EnsureOccurrence, which ends the series
+			made, err := r.EnsureOccurrence(p.id, next)
+			if err != nil {
+				return created, err
+			}
+			if made {
+				created++
+			}
+			if !ok {
+				break
+			}
+		}
+	}
+	return created, nil
+}
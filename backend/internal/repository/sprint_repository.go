@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrSprintActive is returned when a project already has an active sprint
+var ErrSprintActive = errors.New("project already has an active sprint")
+
+type SprintRepository struct {
+	db *sql.DB
+}
+
+func NewSprintRepository(db *sql.DB) *SprintRepository {
+	return &SprintRepository{db: db}
+}
+
+// sprintColumns lists the columns selected for every sprint read, including
+// task counts. It must be used with sprintFrom and scanned with scanSprint.
+const sprintColumns = `
+	s.id, s.project_id, s.name, s.goal, s.start_date, s.end_date, s.state, s.carried_over,
+	s.started_at, s.closed_at, s.created_at, s.updated_at,
+	COUNT(t.id), COUNT(t.id) FILTER (WHERE t.status = 'done')`
+
+const sprintFrom = `
+	FROM sprints s
+	LEFT JOIN tasks t ON t.sprint_id = s.id AND t.deleted_at IS NULL`
+
+const sprintGroupBy = ` GROUP BY s.id`
+
+// scanSprint scans a row selected with sprintColumns
+func scanSprint(row rowScanner) (*models.Sprint, error) {
+	sprint := &models.Sprint{}
+	var startDate, endDate, startedAt, closedAt, updatedAt sql.NullTime
+
+	err := row.Scan(&sprint.ID, &sprint.ProjectID, &sprint.Name, &sprint.Goal, &startDate, &endDate, &sprint.State,
+		&sprint.CarriedOver, &startedAt, &closedAt, &sprint.CreatedAt, &updatedAt, &sprint.TaskCount, &sprint.DoneCount)
+	if err != nil {
+		return nil, err
+	}
+	if startDate.Valid {
+		sprint.StartDate = &startDate.Time
+	}
+	if endDate.Valid {
+		sprint.EndDate = &endDate.Time
+	}
+	if startedAt.Valid {
+		sprint.StartedAt = &startedAt.Time
+	}
+	if closedAt.Valid {
+		sprint.ClosedAt = &closedAt.Time
+	}
+	if updatedAt.Valid {
+		sprint.UpdatedAt = &updatedAt.Time
+	}
+	return sprint, nil
+}
+
+// CreateSprint creates a planned sprint
+func (r *SprintRepository) CreateSprint(sprint *models.Sprint) error {
+	sprint.ID = uuid.New().String()
+	sprint.State = models.SprintPlanned
+	now := time.Now()
+	sprint.CreatedAt = now
+	sprint.UpdatedAt = &now
+
+	_, err := r.db.Exec(`
+		INSERT INTO sprints (id, project_id, name, goal, start_date, end_date, state, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, sprint.ID, sprint.ProjectID, sprint.Name, sprint.Goal, sprint.StartDate, sprint.EndDate, sprint.State,
+		sprint.CreatedAt, sprint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sprint: %w", err)
+	}
+	return nil
+}
+
+// GetSprints lists a project's sprints by start date, undated ones last
+func (r *SprintRepository) GetSprints(projectID string) ([]*models.Sprint, error) {
+	rows, err := r.db.Query(`SELECT `+sprintColumns+sprintFrom+`
+		WHERE s.project_id = $1`+sprintGroupBy+`
+		ORDER BY s.start_date ASC NULLS LAST, s.created_at ASC
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sprints: %w", err)
+	}
+	defer rows.Close()
+
+	sprints := []*models.Sprint{}
+	for rows.Next() {
+		sprint, err := scanSprint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sprint: %w", err)
+		}
+		sprints = append(sprints, sprint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sprints: %w", err)
+	}
+	return sprints, nil
+}
+
+// GetSprint retrieves a sprint of a project
+func (r *SprintRepository) GetSprint(projectID, id string) (*models.Sprint, error) {
+	sprint, err := scanSprint(r.db.QueryRow(`SELECT `+sprintColumns+sprintFrom+`
+		WHERE s.id = $1 AND s.project_id = $2`+sprintGroupBy, id, projectID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("sprint not found")
+		}
+		return nil, fmt.Errorf("failed to get sprint: %w", err)
+	}
+	return sprint, nil
+}
+
+// UpdateSprint changes the name, goal and dates of a sprint that isn't closed
+func (r *SprintRepository) UpdateSprint(sprint *models.Sprint) error {
+	now := time.Now()
+	sprint.UpdatedAt = &now
+
+	result, err := r.db.Exec(`
+		UPDATE sprints SET name = $1, goal = $2, start_date = $3, end_date = $4, updated_at = $5
+		WHERE id = $6 AND project_id = $7 AND state <> 'closed'
+	`, sprint.Name, sprint.Goal, sprint.StartDate, sprint.EndDate, sprint.UpdatedAt, sprint.ID, sprint.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to update sprint: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("sprint not found or closed")
+	}
+	return nil
+}
+
+// DeleteSprint deletes a planned sprint; its tasks return to the backlog
+func (r *SprintRepository) DeleteSprint(projectID, id string) error {
+	result, err := r.db.Exec(`DELETE FROM sprints WHERE id = $1 AND project_id = $2 AND state = 'planned'`, id, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sprint: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("sprint not found or not planned")
+	}
+	return nil
+}
+
+// StartSprint makes a planned sprint the project's active sprint
+func (r *SprintRepository) StartSprint(projectID, id string) error {
+	result, err := r.db.Exec(`
+		UPDATE sprints SET state = 'active', started_at = NOW(), updated_at = NOW(),
+		                   start_date = COALESCE(start_date, CURRENT_DATE)
+		WHERE id = $1 AND project_id = $2 AND state = 'planned'
+	`, id, projectID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "idx_sprints_one_active" {
+			return ErrSprintActive
+		}
+		return fmt.Errorf("failed to start sprint: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("sprint not found or not planned")
+	}
+	return nil
+}
+
+// CloseSprint closes the active sprint and moves its unfinished tasks to the
+// sprint carryTo, or to the backlog if carryTo is empty. It returns the
+// number of tasks carried over.
+func (r *SprintRepository) CloseSprint(projectID, id, carryTo string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var state string
+	err = tx.QueryRow(`SELECT state FROM sprints WHERE id = $1 AND project_id = $2 FOR UPDATE`, id, projectID).Scan(&state)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("sprint not found")
+		}
+		return 0, fmt.Errorf("failed to get sprint: %w", err)
+	}
+	if state != models.SprintActive {
+		return 0, fmt.Errorf("sprint not active")
+	}
+
+	var target interface{}
+	if carryTo != "" {
+		target = carryTo
+	}
+	result, err := tx.Exec(`
+		UPDATE tasks SET sprint_id = $2, updated_at = NOW()
+		WHERE sprint_id = $1 AND status <> 'done' AND deleted_at IS NULL
+	`, id, target)
+	if err != nil {
+		return 0, fmt.Errorf("failed to carry over tasks: %w", err)
+	}
+	carried := rowsAffected(result)
+
+	_, err = tx.Exec(`
+		UPDATE sprints SET state = 'closed', closed_at = NOW(), updated_at = NOW(), carried_over = $2,
+		                   end_date = COALESCE(end_date, CURRENT_DATE)
+		WHERE id = $1
+	`, id, carried)
+	if err != nil {
+		return 0, fmt.Errorf("failed to close sprint: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit sprint: %w", err)
+	}
+	return carried, nil
+}
+
+// NextPlannedSprint returns the planned sprint that starts next, or nil
+func (r *SprintRepository) NextPlannedSprint(projectID string) (*models.Sprint, error) {
+	sprint, err := scanSprint(r.db.QueryRow(`SELECT `+sprintColumns+sprintFrom+`
+		WHERE s.project_id = $1 AND s.state = 'planned'`+sprintGroupBy+`
+		ORDER BY s.start_date ASC NULLS LAST, s.created_at ASC
+		LIMIT 1
+	`, projectID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get next sprint: %w", err)
+	}
+	return sprint, nil
+}
+
+// SetTasksSprint puts tasks of the project into a sprint, or into the
+// backlog if sprintID is empty. It returns the IDs of the tasks changed.
+func (r *SprintRepository) SetTasksSprint(projectID, sprintID string, taskIDs []string) ([]string, error) {
+	var sprint interface{}
+	if sprintID != "" {
+		sprint = sprintID
+	}
+	rows, err := r.db.Query(`
+		UPDATE tasks SET sprint_id = $3, updated_at = NOW()
+		WHERE id = ANY($1) AND project_id = $2 AND deleted_at IS NULL
+		RETURNING id
+	`, pq.Array(taskIDs), projectID, sprint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tasks: %w", err)
+	}
+	defer rows.Close()
+
+	changed := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		changed = append(changed, id)
+	}
+	return changed, rows.Err()
+}
+
+// Velocity returns what each started sprint of a project completed, oldest
+// first. pointsFieldID names a number custom field whose values are summed
+// as points; it may be empty.
+func (r *SprintRepository) Velocity(projectID, pointsFieldID string) ([]models.SprintVelocity, error) {
+	rows, err := r.db.Query(`
+		SELECT s.id, s.name, s.state, s.start_date, s.end_date, s.carried_over,
+		       COUNT(t.id) FILTER (WHERE t.status = 'done'),
+		       SUM((t.custom_fields ->> $2::text)::numeric)
+		           FILTER (WHERE t.status = 'done' AND jsonb_typeof(t.custom_fields -> $2::text) = 'number')
+		FROM sprints s
+		LEFT JOIN tasks t ON t.sprint_id = s.id AND t.deleted_at IS NULL
+		WHERE s.project_id = $1 AND s.state <> 'planned'
+		GROUP BY s.id
+		ORDER BY s.started_at ASC
+	`, projectID, pointsFieldID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get velocity: %w", err)
+	}
+	defer rows.Close()
+
+	velocity := []models.SprintVelocity{}
+	for rows.Next() {
+		var v models.SprintVelocity
+		var startDate, endDate sql.NullTime
+		var points sql.NullFloat64
+		if err := rows.Scan(&v.SprintID, &v.Name, &v.State, &startDate, &endDate, &v.CarriedOver, &v.CompletedTasks, &points); err != nil {
+			return nil, fmt.Errorf("failed to scan velocity: %w", err)
+		}
+		if startDate.Valid {
+			v.StartDate = &startDate.Time
+		}
+		if endDate.Valid {
+			v.EndDate = &endDate.Time
+		}
+		if pointsFieldID != "" {
+			v.CompletedPoints = &points.Float64
+		}
+		velocity = append(velocity, v)
+	}
+	return velocity, rows.Err()
+}
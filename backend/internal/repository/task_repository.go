@@ -1,14 +1,18 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"task-management/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type TaskRepository struct {
@@ -19,8 +23,37 @@ func NewTaskRepository(db *sql.DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 // CreateTask creates a new task
 func (r *TaskRepository) CreateTask(task *models.Task) error {
+	_, err := insertTask(r.db, task)
+	return err
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertTask assigns an ID and timestamps and inserts the task. It reports
+// false when the task is an occurrence of a recurring series that already
+// exists, which makes occurrence creation idempotent.
+//
+// The task's number is taken from its project's counter in the same
+// statement. The counter row stays locked until the transaction ends, so
+// concurrent inserts into a project never get the same number.
+func insertTask(db queryer, task *models.Task) (bool, error) {
+	return insertNumberedTask(db, task, 0)
+}
+
+// insertNumberedTask is insertTask with a number reserved beforehand by
+// reserveTaskNumbers, which leaves the project's counter unlocked. A number
+// of 0 takes the next one from the counter.
+func insertNumberedTask(db queryer, task *models.Task, number int) (bool, error) {
 	// สร้าง UUID สำหรับ task
 	task.ID = uuid.New().String()
 
@@ -29,14 +62,37 @@ func (r *TaskRepository) CreateTask(task *models.Task) error {
 	now := time.Now()
 	task.UpdatedAt = &now
 
-	// Insert into database
+	if task.CustomFields == nil {
+		task.CustomFields = map[string]interface{}{}
+	}
+	customFields, err := json.Marshal(task.CustomFields)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode custom fields: %w", err)
+	}
+	if task.Labels == nil {
+		task.Labels = []string{}
+	}
+
+	// Insert into database. A missing project leaves number NULL and fails on
+	// the project foreign key as before. New tasks go to the top of their
+	// status column.
+	seq := `UPDATE projects SET task_seq = task_seq + 1 WHERE id = $2 RETURNING key, task_seq`
+	if number > 0 {
+		seq = `SELECT key, $19::int AS task_seq FROM projects WHERE id = $2`
+	}
 	query := `
-		INSERT INTO tasks (id, project_id, user_id, title, description, status, priority, due_date, assigned_to, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		WITH seq AS (
+			` + seq + `
+		)
+		INSERT INTO tasks (id, project_id, user_id, title, description, status, priority, due_date, assigned_to, created_at, updated_at,
+		                   custom_fields, labels, recurrence_id, recurrence_index, milestone_id, estimate_minutes, due_all_day, number, rank)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, (SELECT task_seq FROM seq),
+		        ` + topRankSQL("$2", "$6") + `)
+		ON CONFLICT (recurrence_id, recurrence_index) DO NOTHING
+		RETURNING number, (SELECT key FROM seq), rank, version
 	`
 
-	_, err := r.db.Exec(
-		query,
+	args := []interface{}{
 		task.ID,
 		task.ProjectID,
 		task.UserID,
@@ -48,25 +104,194 @@ func (r *TaskRepository) CreateTask(task *models.Task) error {
 		task.AssignedTo,
 		task.CreatedAt,
 		task.UpdatedAt,
-	)
+		customFields,
+		pq.Array(task.Labels),
+		task.RecurrenceID,
+		task.RecurrenceIndex,
+		task.MilestoneID,
+		task.EstimateMinutes,
+		task.DueDate != nil && task.DueAllDay,
+	}
+	if number > 0 {
+		args = append(args, number)
+	}
+	err = db.QueryRow(query, args...).Scan(&task.Number, &task.Key, &task.Rank, &task.Version)
 
 	if err != nil {
-		return fmt.Errorf("failed to create task: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create task: %w", err)
 	}
+	task.Key = taskKey(task.Key, task.Number)
 
-	return nil
+	return true, nil
 }
 
-// GetTasksByUserID retrieves all tasks for a user
-func (r *TaskRepository) GetTasksByUserID(userID string) ([]*models.Task, error) {
-	query := `
-		SELECT id, project_id, user_id, title, description, status, priority, due_date, created_at, updated_at
-		FROM tasks
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
+// reserveTaskNumbers takes count numbers from a project's counter at once
+// and returns the first. Run outside the transaction that inserts the tasks,
+// it holds the project row only for this one statement.
+func reserveTaskNumbers(db queryer, projectID string, count int) (int, error) {
+	var last int
+	err := db.QueryRow(`UPDATE projects SET task_seq = task_seq + $2 WHERE id = $1 RETURNING task_seq`,
+		projectID, count).Scan(&last)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("project not found")
+		}
+		return 0, fmt.Errorf("failed to reserve task numbers: %w", err)
+	}
+	return last - count + 1, nil
+}
+
+// taskKey formats a task key such as WEB-42
+func taskKey(projectKey string, number int) string {
+	return fmt.Sprintf("%s-%d", projectKey, number)
+}
+
+// taskColumns lists the columns selected for every task read. It must be
+// used together with taskFrom and scanned with scanTask.
+const taskColumns = `
+	t.id, t.project_id, t.user_id, t.title, t.description, t.status, t.priority, t.due_date, t.due_all_day, t.assigned_to,
+	t.created_at, t.updated_at, t.deleted_at, u.name, u.email, t.custom_fields, t.labels,
+	t.recurrence_id, t.recurrence_index, rs.rule, p.key, t.number, t.sprint_id, t.milestone_id, t.rank, t.version,
+	t.estimate_minutes, (SELECT COALESCE(SUM(wl.minutes), 0) FROM work_logs wl WHERE wl.task_id = t.id), p.name`
+
+// taskFrom joins the assignee so every task payload carries assignee info.
+// Tasks of soft-deleted projects are hidden by filtering on p.deleted_at.
+const taskFrom = `
+	FROM tasks t
+	INNER JOIN projects p ON t.project_id = p.id
+	LEFT JOIN users u ON t.assigned_to = u.id
+	LEFT JOIN task_recurrences rs ON t.recurrence_id = rs.id AND rs.active`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask scans a row selected with taskColumns into a task
+func scanTask(row rowScanner) (*models.Task, error) {
+	task := &models.Task{}
+	var updatedAt sql.NullTime
+	var dueDate sql.NullTime
+	var deletedAt sql.NullTime
+	var projectID sql.NullString
+	var assignedTo sql.NullString
+	var assigneeName sql.NullString
+	var assigneeEmail sql.NullString
+	var customFields []byte
+	var recurrenceID sql.NullString
+	var recurrenceIndex sql.NullInt64
+	var recurrenceRule sql.NullString
+	var projectKey sql.NullString
+	var number sql.NullInt64
+	var sprintID sql.NullString
+	var milestoneID sql.NullString
+	var estimate sql.NullInt64
+	var projectName sql.NullString
+
+	err := row.Scan(
+		&task.ID,
+		&projectID,
+		&task.UserID,
+		&task.Title,
+		&task.Description,
+		&task.Status,
+		&task.Priority,
+		&dueDate,
+		&task.DueAllDay,
+		&assignedTo,
+		&task.CreatedAt,
+		&updatedAt,
+		&deletedAt,
+		&assigneeName,
+		&assigneeEmail,
+		&customFields,
+		pq.Array(&task.Labels),
+		&recurrenceID,
+		&recurrenceIndex,
+		&recurrenceRule,
+		&projectKey,
+		&number,
+		&sprintID,
+		&milestoneID,
+		&task.Rank,
+		&task.Version,
+		&estimate,
+		&task.LoggedMinutes,
+		&projectName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	task.ProjectName = projectName.String
+	if number.Valid {
+		task.Number = int(number.Int64)
+		task.Key = taskKey(projectKey.String, task.Number)
+	}
+
+	if recurrenceID.Valid {
+		task.RecurrenceID = &recurrenceID.String
+		index := int(recurrenceIndex.Int64)
+		task.RecurrenceIndex = &index
+	}
+	if recurrenceRule.Valid {
+		task.RecurrenceRule = &recurrenceRule.String
+	}
+	if sprintID.Valid {
+		task.SprintID = &sprintID.String
+	}
+	if milestoneID.Valid {
+		task.MilestoneID = &milestoneID.String
+	}
+	if estimate.Valid {
+		minutes := int(estimate.Int64)
+		task.EstimateMinutes = &minutes
+	}
+	if task.Labels == nil {
+		task.Labels = []string{}
+	}
 
-	rows, err := r.db.Query(query, userID)
+	if err := json.Unmarshal(customFields, &task.CustomFields); err != nil {
+		return nil, fmt.Errorf("failed to decode custom fields: %w", err)
+	}
+	if task.CustomFields == nil {
+		task.CustomFields = map[string]interface{}{}
+	}
+
+	// Handle nullable fields
+	if projectID.Valid {
+		task.ProjectID = projectID.String
+	}
+	if updatedAt.Valid {
+		task.UpdatedAt = &updatedAt.Time
+	}
+	if dueDate.Valid {
+		// In UTC, all-day dates are midnight of their day whatever the session timezone
+		dueAt := dueDate.Time.UTC()
+		task.DueDate = &dueAt
+	}
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
+	}
+	if assignedTo.Valid {
+		task.AssignedTo = &assignedTo.String
+	}
+	if assigneeName.Valid {
+		task.AssigneeName = &assigneeName.String
+	}
+	if assigneeEmail.Valid {
+		task.AssigneeEmail = &assigneeEmail.String
+	}
+
+	return task, nil
+}
+
+// queryTasks runs a task query and scans all rows
+func (r *TaskRepository) queryTasks(query string, args ...interface{}) ([]*models.Task, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tasks: %w", err)
 	}
@@ -74,39 +299,10 @@ func (r *TaskRepository) GetTasksByUserID(userID string) ([]*models.Task, error)
 
 	var tasks []*models.Task
 	for rows.Next() {
-		task := &models.Task{}
-		var updatedAt sql.NullTime
-		var dueDate sql.NullTime
-		var projectID sql.NullString
-
-		err := rows.Scan(
-			&task.ID,
-			&projectID,
-			&task.UserID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.Priority,
-			&dueDate,
-			&task.CreatedAt,
-			&updatedAt,
-		)
-
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %w", err)
 		}
-
-		// Handle nullable fields
-		if projectID.Valid {
-			task.ProjectID = projectID.String
-		}
-		if updatedAt.Valid {
-			task.UpdatedAt = &updatedAt.Time
-		}
-		if dueDate.Valid {
-			task.DueDate = &dueDate.Time
-		}
-
 		tasks = append(tasks, task)
 	}
 
@@ -117,103 +313,230 @@ func (r *TaskRepository) GetTasksByUserID(userID string) ([]*models.Task, error)
 	return tasks, nil
 }
 
-// GetTasksByProjectID retrieves all tasks for a project with assignee info (for team collaboration)
-func (r *TaskRepository) GetTasksByProjectID(projectID string) ([]*models.Task, error) {
-	query := `
-		SELECT t.id, t.project_id, t.user_id, t.title, t.description, t.status, t.priority, t.due_date, t.assigned_to, t.created_at, t.updated_at,
-		       u.name as assignee_name, u.email as assignee_email
-		FROM tasks t
-		LEFT JOIN users u ON t.assigned_to = u.id
-		WHERE t.project_id = $1
+// GetTasksByUserID retrieves all tasks for a user
+func (r *TaskRepository) GetTasksByUserID(userID string) ([]*models.Task, error) {
+	query := `SELECT ` + taskColumns + taskFrom + `
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL AND p.deleted_at IS NULL
 		ORDER BY t.created_at DESC
 	`
+	return r.queryTasks(query, userID)
+}
+
+// TaskFilter narrows and orders a project's task list. Zero values mean no filter.
+type TaskFilter struct {
+	Status     string
+	Priority   string
+	AssignedTo string
+	// Open leaves out completed tasks
+	Open bool
+	// Dated leaves out tasks without a due date
+	Dated bool
+	// Sprint is a sprint ID, "active" for the project's active sprint or
+	// "backlog" for tasks in no sprint
+	Sprint string
+	// Milestone is a milestone ID or "none" for tasks linked to no milestone
+	Milestone    string
+	CustomFields []CustomFieldFilter
+	// Sort is created_at, due_date, title or priority; SortField sorts by a
+	// custom field instead and takes precedence. Without either tasks are in
+	// board order: by status column, then rank.
+	Sort      string
+	SortField *models.CustomField
+	SortDesc  bool
+}
+
+// CustomFieldFilter matches tasks whose custom field equals Value. For
+// multi-select fields it matches tasks where Value is one of the selections.
+type CustomFieldFilter struct {
+	Field *models.CustomField
+	Value string
+}
+
+// taskSortColumns maps the built-in sort keys to SQL expressions
+var taskSortColumns = map[string]string{
+	"created_at": "t.created_at",
+	"due_date":   "t.due_date",
+	"title":      "LOWER(t.title)",
+	"priority":   "CASE t.priority WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END",
+}
+
+// customFieldSQL returns the SQL expression reading a custom field as a
+// comparable value. argIndex is the placeholder holding the field ID.
+func customFieldSQL(field *models.CustomField, argIndex int) string {
+	expr := fmt.Sprintf("(t.custom_fields ->> $%d::text)", argIndex)
+	switch field.Type {
+	case models.FieldTypeNumber:
+		return expr + "::numeric"
+	case models.FieldTypeDate:
+		return expr + "::date"
+	}
+	return expr
+}
 
-	rows, err := r.db.Query(query, projectID)
+// where appends the filter's conditions to a query whose arguments are args
+func (f TaskFilter) where(args []interface{}) (string, []interface{}) {
+	var clause strings.Builder
+	arg := func(value interface{}) int {
+		args = append(args, value)
+		return len(args)
+	}
+
+	if f.Status != "" {
+		fmt.Fprintf(&clause, " AND t.status = $%d", arg(f.Status))
+	}
+	if f.Open {
+		clause.WriteString(" AND t.status <> 'done'")
+	}
+	if f.Dated {
+		clause.WriteString(" AND t.due_date IS NOT NULL")
+	}
+	if f.Priority != "" {
+		fmt.Fprintf(&clause, " AND t.priority = $%d", arg(f.Priority))
+	}
+	if f.AssignedTo == "none" {
+		clause.WriteString(" AND t.assigned_to IS NULL")
+	} else if f.AssignedTo != "" {
+		fmt.Fprintf(&clause, " AND t.assigned_to = $%d", arg(f.AssignedTo))
+	}
+	switch f.Sprint {
+	case "":
+	case "backlog":
+		clause.WriteString(" AND t.sprint_id IS NULL")
+	case "active":
+		clause.WriteString(" AND t.sprint_id = (SELECT s.id FROM sprints s WHERE s.project_id = t.project_id AND s.state = 'active')")
+	default:
+		fmt.Fprintf(&clause, " AND t.sprint_id = $%d", arg(f.Sprint))
+	}
+	if f.Milestone == "none" {
+		clause.WriteString(" AND t.milestone_id IS NULL")
+	} else if f.Milestone != "" {
+		fmt.Fprintf(&clause, " AND t.milestone_id = $%d", arg(f.Milestone))
+	}
+	for _, cf := range f.CustomFields {
+		key := arg(cf.Field.ID)
+		value := arg(cf.Value)
+		switch cf.Field.Type {
+		case models.FieldTypeMultiSelect:
+			fmt.Fprintf(&clause, " AND (t.custom_fields -> $%d::text) ? $%d::text", key, value)
+		case models.FieldTypeNumber:
+			fmt.Fprintf(&clause, " AND %s = $%d::numeric", customFieldSQL(cf.Field, key), value)
+		case models.FieldTypeDate:
+			fmt.Fprintf(&clause, " AND %s = $%d::date", customFieldSQL(cf.Field, key), value)
+		default:
+			fmt.Fprintf(&clause, " AND %s = $%d::text", customFieldSQL(cf.Field, key), value)
+		}
+	}
+	return clause.String(), args
+}
+
+// orderBy returns the ORDER BY clause for the filter, adding arguments as needed
+func (f TaskFilter) orderBy(args []interface{}) (string, []interface{}) {
+	direction := "ASC"
+	if f.SortDesc {
+		direction = "DESC"
+	}
+
+	if f.SortField != nil {
+		args = append(args, f.SortField.ID)
+		return fmt.Sprintf(" ORDER BY %s %s NULLS LAST, t.created_at DESC", customFieldSQL(f.SortField, len(args)), direction), args
+	}
+	if column, ok := taskSortColumns[f.Sort]; ok {
+		return fmt.Sprintf(" ORDER BY %s %s NULLS LAST, t.created_at DESC", column, direction), args
+	}
+	return " ORDER BY " + statusOrderSQL + ", t.rank, t.created_at DESC", args
+}
+
+// statusOrderSQL orders tasks by board column
+const statusOrderSQL = `CASE t.status WHEN 'todo' THEN 1 WHEN 'in-progress' THEN 2 WHEN 'done' THEN 3 ELSE 4 END`
+
+// Relations of a user to a task in their task list
+const (
+	RelationAssigned = "assigned"
+	RelationCreated  = "created"
+)
+
+// GetTasksForUser lists the tasks assigned to or created by a user across
+// the projects they are a member of, narrowed by filter. relation limits
+// the list to assigned or created tasks. Archived projects are left out.
+func (r *TaskRepository) GetTasksForUser(userID, relation string, filter TaskFilter) ([]*models.Task, error) {
+	args := []interface{}{userID}
+	conditions, args := filter.where(args)
+	order, args := filter.orderBy(args)
+
+	involved := "(t.assigned_to = $1 OR t.user_id = $1)"
+	switch relation {
+	case RelationAssigned:
+		involved = "t.assigned_to = $1"
+	case RelationCreated:
+		involved = "t.user_id = $1"
+	}
+
+	query := `SELECT ` + taskColumns + taskFrom + `
+		WHERE ` + involved + ` AND t.deleted_at IS NULL AND p.deleted_at IS NULL AND p.archived_at IS NULL
+		  AND EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = t.project_id AND pm.user_id = $1)` +
+		conditions + order
+	return r.queryTasks(query, args...)
+}
+
+// GetTasksByProjectID retrieves the tasks of a project with assignee info (for team collaboration)
+func (r *TaskRepository) GetTasksByProjectID(projectID string, filter TaskFilter) ([]*models.Task, error) {
+	args := []interface{}{projectID}
+	conditions, args := filter.where(args)
+	order, args := filter.orderBy(args)
+
+	query := `SELECT ` + taskColumns + taskFrom + `
+		WHERE t.project_id = $1 AND t.deleted_at IS NULL AND p.deleted_at IS NULL` + conditions + order
+	return r.queryTasks(query, args...)
+}
+
+// EachTaskByProjectID calls fn for every task of a project matching filter,
+// in filter order. Rows are scanned one at a time as the driver reads them
+// off the connection, so exports never hold the whole project in memory.
+// Iteration stops at the first error from fn or when ctx is cancelled.
+func (r *TaskRepository) EachTaskByProjectID(ctx context.Context, projectID string, filter TaskFilter, fn func(*models.Task) error) error {
+	args := []interface{}{projectID}
+	conditions, args := filter.where(args)
+	order, args := filter.orderBy(args)
+
+	query := `SELECT ` + taskColumns + taskFrom + `
+		WHERE t.project_id = $1 AND t.deleted_at IS NULL AND p.deleted_at IS NULL` + conditions + order
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tasks: %w", err)
+		return fmt.Errorf("failed to get tasks: %w", err)
 	}
 	defer rows.Close()
 
-	var tasks []*models.Task
 	for rows.Next() {
-		task := &models.Task{}
-		var updatedAt sql.NullTime
-		var dueDate sql.NullTime
-		var projectIDVal sql.NullString
-		var assignedTo sql.NullString
-		var assigneeName sql.NullString
-		var assigneeEmail sql.NullString
-
-		err := rows.Scan(
-			&task.ID,
-			&projectIDVal,
-			&task.UserID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.Priority,
-			&dueDate,
-			&assignedTo,
-			&task.CreatedAt,
-			&updatedAt,
-			&assigneeName,
-			&assigneeEmail,
-		)
+		task, err := scanTask(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan task: %w", err)
+			return fmt.Errorf("failed to scan task: %w", err)
 		}
-
-		if projectIDVal.Valid {
-			task.ProjectID = projectIDVal.String
-		}
-		if updatedAt.Valid {
-			task.UpdatedAt = &updatedAt.Time
-		}
-		if dueDate.Valid {
-			task.DueDate = &dueDate.Time
-		}
-		if assignedTo.Valid {
-			task.AssignedTo = &assignedTo.String
-		}
-		if assigneeName.Valid {
-			task.AssigneeName = &assigneeName.String
-		}
-		if assigneeEmail.Valid {
-			task.AssigneeEmail = &assigneeEmail.String
+		if err := fn(task); err != nil {
+			return err
 		}
-
-		tasks = append(tasks, task)
 	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate tasks: %w", err)
+	}
+	return nil
+}
 
-	return tasks, nil
+// GetDeletedTasksByProjectID retrieves the soft-deleted tasks of a project (the project trash)
+func (r *TaskRepository) GetDeletedTasksByProjectID(projectID string) ([]*models.Task, error) {
+	query := `SELECT ` + taskColumns + taskFrom + `
+		WHERE t.project_id = $1 AND t.deleted_at IS NOT NULL
+		ORDER BY t.deleted_at DESC
+	`
+	return r.queryTasks(query, projectID)
 }
 
 // GetTaskByID retrieves a task by ID
 func (r *TaskRepository) GetTaskByID(id string) (*models.Task, error) {
-	task := &models.Task{}
-	var updatedAt sql.NullTime
-	var dueDate sql.NullTime
-	var projectID sql.NullString
-
-	query := `
-		SELECT id, project_id, user_id, title, description, status, priority, due_date, created_at, updated_at
-		FROM tasks
-		WHERE id = $1
+	query := `SELECT ` + taskColumns + taskFrom + `
+		WHERE t.id = $1 AND t.deleted_at IS NULL AND p.deleted_at IS NULL
 	`
 
-	err := r.db.QueryRow(query, id).Scan(
-		&task.ID,
-		&projectID,
-		&task.UserID,
-		&task.Title,
-		&task.Description,
-		&task.Status,
-		&task.Priority,
-		&dueDate,
-		&task.CreatedAt,
-		&updatedAt,
-	)
-
+	task, err := scanTask(r.db.QueryRow(query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("task not found")
@@ -221,32 +544,79 @@ func (r *TaskRepository) GetTaskByID(id string) (*models.Task, error) {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// Handle nullable fields
-	if projectID.Valid {
-		task.ProjectID = projectID.String
-	}
-	if updatedAt.Valid {
-		task.UpdatedAt = &updatedAt.Time
-	}
-	if dueDate.Valid {
-		task.DueDate = &dueDate.Time
+	return task, nil
+}
+
+// GetDeletedTaskByID retrieves a soft-deleted task by ID
+func (r *TaskRepository) GetDeletedTaskByID(id string) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + taskFrom + `
+		WHERE t.id = $1 AND t.deleted_at IS NOT NULL
+	`
+
+	task, err := scanTask(r.db.QueryRow(query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("task not found")
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
 	return task, nil
 }
 
+// GetTaskIDByKey resolves a task key such as WEB-42 to the task's ID. Deleted
+// tasks resolve too so they can be restored by key.
+func (r *TaskRepository) GetTaskIDByKey(key string) (string, error) {
+	projectKey, number, ok := models.ParseTaskKey(key)
+	if !ok {
+		return "", fmt.Errorf("task not found")
+	}
+
+	var id string
+	err := r.db.QueryRow(`
+		SELECT t.id FROM tasks t
+		INNER JOIN projects p ON t.project_id = p.id
+		WHERE p.key = $1 AND t.number = $2
+	`, projectKey, number).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("task not found")
+		}
+		return "", fmt.Errorf("failed to get task: %w", err)
+	}
+	return id, nil
+}
+
 // UpdateTask updates an existing task
+//
+// The update only applies if the task is still at task.Version; otherwise
+// ErrVersionConflict is returned. task.Version is set to the new version.
 func (r *TaskRepository) UpdateTask(task *models.Task) error {
 	now := time.Now()
 	task.UpdatedAt = &now
 
+	if task.CustomFields == nil {
+		task.CustomFields = map[string]interface{}{}
+	}
+	customFields, err := json.Marshal(task.CustomFields)
+	if err != nil {
+		return fmt.Errorf("failed to encode custom fields: %w", err)
+	}
+
+	if task.Labels == nil {
+		task.Labels = []string{}
+	}
+
 	query := `
 		UPDATE tasks
-		SET title = $1, description = $2, status = $3, priority = $4, due_date = $5, assigned_to = $6, updated_at = $7
-		WHERE id = $8 AND user_id = $9
+		SET title = $1, description = $2, status = $3, priority = $4, due_date = $5, assigned_to = $6, updated_at = $7,
+		    custom_fields = $10, labels = $11, milestone_id = $12, estimate_minutes = $14, due_all_day = $15,
+		    rank = CASE WHEN status <> $3 THEN ` + topRankSQL("tasks.project_id", "$3") + ` ELSE rank END
+		WHERE id = $8 AND user_id = $9 AND deleted_at IS NULL AND version = $13
+		RETURNING version
 	`
 
-	result, err := r.db.Exec(
+	err = r.db.QueryRow(
 		query,
 		task.Title,
 		task.Description,
@@ -257,27 +627,278 @@ func (r *TaskRepository) UpdateTask(task *models.Task) error {
 		task.UpdatedAt,
 		task.ID,
 		task.UserID,
-	)
+		customFields,
+		pq.Array(task.Labels),
+		task.MilestoneID,
+		task.Version,
+		task.EstimateMinutes,
+		task.DueDate != nil && task.DueAllDay,
+	).Scan(&task.Version)
 
+	if errors.Is(err, sql.ErrNoRows) {
+		var version int
+		err := r.db.QueryRow(`SELECT version FROM tasks WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, task.ID, task.UserID).Scan(&version)
+		if err == nil && version != task.Version {
+			return ErrVersionConflict
+		}
+		return fmt.Errorf("task not found or unauthorized")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return nil
+}
+
+// ErrVersionConflict is returned when a task or project changed since the
+// version an update was based on
+var ErrVersionConflict = errors.New("version conflict")
+
+// SetStatus changes only the status of a task. It reports false if the task
+// already had that status.
+func (r *TaskRepository) SetStatus(id, status string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE tasks SET status = $1, rank = `+topRankSQL("tasks.project_id", "$1")+`, updated_at = NOW()
+		WHERE id = $2 AND status <> $1 AND deleted_at IS NULL
+	`, status, id)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return false, fmt.Errorf("failed to update task status: %w", err)
 	}
+	return rowsAffected(result) > 0, nil
+}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("task not found or unauthorized")
+// bulkUpdates are the statements of the bulk operations that change every
+// task the same way. $1 is the task IDs and $2 the operation's value, if any.
+var bulkUpdates = map[string]string{
+	models.BulkSetStatus: `
+		UPDATE tasks SET status = $2, updated_at = NOW(),
+		                 rank = CASE WHEN status <> $2 THEN ` + topRankSQL("tasks.project_id", "$2") + ` ELSE rank END
+		WHERE id = ANY($1) AND deleted_at IS NULL`,
+	models.BulkSetPriority: `UPDATE tasks SET priority = $2, updated_at = NOW() WHERE id = ANY($1) AND deleted_at IS NULL`,
+	models.BulkSetAssignee: `UPDATE tasks SET assigned_to = NULLIF($2, '')::uuid, updated_at = NOW() WHERE id = ANY($1) AND deleted_at IS NULL`,
+	models.BulkAddLabel: `
+		UPDATE tasks SET labels = array_append(labels, $2), updated_at = NOW()
+		WHERE id = ANY($1) AND deleted_at IS NULL AND NOT ($2 = ANY(labels))`,
+	models.BulkRemoveLabel: `
+		UPDATE tasks SET labels = array_remove(labels, $2), updated_at = NOW()
+		WHERE id = ANY($1) AND deleted_at IS NULL AND $2 = ANY(labels)`,
+	models.BulkDelete: `UPDATE tasks SET deleted_at = NOW() WHERE id = ANY($1) AND deleted_at IS NULL`,
+}
+
+// BulkUpdateTasks applies a bulk operation to the tasks in one transaction.
+// The value is checked by the caller; a move takes each task's transfer and
+// activity note from moves. The tasks are locked first and passed to
+// allowed with their current creator and project, and whether that project
+// is archived; only those it accepts are changed, so a task changed between
+// the caller's checks and the update is left out. allowed runs while the
+// rows are locked and must not query the database. It returns the IDs of
+// the changed tasks.
+func (r *TaskRepository) BulkUpdateTasks(ids []string, operation, value string, moves map[string]TaskMove,
+	allowed func(task *models.Task, archived bool) bool) ([]string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT t.id, t.user_id, t.project_id, p.archived_at IS NOT NULL
+		FROM tasks t
+		INNER JOIN projects p ON p.id = t.project_id
+		WHERE t.id = ANY($1) AND t.deleted_at IS NULL
+		ORDER BY t.id
+		FOR UPDATE OF t
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock tasks: %w", err)
+	}
+	type lockedTask struct {
+		task     *models.Task
+		archived bool
+	}
+	var locked []lockedTask
+	for rows.Next() {
+		item := lockedTask{task: &models.Task{}}
+		if err := rows.Scan(&item.task.ID, &item.task.UserID, &item.task.ProjectID, &item.archived); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		locked = append(locked, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock tasks: %w", err)
+	}
+
+	changed := []string{}
+	for _, item := range locked {
+		if allowed(item.task, item.archived) {
+			changed = append(changed, item.task.ID)
+		}
+	}
+	if len(changed) == 0 {
+		return changed, nil
 	}
 
+	if operation == models.BulkMove {
+		for _, id := range changed {
+			move, ok := moves[id]
+			if !ok {
+				return nil, fmt.Errorf("no move for task %s", id)
+			}
+			if err := moveTask(tx, id, move.To); err != nil {
+				return nil, err
+			}
+			move.Note.TaskID = id
+			if _, err := insertActivity(tx, move.Note, ""); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		query, ok := bulkUpdates[operation]
+		if !ok {
+			return nil, fmt.Errorf("unknown bulk operation %q", operation)
+		}
+		args := []interface{}{pq.Array(changed)}
+		if operation != models.BulkDelete {
+			args = append(args, value)
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return nil, fmt.Errorf("failed to update tasks: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit bulk update: %w", err)
+	}
+	return changed, nil
+}
+
+// TaskMove is how one task of a bulk move is moved, with the note for its
+// activity log
+type TaskMove struct {
+	To   TaskTransfer
+	Note *models.TaskActivity
+}
+
+// TaskTransfer describes where a task is moved or copied to
+type TaskTransfer struct {
+	ProjectID string
+	// AssignedTo is the assignee in the target project, "" for none. If nil
+	// the assignee is kept when they are a member of the target project.
+	AssignedTo *string
+	// CustomFields are the values of the target project's custom fields
+	CustomFields map[string]interface{}
+}
+
+// moveTask moves a task to another project, where it gets the next task
+// number. A recurring occurrence leaves its series, which stays in the old
+// project, and the task leaves its sprint.
+func moveTask(db execer, taskID string, to TaskTransfer) error {
+	if to.CustomFields == nil {
+		to.CustomFields = map[string]interface{}{}
+	}
+	customFields, err := json.Marshal(to.CustomFields)
+	if err != nil {
+		return fmt.Errorf("failed to encode custom fields: %w", err)
+	}
+	var assignee interface{}
+	if to.AssignedTo != nil && *to.AssignedTo != "" {
+		assignee = *to.AssignedTo
+	}
+
+	result, err := db.Exec(`
+		WITH seq AS (
+			UPDATE projects SET task_seq = task_seq + 1 WHERE id = $2 RETURNING task_seq
+		)
+		UPDATE tasks t
+		SET project_id = $2,
+		    number = (SELECT task_seq FROM seq),
+		    custom_fields = $3,
+		    assigned_to = CASE
+		        WHEN $4 THEN $5::uuid
+		        WHEN EXISTS (
+		            SELECT 1 FROM project_members pm WHERE pm.project_id = $2 AND pm.user_id = t.assigned_to
+		        ) THEN t.assigned_to
+		    END,
+		    recurrence_id = NULL,
+		    recurrence_index = NULL,
+		    sprint_id = NULL,
+		    milestone_id = NULL,
+		    rank = `+topRankSQL("$2", "t.status")+`,
+		    updated_at = NOW()
+		WHERE t.id = $1 AND t.project_id <> $2 AND t.deleted_at IS NULL
+	`, taskID, to.ProjectID, customFields, to.AssignedTo != nil, assignee)
+	if err != nil {
+		return fmt.Errorf("failed to move task: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("task %s not found or already in the project", taskID)
+	}
 	return nil
 }
 
-// DeleteTask deletes a task
+// MoveTask moves a task to another project and notes the move in the
+// task's activity log
+func (r *TaskRepository) MoveTask(taskID string, to TaskTransfer, note *models.TaskActivity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := moveTask(tx, taskID, to); err != nil {
+		return err
+	}
+	note.TaskID = taskID
+	if _, err := insertActivity(tx, note, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit move: %w", err)
+	}
+	return nil
+}
+
+// CopyTask creates task as a copy of the task sourceID, together with the
+// source's activity log and a note of the copy
+func (r *TaskRepository) CopyTask(sourceID string, task *models.Task, note *models.TaskActivity) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := insertTask(tx, task); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO task_activity (id, task_id, actor_id, type, message, data, ref, created_at)
+		SELECT uuid_generate_v4(), $2, actor_id, type, message, data, ref, created_at
+		FROM task_activity
+		WHERE task_id = $1
+	`, sourceID, task.ID)
+	if err != nil {
+		return fmt.Errorf("failed to copy activity: %w", err)
+	}
+
+	note.TaskID = task.ID
+	if _, err := insertActivity(tx, note, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit copy: %w", err)
+	}
+	return nil
+}
+
+// DeleteTask soft-deletes a task. It stays in the project trash until it is
+// restored or purged.
 func (r *TaskRepository) DeleteTask(id, userID string) error {
-	query := `DELETE FROM tasks WHERE id = $1 AND user_id = $2`
+	query := `UPDATE tasks SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
 
 	result, err := r.db.Exec(query, id, userID)
 	if err != nil {
@@ -295,3 +916,284 @@ func (r *TaskRepository) DeleteTask(id, userID string) error {
 
 	return nil
 }
+
+// RestoreTask brings a soft-deleted task back from the trash
+func (r *TaskRepository) RestoreTask(id string) error {
+	query := `UPDATE tasks SET deleted_at = NULL, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore task: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("task not found in trash")
+	}
+
+	return nil
+}
+
+// PurgeDeletedTasks permanently removes tasks that were soft-deleted before the given time
+func (r *TaskRepository) PurgeDeletedTasks(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge tasks: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// rankStep is the gap between neighbouring ranks after a column is
+// renumbered, and between a new task and the top of its column
+const rankStep = 1024.0
+
+// minRankGap is the smallest gap between neighbours that is still split.
+// Columns with closer ranks are renumbered before a move and by
+// RebalanceRanks.
+const minRankGap = 1e-6
+
+// ErrRankConflict is returned when a task's new neighbours are no longer next
+// to each other in their column, usually because of a concurrent move
+var ErrRankConflict = errors.New("task order has changed")
+
+// topRankSQL returns the SQL for a rank above every task of a status column,
+// given SQL expressions for the column's project and status
+func topRankSQL(project, status string) string {
+	return fmt.Sprintf(`COALESCE((SELECT MIN(c.rank) FROM tasks c WHERE c.project_id = %s AND c.status = %s AND c.deleted_at IS NULL), 0) - %g`,
+		project, status, rankStep)
+}
+
+// lockColumn serializes moves and renumbering within a status column until
+// the transaction ends
+func lockColumn(db execer, projectID, status string) error {
+	if _, err := db.Exec(`SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`, projectID, status); err != nil {
+		return fmt.Errorf("failed to lock column: %w", err)
+	}
+	return nil
+}
+
+// renumberColumn spreads the ranks of a status column rankStep apart,
+// keeping its order
+func renumberColumn(db execer, projectID, status string) error {
+	_, err := db.Exec(`
+		UPDATE tasks t SET rank = ranked.n * $3
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY rank, created_at DESC) AS n
+			FROM tasks
+			WHERE project_id = $1 AND status = $2 AND deleted_at IS NULL
+		) ranked
+		WHERE t.id = ranked.id
+	`, projectID, status, rankStep)
+	if err != nil {
+		return fmt.Errorf("failed to renumber column: %w", err)
+	}
+	return nil
+}
+
+// PositionTask moves a task to a status column, between the task above it
+// (beforeID) and the task below it (afterID). An empty beforeID puts the
+// task at the top of the column and an empty afterID at the bottom; with
+// neither the task goes to the top. Only the moved task is updated unless
+// its neighbours are too close, in which case the column is renumbered
+// first.
+//
+// Moves within a column are serialized. If the neighbours are not in the
+// column or no longer adjacent ErrRankConflict is returned, so a client
+// working from a stale board can reload instead of misplacing the task.
+func (r *TaskRepository) PositionTask(taskID, status, beforeID, afterID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var projectID string
+	err = tx.QueryRow(`SELECT project_id FROM tasks WHERE id = $1 AND deleted_at IS NULL`, taskID).Scan(&projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("task not found")
+		}
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if err := lockColumn(tx, projectID, status); err != nil {
+		return err
+	}
+
+	// neighbour reads a neighbour's rank; it must be another task of the column
+	neighbour := func(id string) (float64, error) {
+		var rank float64
+		err := tx.QueryRow(`
+			SELECT rank FROM tasks
+			WHERE id = $1 AND id <> $2 AND project_id = $3 AND status = $4 AND deleted_at IS NULL
+		`, id, taskID, projectID, status).Scan(&rank)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrRankConflict
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get neighbour: %w", err)
+		}
+		return rank, nil
+	}
+	// between counts the other tasks of the column ranked strictly between
+	// low and high
+	between := func(low, high *float64) (int, error) {
+		exclude := []string{taskID}
+		for _, id := range []string{beforeID, afterID} {
+			if id != "" {
+				exclude = append(exclude, id)
+			}
+		}
+		var count int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM tasks
+			WHERE project_id = $1 AND status = $2 AND deleted_at IS NULL AND id <> ALL($3)
+			  AND ($4::float8 IS NULL OR rank > $4) AND ($5::float8 IS NULL OR rank < $5)
+		`, projectID, status, pq.Array(exclude), low, high).Scan(&count)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check neighbours: %w", err)
+		}
+		return count, nil
+	}
+
+	var rank float64
+	for attempt := 0; ; attempt++ {
+		var before, after *float64
+		if beforeID != "" {
+			value, err := neighbour(beforeID)
+			if err != nil {
+				return err
+			}
+			before = &value
+		}
+		if afterID != "" {
+			value, err := neighbour(afterID)
+			if err != nil {
+				return err
+			}
+			after = &value
+		}
+
+		if before != nil && after != nil {
+			if *after < *before {
+				return ErrRankConflict
+			}
+			// Ties and tiny gaps can't be split; renumber the column once
+			if *after-*before < minRankGap {
+				if attempt > 0 {
+					return ErrRankConflict
+				}
+				if err := renumberColumn(tx, projectID, status); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if before != nil || after != nil {
+			count, err := between(before, after)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrRankConflict
+			}
+		}
+
+		switch {
+		case before != nil && after != nil:
+			rank = *before + (*after-*before)/2
+		case before != nil:
+			rank = *before + rankStep
+		case after != nil:
+			rank = *after - rankStep
+		default:
+			err := tx.QueryRow(`SELECT `+topRankSQL("$1", "$2"), projectID, status).Scan(&rank)
+			if err != nil {
+				return fmt.Errorf("failed to get top of column: %w", err)
+			}
+		}
+		break
+	}
+
+	_, err = tx.Exec(`
+		UPDATE tasks SET status = $2, rank = $3,
+		                 updated_at = CASE WHEN status <> $2 THEN NOW() ELSE updated_at END
+		WHERE id = $1
+	`, taskID, status, rank)
+	if err != nil {
+		return fmt.Errorf("failed to position task: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit position: %w", err)
+	}
+	return nil
+}
+
+// RebalanceRanks renumbers every status column with neighbours closer than
+// minRankGap and returns the number of columns renumbered
+func (r *TaskRepository) RebalanceRanks() (int, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT project_id, status
+		FROM (
+			SELECT project_id, status, rank - LAG(rank) OVER (PARTITION BY project_id, status ORDER BY rank) AS gap
+			FROM tasks
+			WHERE deleted_at IS NULL
+		) gaps
+		WHERE gap < $1
+	`, minRankGap)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find crowded columns: %w", err)
+	}
+	type column struct{ projectID, status string }
+	var columns []column
+	for rows.Next() {
+		var c column
+		if err := rows.Scan(&c.projectID, &c.status); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate columns: %w", err)
+	}
+
+	for i, c := range columns {
+		if err := r.rebalanceColumn(c.projectID, c.status); err != nil {
+			return i, err
+		}
+	}
+	return len(columns), nil
+}
+
+// rebalanceColumn renumbers one column while holding its lock
+func (r *TaskRepository) rebalanceColumn(projectID, status string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockColumn(tx, projectID, status); err != nil {
+		return err
+	}
+	if err := renumberColumn(tx, projectID, status); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rebalance: %w", err)
+	}
+	return nil
+}
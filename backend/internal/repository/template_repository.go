@@ -0,0 +1,340 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type TemplateRepository struct {
+	db *sql.DB
+}
+
+func NewTemplateRepository(db *sql.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// Snapshot captures what opts selects from a project. Relative due dates are
+// counted in days from the day the project was created.
+func (r *TemplateRepository) Snapshot(project *models.Project, opts models.SnapshotOptions) (*models.TemplateContent, error) {
+	content := &models.TemplateContent{
+		ProjectDescription: project.Description,
+		CustomFields:       []models.TemplateField{},
+		Tasks:              []models.TemplateTask{},
+	}
+
+	var fields []*models.CustomField
+	if opts.CustomFields {
+		rows, err := r.db.Query(`SELECT `+customFieldColumns+` FROM project_custom_fields WHERE project_id = $1 ORDER BY position, created_at`, project.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get custom fields: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			field, err := scanCustomField(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan custom field: %w", err)
+			}
+			fields = append(fields, field)
+			content.CustomFields = append(content.CustomFields, models.TemplateField{
+				Name:       field.Name,
+				Type:       field.Type,
+				Options:    field.Options,
+				Required:   field.Required,
+				Validation: field.Validation,
+			})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to iterate custom fields: %w", err)
+		}
+	}
+
+	if opts.Tasks {
+		query := `SELECT ` + taskColumns + taskFrom + `
+			WHERE t.project_id = $1 AND t.deleted_at IS NULL AND ($2 OR t.status <> 'done')
+			ORDER BY t.number
+		`
+		rows, err := r.db.Query(query, project.ID, opts.CompletedTasks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tasks: %w", err)
+		}
+		defer rows.Close()
+
+		start := dateOf(project.CreatedAt)
+		for rows.Next() {
+			task, err := scanTask(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan task: %w", err)
+			}
+			item := models.TemplateTask{
+				Title:        task.Title,
+				Description:  task.Description,
+				Status:       task.Status,
+				Priority:     task.Priority,
+				Labels:       task.Labels,
+				CustomFields: map[string]interface{}{},
+			}
+			if opts.Members {
+				item.AssignedTo = task.AssignedTo
+			}
+			if task.DueDate != nil {
+				switch opts.DueDates {
+				case models.DueDatesKeep:
+					item.DueDate = task.DueDate
+					item.DueTimed = !task.DueAllDay
+				case models.DueDatesRelative:
+					days := int(dateOf(*task.DueDate).Sub(start).Hours() / 24)
+					item.DueInDays = &days
+				}
+			}
+			for _, field := range fields {
+				if value, ok := task.CustomFields[field.ID]; ok {
+					item.CustomFields[field.Name] = value
+				}
+			}
+			content.Tasks = append(content.Tasks, item)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to iterate tasks: %w", err)
+		}
+	}
+
+	if opts.Members {
+		rows, err := r.db.Query(`SELECT user_id, role FROM project_members WHERE project_id = $1 ORDER BY joined_at`, project.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get members: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var member models.ProjectMember
+			if err := rows.Scan(&member.UserID, &member.Role); err != nil {
+				return nil, fmt.Errorf("failed to scan member: %w", err)
+			}
+			content.Members = append(content.Members, member)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to iterate members: %w", err)
+		}
+	}
+
+	return content, nil
+}
+
+// dateOf truncates a time to midnight UTC of its day
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// CreateTemplate stores a template
+func (r *TemplateRepository) CreateTemplate(template *models.ProjectTemplate) error {
+	template.ID = uuid.New().String()
+	template.CreatedAt = time.Now()
+
+	content, err := json.Marshal(template.Content)
+	if err != nil {
+		return fmt.Errorf("failed to encode template: %w", err)
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO project_templates (id, name, description, source_project_id, created_by, content, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, template.ID, template.Name, template.Description, template.SourceProjectID, template.CreatedBy, content, template.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+	return nil
+}
+
+const templateColumns = `id, name, description, source_project_id, created_by, content, created_at`
+
+// scanTemplate scans a row selected with templateColumns
+func scanTemplate(row rowScanner) (*models.ProjectTemplate, error) {
+	template := &models.ProjectTemplate{}
+	var sourceProjectID, createdBy sql.NullString
+	var content []byte
+
+	err := row.Scan(&template.ID, &template.Name, &template.Description, &sourceProjectID, &createdBy, &content, &template.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &template.Content); err != nil {
+		return nil, fmt.Errorf("failed to decode template: %w", err)
+	}
+	if sourceProjectID.Valid {
+		template.SourceProjectID = &sourceProjectID.String
+	}
+	if createdBy.Valid {
+		template.CreatedBy = &createdBy.String
+	}
+	return template, nil
+}
+
+// GetTemplate retrieves a template by ID
+func (r *TemplateRepository) GetTemplate(id string) (*models.ProjectTemplate, error) {
+	template, err := scanTemplate(r.db.QueryRow(`SELECT `+templateColumns+` FROM project_templates WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("template not found")
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return template, nil
+}
+
+// GetTemplatesForUser lists the templates a user created or that were saved
+// from a project they are a member of. all lists every template (admins).
+func (r *TemplateRepository) GetTemplatesForUser(userID string, all bool) ([]*models.ProjectTemplate, error) {
+	rows, err := r.db.Query(`
+		SELECT `+templateColumns+`
+		FROM project_templates
+		WHERE $2 OR created_by = $1 OR source_project_id IN (
+			SELECT project_id FROM project_members WHERE user_id = $1
+		)
+		ORDER BY name, created_at
+	`, userID, all)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []*models.ProjectTemplate{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate templates: %w", err)
+	}
+	return templates, nil
+}
+
+// DeleteTemplate removes a template; projects created from it are unaffected
+func (r *TemplateRepository) DeleteTemplate(id string) error {
+	result, err := r.db.Exec(`DELETE FROM project_templates WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("template not found")
+	}
+	return nil
+}
+
+// CreateProjectFrom creates a project owned by ownerID together with the
+// content's custom fields, tasks and members in one transaction. Relative
+// due dates count from today.
+func (r *TemplateRepository) CreateProjectFrom(project *models.Project, ownerID string, content *models.TemplateContent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	project.ID = uuid.New().String()
+	now := time.Now()
+	project.CreatedAt = now
+	project.UpdatedAt = &now
+	project.Version = 1
+
+	_, err = tx.Exec(`INSERT INTO projects (id, name, description, key, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		project.ID, project.Name, project.Description, project.Key, project.CreatedAt, project.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+
+	// The owner is PO; cloned POs become PMs so the project has one owner
+	members := map[string]bool{ownerID: true}
+	if err := addMember(tx, project.ID, ownerID, models.RolePO); err != nil {
+		return err
+	}
+	for _, member := range content.Members {
+		if members[member.UserID] {
+			continue
+		}
+		role := member.Role
+		if role == models.RolePO {
+			role = models.RolePM
+		}
+		if err := addMember(tx, project.ID, member.UserID, role); err != nil {
+			return err
+		}
+		members[member.UserID] = true
+	}
+
+	fieldIDs := map[string]string{}
+	for i, item := range content.CustomFields {
+		options, err := json.Marshal(item.Options)
+		if err != nil {
+			return fmt.Errorf("failed to encode options: %w", err)
+		}
+		validation, err := json.Marshal(item.Validation)
+		if err != nil {
+			return fmt.Errorf("failed to encode validation: %w", err)
+		}
+		id := uuid.New().String()
+		_, err = tx.Exec(`
+			INSERT INTO project_custom_fields (id, project_id, name, field_type, options, required, validation, position, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		`, id, project.ID, item.Name, item.Type, options, item.Required, validation, i, now)
+		if err != nil {
+			return fmt.Errorf("failed to create custom field: %w", err)
+		}
+		fieldIDs[item.Name] = id
+	}
+
+	today := dateOf(now)
+	for _, item := range content.Tasks {
+		task := &models.Task{
+			ProjectID:    project.ID,
+			UserID:       ownerID,
+			Title:        item.Title,
+			Description:  item.Description,
+			Status:       item.Status,
+			Priority:     item.Priority,
+			Labels:       item.Labels,
+			DueDate:      item.DueDate,
+			DueAllDay:    !item.DueTimed,
+			CustomFields: map[string]interface{}{},
+		}
+		if item.DueInDays != nil {
+			dueDate := today.AddDate(0, 0, *item.DueInDays)
+			task.DueDate = &dueDate
+			task.DueAllDay = true
+		}
+		if item.AssignedTo != nil && members[*item.AssignedTo] {
+			task.AssignedTo = item.AssignedTo
+		}
+		for name, value := range item.CustomFields {
+			if id, ok := fieldIDs[name]; ok {
+				task.CustomFields[id] = value
+			}
+		}
+		if _, err := insertTask(tx, task); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit project: %w", err)
+	}
+	return nil
+}
+
+// addMember adds a user to a project within a transaction
+func addMember(db execer, projectID, userID, role string) error {
+	_, err := db.Exec(`INSERT INTO project_members (id, project_id, user_id, role) VALUES ($1, $2, $3, $4)`,
+		uuid.New().String(), projectID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	return nil
+}
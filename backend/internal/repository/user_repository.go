@@ -35,12 +35,15 @@ func (r *UserRepository) CreateUser(user *models.User, password string) error {
 	if user.SystemRole == "" {
 		user.SystemRole = "user"
 	}
+	if user.Timezone == "" {
+		user.Timezone = "UTC"
+	}
 
 	query := `
-	INSERT INTO users (id, email, password_hash, name, system_role, created_at)
-	VALUES ($1, $2, $3, $4, $5, $6)
+	INSERT INTO users (id, email, password_hash, name, system_role, timezone, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
-	_, err = r.db.Exec(query, user.ID, user.Email, user.PasswordHash, user.Name, user.SystemRole, user.CreatedAt)
+	_, err = r.db.Exec(query, user.ID, user.Email, user.PasswordHash, user.Name, user.SystemRole, user.Timezone, user.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -52,9 +55,9 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 
 	query := `
-		SELECT id, email, password_hash, name, system_role, created_at
+		SELECT id, email, password_hash, name, system_role, timezone, created_at
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	err := r.db.QueryRow(query, email).Scan(
@@ -63,6 +66,7 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 		&user.PasswordHash,
 		&user.Name,
 		&user.SystemRole,
+		&user.Timezone,
 		&user.CreatedAt,
 	)
 
@@ -79,9 +83,9 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 	user := &models.User{}
 	query := `
-		SELECT id, email, password_hash, name, system_role, created_at
+		SELECT id, email, password_hash, name, system_role, timezone, created_at
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID,
@@ -89,6 +93,7 @@ func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 		&user.PasswordHash,
 		&user.Name,
 		&user.SystemRole,
+		&user.Timezone,
 		&user.CreatedAt,
 	)
 	if err != nil {
@@ -100,21 +105,47 @@ func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
 	return user, nil
 }
 
+// UpdateTimezone sets a user's timezone
+func (r *UserRepository) UpdateTimezone(id, timezone string) error {
+	result, err := r.db.Exec(`UPDATE users SET timezone = $1 WHERE id = $2 AND deleted_at IS NULL`, timezone, id)
+	if err != nil {
+		return fmt.Errorf("failed to update timezone: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// KnownTimezone reports whether the database knows the timezone name.
+// Queries convert due dates with AT TIME ZONE for every user at once, so a
+// name Postgres rejects would fail them for everyone.
+func (r *UserRepository) KnownTimezone(name string) (bool, error) {
+	var known bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_timezone_names WHERE name = $1)`, name).Scan(&known)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up timezone: %w", err)
+	}
+	return known, nil
+}
+
 // VerifyPassword verifies the password for a user
 func (r *UserRepository) VerifyPassword(hashedPassword, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
 }
 
-// GetAllUsers returns all users (admin only)
+// GetAllUsers returns all users (admin only). The former member placeholder
+// is not a real account and is left out.
 func (r *UserRepository) GetAllUsers() ([]*models.User, error) {
 	query := `
-		SELECT id, email, name, system_role, created_at
+		SELECT id, email, name, system_role, timezone, created_at
 		FROM users
+		WHERE deleted_at IS NULL AND email <> $1
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, FormerMemberEmail)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query users: %w", err)
 	}
@@ -123,7 +154,7 @@ func (r *UserRepository) GetAllUsers() ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
-		err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.SystemRole, &user.CreatedAt)
+		err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.SystemRole, &user.Timezone, &user.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -137,24 +168,272 @@ func (r *UserRepository) GetAllUsers() ([]*models.User, error) {
 	return users, nil
 }
 
-// DeleteUser deletes a user by ID
-func (r *UserRepository) DeleteUser(userID string) error {
-	// First, delete all tasks associated with the user
-	_, err := r.db.Exec("DELETE FROM tasks WHERE user_id = $1", userID)
+// GetDeletedUsers returns all soft-deleted users (admin trash)
+func (r *UserRepository) GetDeletedUsers() ([]*models.User, error) {
+	query := `
+		SELECT id, email, name, system_role, timezone, created_at, deleted_at
+		FROM users
+		WHERE deleted_at IS NOT NULL AND email <> $1
+		ORDER BY deleted_at DESC
+	`
+
+	rows, err := r.db.Query(query, FormerMemberEmail)
 	if err != nil {
-		return fmt.Errorf("failed to delete user tasks: %w", err)
+		return nil, fmt.Errorf("failed to query deleted users: %w", err)
 	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		var deletedAt time.Time
+		err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.SystemRole, &user.Timezone, &user.CreatedAt, &deletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.DeletedAt = &deletedAt
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %w", err)
+	}
+
+	return users, nil
+}
+
+// FormerMemberEmail identifies the placeholder account that receives the
+// work of deleted users when no other owner is chosen
+const FormerMemberEmail = "former-member@system.local"
+
+// ErrSuccessorRequired is returned when a user is the last PO of a project
+// and no successor was given
+var ErrSuccessorRequired = errors.New("user is the last PO of a project and no successor was given")
 
-	// Delete project memberships
-	_, err = r.db.Exec("DELETE FROM project_members WHERE user_id = $1", userID)
+// DeleteUser deletes a user in a single transaction without destroying
+// their work. Created tasks move to opts.ReassignTo (or the former member
+// placeholder), assignments are cleared or reassigned, projects where the
+// user is the last PO are handed to a successor, and the account is
+// soft-deleted. With dryRun the transaction is rolled back and only the
+// preview is returned.
+func (r *UserRepository) DeleteUser(userID string, opts models.DeleteUserRequest, dryRun bool) (*models.UserDeletionPreview, error) {
+	preview := &models.UserDeletionPreview{
+		UserID:            userID,
+		DryRun:            dryRun,
+		AssignmentsAction: "clear",
+		OwnedProjects:     []*models.ProjectSuccession{},
+	}
+	if opts.Assignments == "reassign" {
+		preview.AssignmentsAction = "reassign"
+	}
+
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete project memberships: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the user so concurrent deletions serialize
+	var lockedID string
+	err = tx.QueryRow("SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", userID).Scan(&lockedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Resolve who receives the created tasks
+	var newOwnerID string
+	if opts.ReassignTo != nil && *opts.ReassignTo != "" {
+		if *opts.ReassignTo == userID {
+			return nil, fmt.Errorf("invalid reassign_to: cannot reassign to the deleted user")
+		}
+		if err := r.activeUserExists(tx, *opts.ReassignTo); err != nil {
+			return nil, fmt.Errorf("invalid reassign_to: %w", err)
+		}
+		newOwnerID = *opts.ReassignTo
+		preview.CreatedTasksTo = newOwnerID
+	} else {
+		if preview.AssignmentsAction == "reassign" {
+			return nil, fmt.Errorf("invalid assignments: reassign requires reassign_to")
+		}
+		newOwnerID, err = r.formerMemberID(tx)
+		if err != nil {
+			return nil, err
+		}
+		preview.CreatedTasksTo = "former_member"
 	}
 
-	// Delete the user
-	result, err := r.db.Exec("DELETE FROM users WHERE id = $1", userID)
+	// Projects where the user is the only PO need a successor
+	rows, err := tx.Query(`
+		SELECT p.id, p.name
+		FROM project_members pm
+		INNER JOIN projects p ON p.id = pm.project_id
+		WHERE pm.user_id = $1 AND pm.role = 'po' AND p.deleted_at IS NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM project_members other
+			INNER JOIN users ou ON ou.id = other.user_id
+			WHERE other.project_id = pm.project_id AND other.user_id <> $1
+			  AND other.role = 'po' AND ou.deleted_at IS NULL
+		  )
+		ORDER BY p.name
+	`, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		return nil, fmt.Errorf("failed to get owned projects: %w", err)
+	}
+	for rows.Next() {
+		succession := &models.ProjectSuccession{}
+		if err := rows.Scan(&succession.ProjectID, &succession.ProjectName); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan owned project: %w", err)
+		}
+		if successor, ok := opts.Successors[succession.ProjectID]; ok && successor != "" {
+			succession.SuccessorID = &successor
+		} else if opts.SuccessorID != nil && *opts.SuccessorID != "" {
+			successor := *opts.SuccessorID
+			succession.SuccessorID = &successor
+		}
+		if succession.SuccessorID == nil {
+			preview.Blocked = true
+		}
+		preview.OwnedProjects = append(preview.OwnedProjects, succession)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate owned projects: %w", err)
+	}
+
+	if preview.Blocked && !dryRun {
+		return preview, ErrSuccessorRequired
+	}
+
+	for _, succession := range preview.OwnedProjects {
+		if succession.SuccessorID == nil {
+			// Only reachable in a dry run that reports the block
+			continue
+		}
+		successorID := *succession.SuccessorID
+		if successorID == userID {
+			return nil, fmt.Errorf("invalid successor: cannot hand a project to the deleted user")
+		}
+		if err := r.activeUserExists(tx, successorID); err != nil {
+			return nil, fmt.Errorf("invalid successor for project %s: %w", succession.ProjectID, err)
+		}
+		_, err := tx.Exec(`
+			INSERT INTO project_members (id, project_id, user_id, role) VALUES ($1, $2, $3, 'po')
+			ON CONFLICT (project_id, user_id) DO UPDATE SET role = 'po'
+		`, uuid.New().String(), succession.ProjectID, successorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to promote successor: %w", err)
+		}
+	}
+
+	// Move created tasks, including those in the trash
+	result, err := tx.Exec("UPDATE tasks SET user_id = $1 WHERE user_id = $2", newOwnerID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign created tasks: %w", err)
+	}
+	preview.CreatedTasks = rowsAffected(result)
+
+	// Clear assignments, or hand them over where the new owner is a project member
+	if preview.AssignmentsAction == "reassign" {
+		result, err = tx.Exec(`
+			UPDATE tasks t SET assigned_to = CASE
+				WHEN EXISTS (SELECT 1 FROM project_members pm WHERE pm.project_id = t.project_id AND pm.user_id = $1) THEN $1::uuid
+				ELSE NULL
+			END
+			WHERE t.assigned_to = $2
+		`, newOwnerID, userID)
+	} else {
+		result, err = tx.Exec("UPDATE tasks SET assigned_to = NULL WHERE assigned_to = $1", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update task assignments: %w", err)
+	}
+	preview.AssignedTasks = rowsAffected(result)
+
+	// Recurring series create future occurrences from their template
+	_, err = tx.Exec(`
+		UPDATE task_recurrences SET
+			created_by = CASE WHEN created_by = $1 THEN $2::uuid ELSE created_by END,
+			assigned_to = CASE WHEN assigned_to = $1 THEN NULL ELSE assigned_to END
+		WHERE created_by = $1 OR assigned_to = $1
+	`, userID, newOwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update recurring tasks: %w", err)
+	}
+
+	result, err = tx.Exec("DELETE FROM project_members WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete project memberships: %w", err)
+	}
+	preview.MembershipsRemoved = rowsAffected(result)
+
+	if _, err := tx.Exec("UPDATE users SET deleted_at = NOW() WHERE id = $1", userID); err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if dryRun {
+		// The deferred rollback discards every change
+		return preview, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user deletion: %w", err)
+	}
+
+	return preview, nil
+}
+
+// activeUserExists checks that a user exists and is not deleted
+func (r *UserRepository) activeUserExists(tx *sql.Tx, userID string) error {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// formerMemberID returns the placeholder account, creating it on first use.
+// Its password hash is not a valid bcrypt hash, so nobody can sign in as it.
+func (r *UserRepository) formerMemberID(tx *sql.Tx) (string, error) {
+	_, err := tx.Exec(`
+		INSERT INTO users (id, email, password_hash, name, system_role, created_at)
+		VALUES ($1, $2, '!', 'Former member', 'user', NOW())
+		ON CONFLICT (email) DO NOTHING
+	`, uuid.New().String(), FormerMemberEmail)
+	if err != nil {
+		return "", fmt.Errorf("failed to create former member placeholder: %w", err)
+	}
+
+	var id string
+	if err := tx.QueryRow("SELECT id FROM users WHERE email = $1", FormerMemberEmail).Scan(&id); err != nil {
+		return "", fmt.Errorf("failed to get former member placeholder: %w", err)
+	}
+	return id, nil
+}
+
+// rowsAffected returns the affected row count, treating errors as zero
+func rowsAffected(result sql.Result) int {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// RestoreUser brings a soft-deleted user back from the trash. Only the
+// account comes back: DeleteUser removed the user's project memberships and
+// handed their tasks and assignments to others, and none of that is undone.
+func (r *UserRepository) RestoreUser(userID string) error {
+	result, err := r.db.Exec("UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -163,8 +442,66 @@ func (r *UserRepository) DeleteUser(userID string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return fmt.Errorf("user not found in trash")
 	}
 
 	return nil
 }
+
+// PurgeDeletedUsers permanently removes users that were soft-deleted before
+// the given time. Their work stays: tasks they created and time they logged
+// move to the former member placeholder in the same transaction, as
+// DeleteUser does, so a purge never takes task history with it.
+func (r *UserRepository) PurgeDeletedUsers(before time.Time) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	formerMemberID, err := r.formerMemberID(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	// The placeholder is never purged, even if it was deleted by hand
+	const expired = `SELECT id FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1 AND id <> $2`
+
+	// tasks.user_id cascades on delete, so the rows must be moved first
+	if _, err := tx.Exec("UPDATE tasks SET user_id = $2 WHERE user_id IN ("+expired+")", before, formerMemberID); err != nil {
+		return 0, fmt.Errorf("failed to reassign user tasks: %w", err)
+	}
+
+	// Keep logged time; a timer still running can't be completed by anyone
+	if _, err := tx.Exec("DELETE FROM work_logs WHERE minutes IS NULL AND user_id IN ("+expired+")", before, formerMemberID); err != nil {
+		return 0, fmt.Errorf("failed to delete running timers: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE work_logs SET user_id = $2 WHERE user_id IN ("+expired+")", before, formerMemberID); err != nil {
+		return 0, fmt.Errorf("failed to reassign work logs: %w", err)
+	}
+
+	// Clear assignments explicitly for databases without the assigned_to foreign key
+	if _, err := tx.Exec("UPDATE tasks SET assigned_to = NULL WHERE assigned_to IN ("+expired+")", before, formerMemberID); err != nil {
+		return 0, fmt.Errorf("failed to clear task assignments: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM project_members WHERE user_id IN ("+expired+")", before, formerMemberID); err != nil {
+		return 0, fmt.Errorf("failed to delete project memberships: %w", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM users WHERE id IN ("+expired+")", before, formerMemberID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge users: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+
+	return rowsAffected, nil
+}
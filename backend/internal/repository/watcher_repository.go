@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"task-management/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// WatcherRepository stores who watches a task and notifies them
+type WatcherRepository struct {
+	db *sql.DB
+}
+
+func NewWatcherRepository(db *sql.DB) *WatcherRepository {
+	return &WatcherRepository{db: db}
+}
+
+// GetWatchers lists the watchers of a task, in the order they subscribed
+func (r *WatcherRepository) GetWatchers(taskID string) ([]*models.TaskWatcher, error) {
+	rows, err := r.db.Query(`
+		SELECT w.task_id, w.user_id, u.name, u.email, w.muted, w.created_at
+		FROM task_watchers w
+		INNER JOIN users u ON u.id = w.user_id AND u.deleted_at IS NULL
+		WHERE w.task_id = $1
+		ORDER BY w.created_at ASC, u.name ASC
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchers: %w", err)
+	}
+	defer rows.Close()
+
+	watchers := []*models.TaskWatcher{}
+	for rows.Next() {
+		watcher := &models.TaskWatcher{}
+		if err := rows.Scan(&watcher.TaskID, &watcher.UserID, &watcher.Name, &watcher.Email, &watcher.Muted,
+			&watcher.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watcher: %w", err)
+		}
+		watchers = append(watchers, watcher)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate watchers: %w", err)
+	}
+	return watchers, nil
+}
+
+// GetNotifiedWatcherIDs returns, for each of the tasks, the users that
+// NotifyWatchers reaches: unmuted watchers that are still in the task's
+// project. Tasks without such watchers map to an empty list.
+func (r *WatcherRepository) GetNotifiedWatcherIDs(taskIDs []string) (map[string][]string, error) {
+	rows, err := r.db.Query(`
+		SELECT w.task_id, w.user_id
+		FROM task_watchers w
+		INNER JOIN tasks t ON t.id = w.task_id
+		INNER JOIN users u ON u.id = w.user_id AND u.deleted_at IS NULL
+		INNER JOIN project_members pm ON pm.project_id = t.project_id AND pm.user_id = w.user_id
+		WHERE w.task_id = ANY($1) AND NOT w.muted
+		ORDER BY w.created_at ASC
+	`, pq.Array(taskIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchers: %w", err)
+	}
+	defer rows.Close()
+
+	watchers := make(map[string][]string, len(taskIDs))
+	for _, taskID := range taskIDs {
+		watchers[taskID] = []string{}
+	}
+	for rows.Next() {
+		var taskID, userID string
+		if err := rows.Scan(&taskID, &userID); err != nil {
+			return nil, fmt.Errorf("failed to scan watcher: %w", err)
+		}
+		watchers[taskID] = append(watchers[taskID], userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate watchers: %w", err)
+	}
+	return watchers, nil
+}
+
+// Watch subscribes a user to a task. A nil muted keeps an existing
+// subscription's setting; new subscriptions are unmuted by default.
+func (r *WatcherRepository) Watch(taskID, userID string, muted *bool) error {
+	_, err := r.db.Exec(`
+		INSERT INTO task_watchers (task_id, user_id, muted)
+		VALUES ($1, $2, COALESCE($3, FALSE))
+		ON CONFLICT (task_id, user_id) DO UPDATE SET muted = COALESCE($3, task_watchers.muted)
+	`, taskID, userID, muted)
+	if err != nil {
+		return fmt.Errorf("failed to watch task: %w", err)
+	}
+	return nil
+}
+
+// Unwatch removes a user's subscription to a task
+func (r *WatcherRepository) Unwatch(taskID, userID string) error {
+	result, err := r.db.Exec(`DELETE FROM task_watchers WHERE task_id = $1 AND user_id = $2`, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unwatch task: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("watcher not found")
+	}
+	return nil
+}
+
+// NotifyWatchers creates a notification for every watcher of a task except
+// the user who caused it. Muted watchers, deleted users and users no longer
+// in the task's project are skipped. It returns the number of notifications
+// created.
+func (r *WatcherRepository) NotifyWatchers(taskID, actorID, notificationType, title, body string) (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO notifications (user_id, task_id, project_id, type, title, body)
+		SELECT w.user_id, t.id, t.project_id, $3, $4, $5
+		FROM task_watchers w
+		INNER JOIN tasks t ON t.id = w.task_id
+		INNER JOIN users u ON u.id = w.user_id AND u.deleted_at IS NULL
+		INNER JOIN project_members pm ON pm.project_id = t.project_id AND pm.user_id = w.user_id
+		WHERE w.task_id = $1 AND NOT w.muted AND w.user_id::text <> $2
+	`, taskID, actorID, notificationType, title, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to notify watchers: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookColumns = `id, project_id, url, events, secret, active, consecutive_failures, disabled_reason, created_by, created_at, updated_at`
+
+// scanWebhook scans a row selected with webhookColumns
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	hook := &models.Webhook{}
+	var disabledReason, createdBy sql.NullString
+	var updatedAt sql.NullTime
+
+	err := row.Scan(&hook.ID, &hook.ProjectID, &hook.URL, pq.Array(&hook.Events), &hook.Secret, &hook.Active,
+		&hook.ConsecutiveFailures, &disabledReason, &createdBy, &hook.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if disabledReason.Valid {
+		hook.DisabledReason = &disabledReason.String
+	}
+	if createdBy.Valid {
+		hook.CreatedBy = &createdBy.String
+	}
+	if updatedAt.Valid {
+		hook.UpdatedAt = &updatedAt.Time
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepository) queryWebhooks(query string, args ...interface{}) ([]*models.Webhook, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []*models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// CreateWebhook registers a webhook
+func (r *WebhookRepository) CreateWebhook(hook *models.Webhook) error {
+	hook.ID = uuid.New().String()
+	hook.Active = true
+	now := time.Now()
+	hook.CreatedAt = now
+	hook.UpdatedAt = &now
+
+	_, err := r.db.Exec(`
+		INSERT INTO webhooks (id, project_id, url, events, secret, active, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, hook.ID, hook.ProjectID, hook.URL, pq.Array(hook.Events), hook.Secret, hook.Active, hook.CreatedBy, hook.CreatedAt, hook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// GetWebhooksByProjectID lists a project's webhooks
+func (r *WebhookRepository) GetWebhooksByProjectID(projectID string) ([]*models.Webhook, error) {
+	return r.queryWebhooks(`SELECT `+webhookColumns+` FROM webhooks WHERE project_id = $1 ORDER BY created_at`, projectID)
+}
+
+// GetSubscribedWebhooks returns the active webhooks of a project that receive eventType
+func (r *WebhookRepository) GetSubscribedWebhooks(projectID, eventType string) ([]*models.Webhook, error) {
+	return r.queryWebhooks(`
+		SELECT `+webhookColumns+` FROM webhooks
+		WHERE project_id = $1 AND active AND ($2 = ANY(events) OR '*' = ANY(events))
+	`, projectID, eventType)
+}
+
+// GetWebhookByID returns a webhook of a project. An empty projectID
+// matches any project.
+func (r *WebhookRepository) GetWebhookByID(projectID, webhookID string) (*models.Webhook, error) {
+	hook, err := scanWebhook(r.db.QueryRow(`
+		SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND ($2 = '' OR project_id::text = $2)
+	`, webhookID, projectID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// UpdateWebhook saves the URL, events and active flag. Activating a webhook
+// clears its failure count.
+func (r *WebhookRepository) UpdateWebhook(hook *models.Webhook) error {
+	_, err := r.db.Exec(`
+		UPDATE webhooks
+		SET url = $1, events = $2, active = $3,
+		    consecutive_failures = CASE WHEN $3 AND NOT active THEN 0 ELSE consecutive_failures END,
+		    disabled_reason = CASE WHEN $3 THEN NULL ELSE disabled_reason END,
+		    updated_at = NOW()
+		WHERE id = $4 AND project_id = $5
+	`, hook.URL, pq.Array(hook.Events), hook.Active, hook.ID, hook.ProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (r *WebhookRepository) DeleteWebhook(projectID, webhookID string) error {
+	result, err := r.db.Exec(`DELETE FROM webhooks WHERE id = $1 AND project_id = $2`, webhookID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+	return nil
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_type, d.payload, d.status, d.attempts, d.response_code, d.error, d.duration_ms, d.created_at, d.delivered_at`
+
+// scanDelivery scans a row selected with deliveryColumns
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	var responseCode, durationMs sql.NullInt64
+	var deliveryErr sql.NullString
+	var deliveredAt sql.NullTime
+
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &responseCode, &deliveryErr,
+		&durationMs, &d.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	if responseCode.Valid {
+		code := int(responseCode.Int64)
+		d.ResponseCode = &code
+	}
+	if deliveryErr.Valid {
+		d.Error = &deliveryErr.String
+	}
+	if durationMs.Valid {
+		ms := int(durationMs.Int64)
+		d.DurationMs = &ms
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
+
+// CreateDelivery stores a pending delivery and queues the job that sends it
+// in one transaction. The job payload is {"delivery_id": ...}.
+func (r *WebhookRepository) CreateDelivery(delivery *models.WebhookDelivery, jobType string, maxAttempts int) error {
+	if delivery.ID == "" {
+		delivery.ID = uuid.New().String()
+	}
+	delivery.Status = models.DeliveryPending
+	delivery.CreatedAt = time.Now()
+
+	jobPayload, err := json.Marshal(map[string]string{"delivery_id": delivery.ID})
+	if err != nil {
+		return fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_deliveries (id, webhook_id, event_type, payload, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, delivery.ID, delivery.WebhookID, delivery.EventType, []byte(delivery.Payload), delivery.Status, delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create delivery: %w", err)
+	}
+
+	if _, err := enqueueJob(tx, jobType, jobPayload, delivery.CreatedAt, maxAttempts, ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDelivery returns a delivery
+func (r *WebhookRepository) GetDelivery(deliveryID string) (*models.WebhookDelivery, error) {
+	d, err := scanDelivery(r.db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE d.id = $1`, deliveryID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+	return d, nil
+}
+
+// GetDeliveries returns the most recent deliveries of a webhook
+func (r *WebhookRepository) GetDeliveries(webhookID string, limit int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.Query(`
+		SELECT `+deliveryColumns+` FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt stores the outcome of a delivery attempt
+func (r *WebhookRepository) RecordAttempt(d *models.WebhookDelivery) error {
+	_, err := r.db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, response_code = $3, error = $4, duration_ms = $5, delivered_at = $6
+		WHERE id = $7
+	`, d.Status, d.Attempts, d.ResponseCode, d.Error, d.DurationMs, d.DeliveredAt, d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// RecordSuccess clears the webhook's failure count
+func (r *WebhookRepository) RecordSuccess(webhookID string) error {
+	_, err := r.db.Exec(`UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures <> 0`, webhookID)
+	if err != nil {
+		return fmt.Errorf("failed to reset webhook failures: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed delivery and disables the webhook once
+// maxFailures deliveries in a row have failed. It reports whether the
+// webhook was disabled by this call.
+func (r *WebhookRepository) RecordFailure(webhookID string, maxFailures int) (bool, error) {
+	var disabled bool
+	err := r.db.QueryRow(`
+		UPDATE webhooks
+		SET consecutive_failures = consecutive_failures + 1,
+		    active = active AND consecutive_failures + 1 < $2,
+		    disabled_reason = CASE WHEN active AND consecutive_failures + 1 >= $2
+		                           THEN 'Disabled after ' || $2 || ' failed deliveries in a row'
+		                           ELSE disabled_reason END,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING disabled_reason IS NOT NULL AND consecutive_failures = $2
+	`, webhookID, maxFailures).Scan(&disabled)
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+	return disabled, nil
+}
+
+// DeleteDeliveriesBefore trims the delivery log
+func (r *WebhookRepository) DeleteDeliveriesBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM webhook_deliveries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
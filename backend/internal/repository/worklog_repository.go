@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"task-management/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrTimerRunning is returned when a user starts a timer while one is running
+var ErrTimerRunning = errors.New("a timer is already running")
+
+type WorkLogRepository struct {
+	db *sql.DB
+}
+
+func NewWorkLogRepository(db *sql.DB) *WorkLogRepository {
+	return &WorkLogRepository{db: db}
+}
+
+// workLogColumns lists the columns selected for every work log read. It must
+// be used together with workLogFrom and scanned with scanWorkLog.
+const workLogColumns = `
+	wl.id, wl.task_id, p.key, t.number, t.project_id, wl.user_id, u.name, wl.minutes, wl.log_date, wl.note,
+	wl.started_at, wl.ended_at, wl.created_at, wl.updated_at`
+
+const workLogFrom = `
+	FROM work_logs wl
+	INNER JOIN tasks t ON t.id = wl.task_id
+	INNER JOIN projects p ON p.id = t.project_id
+	INNER JOIN users u ON u.id = wl.user_id`
+
+// scanWorkLog scans a row selected with workLogColumns
+func scanWorkLog(row rowScanner) (*models.WorkLog, error) {
+	workLog := &models.WorkLog{}
+	var projectKey string
+	var number int
+	var minutes sql.NullInt64
+	var startedAt, endedAt, updatedAt sql.NullTime
+
+	err := row.Scan(&workLog.ID, &workLog.TaskID, &projectKey, &number, &workLog.ProjectID, &workLog.UserID,
+		&workLog.UserName, &minutes, &workLog.Date, &workLog.Note, &startedAt, &endedAt, &workLog.CreatedAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	workLog.TaskKey = taskKey(projectKey, number)
+	workLog.Minutes = int(minutes.Int64)
+	workLog.Running = !minutes.Valid
+	if startedAt.Valid {
+		workLog.StartedAt = &startedAt.Time
+	}
+	if endedAt.Valid {
+		workLog.EndedAt = &endedAt.Time
+	}
+	if updatedAt.Valid {
+		workLog.UpdatedAt = &updatedAt.Time
+	}
+	return workLog, nil
+}
+
+// CreateWorkLog records time spent on a task
+func (r *WorkLogRepository) CreateWorkLog(workLog *models.WorkLog) error {
+	workLog.ID = uuid.New().String()
+	now := time.Now()
+	workLog.CreatedAt = now
+	workLog.UpdatedAt = &now
+
+	_, err := r.db.Exec(`
+		INSERT INTO work_logs (id, task_id, user_id, minutes, log_date, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, workLog.ID, workLog.TaskID, workLog.UserID, workLog.Minutes, dateOf(workLog.Date), workLog.Note,
+		workLog.CreatedAt, workLog.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create work log: %w", err)
+	}
+	return nil
+}
+
+// GetWorkLog retrieves a work log with its task's key and project
+func (r *WorkLogRepository) GetWorkLog(id string) (*models.WorkLog, error) {
+	workLog, err := scanWorkLog(r.db.QueryRow(`SELECT `+workLogColumns+workLogFrom+` WHERE wl.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("work log not found")
+		}
+		return nil, fmt.Errorf("failed to get work log: %w", err)
+	}
+	return workLog, nil
+}
+
+// GetWorkLogsByTask lists a task's work logs, newest first. Running timers
+// are included.
+func (r *WorkLogRepository) GetWorkLogsByTask(taskID string) ([]*models.WorkLog, error) {
+	rows, err := r.db.Query(`SELECT `+workLogColumns+workLogFrom+`
+		WHERE wl.task_id = $1
+		ORDER BY wl.log_date DESC, wl.created_at DESC
+	`, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get work logs: %w", err)
+	}
+	defer rows.Close()
+
+	workLogs := []*models.WorkLog{}
+	for rows.Next() {
+		workLog, err := scanWorkLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan work log: %w", err)
+		}
+		workLogs = append(workLogs, workLog)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate work logs: %w", err)
+	}
+	return workLogs, nil
+}
+
+// UpdateWorkLog changes the minutes, date and note of a stopped work log
+func (r *WorkLogRepository) UpdateWorkLog(workLog *models.WorkLog) error {
+	now := time.Now()
+	workLog.UpdatedAt = &now
+
+	result, err := r.db.Exec(`
+		UPDATE work_logs SET minutes = $1, log_date = $2, note = $3, updated_at = $4
+		WHERE id = $5 AND minutes IS NOT NULL
+	`, workLog.Minutes, dateOf(workLog.Date), workLog.Note, workLog.UpdatedAt, workLog.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update work log: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("work log not found or still running")
+	}
+	return nil
+}
+
+// DeleteWorkLog deletes a work log, a running timer included
+func (r *WorkLogRepository) DeleteWorkLog(id string) error {
+	result, err := r.db.Exec(`DELETE FROM work_logs WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete work log: %w", err)
+	}
+	if rowsAffected(result) == 0 {
+		return fmt.Errorf("work log not found")
+	}
+	return nil
+}
+
+// StartTimer starts a timer for the user on a task. It returns
+// ErrTimerRunning if the user already has one running.
+func (r *WorkLogRepository) StartTimer(taskID, userID, note string) (*models.WorkLog, error) {
+	id := uuid.New().String()
+	_, err := r.db.Exec(`
+		INSERT INTO work_logs (id, task_id, user_id, log_date, note, started_at, created_at, updated_at)
+		VALUES ($1, $2, $3, CURRENT_DATE, $4, NOW(), NOW(), NOW())
+	`, id, taskID, userID, note)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "idx_work_logs_one_timer" {
+			return nil, ErrTimerRunning
+		}
+		return nil, fmt.Errorf("failed to start timer: %w", err)
+	}
+	return r.GetWorkLog(id)
+}
+
+// GetRunningTimer returns the user's running timer, or nil if there is none
+func (r *WorkLogRepository) GetRunningTimer(userID string) (*models.WorkLog, error) {
+	workLog, err := scanWorkLog(r.db.QueryRow(`SELECT `+workLogColumns+workLogFrom+`
+		WHERE wl.user_id = $1 AND wl.minutes IS NULL
+	`, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get running timer: %w", err)
+	}
+	return workLog, nil
+}
+
+// StopTimer stops the user's running timer. The elapsed time is rounded up
+// to whole minutes, at least one. It returns nil if no timer was running.
+func (r *WorkLogRepository) StopTimer(userID string) (*models.WorkLog, error) {
+	var id string
+	err := r.db.QueryRow(`
+		UPDATE work_logs
+		SET minutes = GREATEST(1, CEIL(EXTRACT(EPOCH FROM NOW() - started_at) / 60))::INT,
+		    ended_at = NOW(), updated_at = NOW()
+		WHERE user_id = $1 AND minutes IS NULL
+		RETURNING id
+	`, userID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to stop timer: %w", err)
+	}
+	return r.GetWorkLog(id)
+}
+
+// TimeReportFilter selects the work logs a time report totals. Empty fields
+// don't filter.
+type TimeReportFilter struct {
+	ProjectID string
+	UserID    string
+	From      *time.Time
+	To        *time.Time
+}
+
+// Report totals logged time per task and per user. Running timers don't
+// count. A project report for all users also lists the project's open tasks
+// that have an estimate but no time logged in the range, so estimates and
+// logged time can be compared.
+func (r *WorkLogRepository) Report(filter TimeReportFilter) (*models.TimeReport, error) {
+	conditions := []string{"wl.minutes IS NOT NULL", "t.deleted_at IS NULL"}
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ProjectID != "" {
+		add("t.project_id = $%d", filter.ProjectID)
+	}
+	if filter.UserID != "" {
+		add("wl.user_id = $%d", filter.UserID)
+	}
+	if filter.From != nil {
+		add("wl.log_date >= $%d", dateOf(*filter.From))
+	}
+	if filter.To != nil {
+		add("wl.log_date <= $%d", dateOf(*filter.To))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	report := &models.TimeReport{
+		Tasks: []models.TimeReportTask{},
+		Users: []models.TimeReportUser{},
+	}
+
+	estimated := "FALSE"
+	if filter.ProjectID != "" && filter.UserID == "" {
+		estimated = "(t.project_id = $1 AND t.estimate_minutes IS NOT NULL AND t.status <> 'done')"
+	}
+	rows, err := r.db.Query(`
+		WITH logged AS (
+			SELECT wl.task_id, SUM(wl.minutes) AS minutes
+			FROM work_logs wl
+			INNER JOIN tasks t ON t.id = wl.task_id
+			WHERE `+where+`
+			GROUP BY wl.task_id
+		)
+		SELECT t.id, p.key, t.number, t.title, t.project_id, t.status, t.estimate_minutes, COALESCE(l.minutes, 0)
+		FROM tasks t
+		INNER JOIN projects p ON p.id = t.project_id
+		LEFT JOIN logged l ON l.task_id = t.id
+		WHERE t.deleted_at IS NULL AND (l.task_id IS NOT NULL OR `+estimated+`)
+		ORDER BY p.key, t.number
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task models.TimeReportTask
+		var projectKey string
+		var number int
+		var estimate sql.NullInt64
+		if err := rows.Scan(&task.TaskID, &projectKey, &number, &task.Title, &task.ProjectID, &task.Status,
+			&estimate, &task.LoggedMinutes); err != nil {
+			return nil, fmt.Errorf("failed to scan time report task: %w", err)
+		}
+		task.Key = taskKey(projectKey, number)
+		if estimate.Valid {
+			minutes := int(estimate.Int64)
+			task.EstimateMinutes = &minutes
+			report.TotalEstimateMinutes += minutes
+		}
+		report.TotalLoggedMinutes += task.LoggedMinutes
+		report.Tasks = append(report.Tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate time report tasks: %w", err)
+	}
+
+	userRows, err := r.db.Query(`
+		SELECT u.id, u.name, u.email, SUM(wl.minutes)
+		FROM work_logs wl
+		INNER JOIN tasks t ON t.id = wl.task_id
+		INNER JOIN users u ON u.id = wl.user_id
+		WHERE `+where+`
+		GROUP BY u.id, u.name, u.email
+		ORDER BY u.name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time report users: %w", err)
+	}
+	defer userRows.Close()
+
+	for userRows.Next() {
+		var user models.TimeReportUser
+		if err := userRows.Scan(&user.UserID, &user.Name, &user.Email, &user.LoggedMinutes); err != nil {
+			return nil, fmt.Errorf("failed to scan time report user: %w", err)
+		}
+		report.Users = append(report.Users, user)
+	}
+	if err := userRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate time report users: %w", err)
+	}
+	return report, nil
+}
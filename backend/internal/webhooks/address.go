@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// errBlockedAddress is returned for destinations on the server's own or
+// internal networks, which webhooks must not reach
+var errBlockedAddress = errors.New("destination address is not allowed")
+
+// sharedAddressSpace is carrier-grade NAT (RFC 6598), used for internal
+// networks by some cloud providers
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// nat64Prefix embeds an IPv4 address in an IPv6 one (RFC 6052)
+var nat64Prefix = &net.IPNet{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)}
+
+// blockedIP reports whether ip is loopback, private, link-local, unspecified
+// or otherwise not a public unicast address
+func blockedIP(ip net.IP) bool {
+	if nat64Prefix.Contains(ip) {
+		ip = net.IP(ip[12:16])
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip[0] == 0 || ip.Equal(net.IPv4bcast) || sharedAddressSpace.Contains(ip) {
+			return true
+		}
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// CheckURL rejects webhook URLs whose host is, or resolves to, an address
+// webhooks must not reach. Deliveries check the address again when they
+// connect, since DNS can change after registration.
+func CheckURL(ctx context.Context, u *url.URL) error {
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if blockedIP(ip) {
+			return errBlockedAddress
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("host %q could not be resolved", host)
+	}
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return errBlockedAddress
+		}
+	}
+	return nil
+}
+
+// newTransport returns a transport that refuses to connect to blocked
+// addresses. The check runs on the resolved address of each connection, so
+// a host that later resolves to an internal address is still refused.
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: requestTimeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || blockedIP(ip) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	return &http.Transport{
+		// No proxy: the dialer must see the webhook's own address
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   requestTimeout,
+		ResponseHeaderTimeout: requestTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+}
+
+// describeError turns a failed request into a short message for the
+// delivery log that doesn't echo anything from the endpoint
+func describeError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errBlockedAddress):
+		return errBlockedAddress.Error()
+	case errors.As(err, &dnsErr):
+		return "host could not be resolved"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "connection failed"
+	}
+}
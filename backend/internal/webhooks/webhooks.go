@@ -0,0 +1,290 @@
+// Package webhooks sends project events to registered HTTP endpoints.
+//
+// Every event a webhook subscribes to becomes a delivery row plus a job in
+// the background queue; the job POSTs the signed payload and is retried with
+// exponential backoff until it succeeds or runs out of attempts.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-management/internal/events"
+	"task-management/internal/jobs"
+	"task-management/internal/models"
+	"task-management/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// JobTypeDeliver is the job that sends one delivery
+	JobTypeDeliver = "deliver-webhook"
+	// MaxAttempts is how often a delivery is tried before it counts as failed
+	MaxAttempts = 5
+	// MaxConsecutiveFailures failed deliveries in a row disable a webhook
+	MaxConsecutiveFailures = 10
+
+	requestTimeout = 10 * time.Second
+
+	// Request headers
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+)
+
+// Payload is the JSON body POSTed to webhooks
+type Payload struct {
+	DeliveryID string `json:"delivery_id"`
+	events.Event
+	// Watchers maps each task of a task event to the IDs of its watchers
+	// that get notified about it, so receivers can notify only them
+	Watchers map[string][]string `json:"watchers,omitempty"`
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher turns events into queued deliveries
+type Dispatcher struct {
+	webhookRepo *repository.WebhookRepository
+	watcherRepo *repository.WatcherRepository
+}
+
+func NewDispatcher(webhookRepo *repository.WebhookRepository, watcherRepo *repository.WatcherRepository) *Dispatcher {
+	return &Dispatcher{webhookRepo: webhookRepo, watcherRepo: watcherRepo}
+}
+
+// Handle queues a delivery of event to every subscribed webhook of its
+// project. Subscribe it to the event bus.
+func (d *Dispatcher) Handle(event events.Event) {
+	hooks, err := d.webhookRepo.GetSubscribedWebhooks(event.ProjectID, event.Type)
+	if err != nil {
+		log.Printf("[WEBHOOK] Error finding webhooks for %s in project %s: %v", event.Type, event.ProjectID, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	// The watchers are read once for all webhooks of the event
+	var watchers map[string][]string
+	if taskIDs := eventTaskIDs(event); len(taskIDs) > 0 {
+		watchers, err = d.watcherRepo.GetNotifiedWatcherIDs(taskIDs)
+		if err != nil {
+			log.Printf("[WEBHOOK] Error getting watchers for %s in project %s: %v", event.Type, event.ProjectID, err)
+			return
+		}
+	}
+	for _, hook := range hooks {
+		if _, err := d.queue(hook, event, watchers); err != nil {
+			log.Printf("[WEBHOOK] Error queueing %s for webhook %s: %v", event.Type, hook.ID, err)
+		}
+	}
+}
+
+// eventTaskIDs returns the IDs of the tasks an event is about
+func eventTaskIDs(event events.Event) []string {
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if task, ok := data["task"].(*models.Task); ok && task != nil {
+		return []string{task.ID}
+	}
+	tasks, _ := data["tasks"].([]*models.Task)
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+// Queue creates a delivery of event to hook and queues it for sending
+func (d *Dispatcher) Queue(hook *models.Webhook, event events.Event) (*models.WebhookDelivery, error) {
+	return d.queue(hook, event, nil)
+}
+
+// queue is Queue with the watchers of the event's tasks
+func (d *Dispatcher) queue(hook *models.Webhook, event events.Event, watchers map[string][]string) (*models.WebhookDelivery, error) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.New().String(),
+		WebhookID: hook.ID,
+		EventType: event.Type,
+	}
+
+	body, err := json.Marshal(Payload{DeliveryID: delivery.ID, Event: event, Watchers: watchers})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	delivery.Payload = body
+
+	if err := d.webhookRepo.CreateDelivery(delivery, JobTypeDeliver, MaxAttempts); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// NewDeliveryJob returns the job that sends a queued delivery. A failed
+// attempt returns an error so the job queue retries it with backoff; the
+// last attempt marks the delivery failed and counts against the webhook.
+func NewDeliveryJob(webhookRepo *repository.WebhookRepository) jobs.JobFunc {
+	client := &http.Client{
+		Timeout:   requestTimeout,
+		Transport: newTransport(),
+		// Redirects are not followed so a webhook can't be bounced to another host
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return func(ctx context.Context, payload json.RawMessage) error {
+		var p struct {
+			DeliveryID string `json:"delivery_id"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return fmt.Errorf("invalid delivery payload: %w", err)
+		}
+
+		delivery, err := webhookRepo.GetDelivery(p.DeliveryID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return nil // webhook deleted in the meantime
+			}
+			return err
+		}
+		if delivery.Status == models.DeliverySucceeded || delivery.Status == models.DeliveryFailed {
+			return nil
+		}
+		hook, err := webhookRepo.GetWebhookByID("", delivery.WebhookID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				return nil
+			}
+			return err
+		}
+
+		// Disabled webhooks only receive test pings
+		if !hook.Active && delivery.EventType != events.Ping {
+			msg := "webhook is disabled"
+			delivery.Status = models.DeliveryFailed
+			delivery.Error = &msg
+			return webhookRepo.RecordAttempt(delivery)
+		}
+
+		sendErr := send(ctx, client, hook, delivery)
+		delivery.Attempts++
+
+		if sendErr == nil {
+			now := time.Now()
+			delivery.Status = models.DeliverySucceeded
+			delivery.Error = nil
+			delivery.DeliveredAt = &now
+			if err := webhookRepo.RecordAttempt(delivery); err != nil {
+				return err
+			}
+			return webhookRepo.RecordSuccess(hook.ID)
+		}
+
+		if ctx.Err() != nil {
+			// Shutting down; the attempt is not counted
+			return ctx.Err()
+		}
+
+		msg := sendErr.Error()
+		delivery.Error = &msg
+		if delivery.Attempts < MaxAttempts {
+			delivery.Status = models.DeliveryRetrying
+			if err := webhookRepo.RecordAttempt(delivery); err != nil {
+				return err
+			}
+			return sendErr
+		}
+
+		delivery.Status = models.DeliveryFailed
+		if err := webhookRepo.RecordAttempt(delivery); err != nil {
+			return err
+		}
+		if delivery.EventType == events.Ping {
+			return nil // test pings don't count against the webhook
+		}
+		disabled, err := webhookRepo.RecordFailure(hook.ID, MaxConsecutiveFailures)
+		if err != nil {
+			return err
+		}
+		if disabled {
+			log.Printf("[WEBHOOK] Disabled webhook %s of project %s after %d failed deliveries", hook.ID, hook.ProjectID, MaxConsecutiveFailures)
+		}
+		return nil
+	}
+}
+
+// send POSTs the delivery and records the response status on it. Any status
+// other than 2xx is an error. The response body is not kept, so the delivery
+// log can't be used to read pages the endpoint serves.
+func send(ctx context.Context, client *http.Client, hook *models.Webhook, delivery *models.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, strings.NewReader(string(delivery.Payload)))
+	if err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskManagement-Webhook/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, delivery.Payload))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	duration := int(time.Since(start).Milliseconds())
+	delivery.DurationMs = &duration
+	delivery.ResponseCode = nil
+	if err != nil {
+		return errors.New(describeError(err))
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	code := resp.StatusCode
+	delivery.ResponseCode = &code
+
+	if code < 200 || code >= 300 {
+		return fmt.Errorf("endpoint responded with status %d", code)
+	}
+	return nil
+}
+
+// NewCleanupJob returns a job that trims deliveries older than retention from the log
+func NewCleanupJob(webhookRepo *repository.WebhookRepository, retention time.Duration) jobs.JobFunc {
+	return func(ctx context.Context, payload json.RawMessage) error {
+		_, err := webhookRepo.DeleteDeliveriesBefore(time.Now().Add(-retention))
+		return err
+	}
+}
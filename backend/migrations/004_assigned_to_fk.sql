@@ -0,0 +1,11 @@
+-- Migration 004: Foreign key for task assignments
+-- assigned_to was added without a constraint, so deleted users could leave
+-- tasks pointing at missing accounts. Clean those up, then enforce it.
+
+UPDATE tasks SET assigned_to = NULL
+WHERE assigned_to IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM users u WHERE u.id = tasks.assigned_to);
+
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_assigned_to_fkey;
+ALTER TABLE tasks ADD CONSTRAINT tasks_assigned_to_fkey
+    FOREIGN KEY (assigned_to) REFERENCES users(id) ON DELETE SET NULL;
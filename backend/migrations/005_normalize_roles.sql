@@ -0,0 +1,26 @@
+-- Migration 005: Canonical project roles
+-- Migration 002 inserted 'PO' in uppercase while the application checks for
+-- lowercase roles. Normalize every role, repair projects left without a PO
+-- and enforce the canonical set from now on.
+
+UPDATE project_members SET role = LOWER(TRIM(role)) WHERE role <> LOWER(TRIM(role));
+
+-- Anything unrecognised becomes the least privileged role
+UPDATE project_members SET role = 'viewer' WHERE role NOT IN ('po', 'pm', 'member', 'viewer');
+
+-- Every project needs an owner: promote its longest-standing PM, or failing
+-- that its longest-standing member
+UPDATE project_members pm SET role = 'po'
+FROM (
+    SELECT DISTINCT ON (m.project_id) m.id
+    FROM project_members m
+    WHERE NOT EXISTS (
+        SELECT 1 FROM project_members o WHERE o.project_id = m.project_id AND o.role = 'po'
+    )
+    ORDER BY m.project_id, CASE m.role WHEN 'pm' THEN 0 WHEN 'member' THEN 1 ELSE 2 END, m.joined_at
+) heir
+WHERE pm.id = heir.id;
+
+ALTER TABLE project_members DROP CONSTRAINT IF EXISTS project_members_role_check;
+ALTER TABLE project_members ADD CONSTRAINT project_members_role_check
+    CHECK (role IN ('po', 'pm', 'member', 'viewer'));
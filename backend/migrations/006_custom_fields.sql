@@ -0,0 +1,25 @@
+-- Migration 006: Project custom fields
+-- Projects define their own fields; each task stores its values in a JSONB
+-- object keyed by field ID.
+
+CREATE TABLE IF NOT EXISTS project_custom_fields (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    field_type VARCHAR(20) NOT NULL
+        CHECK (field_type IN ('text', 'number', 'date', 'single_select', 'multi_select', 'user')),
+    options JSONB NOT NULL DEFAULT '[]',
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    validation JSONB NOT NULL DEFAULT '{}',
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(project_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_custom_fields_project_id ON project_custom_fields(project_id);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+
+-- GIN index serves the containment and key-existence filters
+CREATE INDEX IF NOT EXISTS idx_tasks_custom_fields ON tasks USING GIN (custom_fields);
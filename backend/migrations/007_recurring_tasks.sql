@@ -0,0 +1,37 @@
+-- Migration 007: Labels and recurring tasks
+-- A series in task_recurrences holds the rule, the due date of the first
+-- occurrence and a template copied into every new occurrence. Occurrences are
+-- regular tasks numbered by recurrence_index within their series.
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE IF NOT EXISTS task_recurrences (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    rule TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    priority VARCHAR(20) NOT NULL DEFAULT 'medium',
+    assigned_to UUID REFERENCES users(id) ON DELETE SET NULL,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    custom_fields JSONB NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_recurrences_active ON task_recurrences(project_id) WHERE active;
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_id UUID REFERENCES task_recurrences(id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_index INT;
+
+-- Each occurrence is created at most once, whichever replica gets there first.
+-- Plain constraint (not a partial index) so it can be an ON CONFLICT target.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tasks_recurrence_occurrence_key') THEN
+        ALTER TABLE tasks ADD CONSTRAINT tasks_recurrence_occurrence_key UNIQUE (recurrence_id, recurrence_index);
+    END IF;
+END $$;
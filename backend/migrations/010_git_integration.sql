@@ -0,0 +1,29 @@
+-- Migration 010: Inbound Git push hooks and task activity
+-- A project's Git integration holds the shared secret that GitHub
+-- (X-Hub-Signature-256) or GitLab (X-Gitlab-Token) hooks are verified with,
+-- and the keywords that move referenced tasks to a status.
+
+CREATE TABLE IF NOT EXISTS git_integrations (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    secret VARCHAR(128) NOT NULL,
+    keywords JSONB NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Activity log of a task. ref identifies the source of an entry (e.g. the
+-- commit SHA) so a redelivered push doesn't link the same commit twice.
+CREATE TABLE IF NOT EXISTS task_activity (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}',
+    ref VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT task_activity_ref_key UNIQUE (task_id, type, ref)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_activity_task_id ON task_activity(task_id, created_at);
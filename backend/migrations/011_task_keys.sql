@@ -0,0 +1,62 @@
+-- Migration 011: Human-readable task keys
+-- Every project has a unique key (e.g. WEB) and numbers its tasks from 1, so a
+-- task can be referred to as WEB-42. projects.task_seq is the last number
+-- handed out; it is incremented in the statement that inserts the task.
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS key VARCHAR(10);
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS task_seq INT NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS number INT;
+
+-- Give existing projects a key from the initials of their name (or its first
+-- letters), adding a digit when the key is already taken
+DO $$
+DECLARE
+    project RECORD;
+    base TEXT;
+    candidate TEXT;
+    n INT;
+BEGIN
+    FOR project IN SELECT id, name FROM projects WHERE key IS NULL ORDER BY created_at, id LOOP
+        base := UPPER(regexp_replace(project.name, '[^A-Za-z0-9 ]', '', 'g'));
+        IF array_length(regexp_split_to_array(trim(base), '\s+'), 1) > 1 THEN
+            SELECT string_agg(left(word, 1), '') INTO base
+            FROM unnest(regexp_split_to_array(trim(base), '\s+')) AS word;
+        END IF;
+        base := left(regexp_replace(base, '[^A-Z0-9]', '', 'g'), 4);
+        IF base !~ '^[A-Z][A-Z0-9]+$' THEN
+            base := 'PRJ';
+        END IF;
+
+        candidate := base;
+        n := 1;
+        WHILE EXISTS (SELECT 1 FROM projects WHERE key = candidate) LOOP
+            n := n + 1;
+            candidate := base || n;
+        END LOOP;
+        UPDATE projects SET key = candidate WHERE id = project.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE projects ALTER COLUMN key SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_key ON projects(key);
+
+-- Number existing tasks by creation time, after any already numbered
+UPDATE tasks t
+SET number = numbered.number
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY created_at, id)
+               + COALESCE((SELECT MAX(number) FROM tasks x WHERE x.project_id = tasks.project_id), 0) AS number
+    FROM tasks
+    WHERE project_id IS NOT NULL AND number IS NULL
+) numbered
+WHERE t.id = numbered.id;
+
+UPDATE projects p
+SET task_seq = GREATEST(p.task_seq, COALESCE((SELECT MAX(number) FROM tasks WHERE project_id = p.id), 0));
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'tasks_project_number_key') THEN
+        ALTER TABLE tasks ADD CONSTRAINT tasks_project_number_key UNIQUE (project_id, number);
+    END IF;
+END $$;
@@ -0,0 +1,22 @@
+-- Migration 012: Task imports
+-- Large imports are validated in the request and run by a job; the validated
+-- tasks wait in task_imports.tasks until the job inserts them in one
+-- transaction. processed is updated as the job goes so progress can be shown.
+
+CREATE TABLE IF NOT EXISTS task_imports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    format VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    total INT NOT NULL DEFAULT 0,
+    processed INT NOT NULL DEFAULT 0,
+    error TEXT,
+    tasks JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_imports_project_id ON task_imports(project_id, created_at);
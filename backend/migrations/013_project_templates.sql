@@ -0,0 +1,17 @@
+-- Migration 013: Project templates
+-- A template is a snapshot of a project's custom fields and tasks that new
+-- projects can start from. The snapshot is stored as JSON so later changes
+-- to the source project don't affect it; the source may even be deleted.
+
+CREATE TABLE IF NOT EXISTS project_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    source_project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    content JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_templates_created_by ON project_templates(created_by);
+CREATE INDEX IF NOT EXISTS idx_project_templates_source ON project_templates(source_project_id);
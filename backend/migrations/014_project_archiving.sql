@@ -0,0 +1,7 @@
+-- Migration 014: Project archiving
+-- An archived project is read-only and hidden from project lists unless
+-- they ask for archived projects. Reminders and recurring tasks skip it.
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_projects_archived_at ON projects(archived_at);
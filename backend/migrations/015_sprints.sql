@@ -0,0 +1,29 @@
+-- Migration 015: Sprints
+-- A sprint groups a project's tasks into a time box. Tasks without a sprint
+-- are the backlog. A project has at most one active sprint; closing it moves
+-- its unfinished tasks to another sprint or the backlog and records how many
+-- were carried over.
+
+CREATE TABLE IF NOT EXISTS sprints (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    goal TEXT NOT NULL DEFAULT '',
+    start_date DATE,
+    end_date DATE,
+    state VARCHAR(20) NOT NULL DEFAULT 'planned'
+        CHECK (state IN ('planned', 'active', 'closed')),
+    carried_over INT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT sprints_dates_check CHECK (end_date IS NULL OR start_date IS NULL OR end_date >= start_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sprints_project_id ON sprints(project_id, start_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sprints_one_active ON sprints(project_id) WHERE state = 'active';
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS sprint_id UUID REFERENCES sprints(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_sprint_id ON tasks(sprint_id);
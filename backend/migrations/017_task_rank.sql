@@ -0,0 +1,17 @@
+-- Migration 017: Manual task ordering
+-- Tasks are ordered within a status column by a fractional rank. Moving a
+-- task gives it a rank between its new neighbours, so only that row changes;
+-- columns whose ranks get too close are renumbered by a periodic job.
+-- Existing tasks keep their current order (newest first).
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS rank DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+UPDATE tasks t
+SET rank = ranked.n * 1024
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY project_id, status ORDER BY created_at DESC) AS n
+    FROM tasks
+) ranked
+WHERE t.id = ranked.id;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_rank ON tasks(project_id, status, rank);
@@ -0,0 +1,30 @@
+-- Migration 018: Row versions for optimistic concurrency
+-- Tasks and projects carry a version that is returned as their ETag. Clients
+-- send it back in If-Match and an update only applies if it still matches.
+-- Tasks are changed from many places (bulk operations, sprints, git pushes,
+-- moves), so a trigger bumps the version on every real change instead of
+-- each statement doing it. Board reordering (rank) and the task number
+-- counter (task_seq) are bookkeeping and leave the version alone.
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_version() RETURNS TRIGGER AS $$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['version', 'updated_at', 'rank', 'task_seq']
+       IS DISTINCT FROM to_jsonb(OLD) - ARRAY['version', 'updated_at', 'rank', 'task_seq'] THEN
+        NEW.version := OLD.version + 1;
+    ELSE
+        NEW.version := OLD.version;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tasks_bump_version ON tasks;
+CREATE TRIGGER tasks_bump_version BEFORE UPDATE ON tasks
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
+
+DROP TRIGGER IF EXISTS projects_bump_version ON projects;
+CREATE TRIGGER projects_bump_version BEFORE UPDATE ON projects
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
@@ -0,0 +1,25 @@
+-- Migration 019: Time tracking
+-- Tasks get an estimate, and time spent is recorded as work logs. A log is
+-- either entered directly or created by a timer: a running timer is a log
+-- without minutes, and stopping it fills them in from the elapsed time.
+-- Each user has at most one running timer.
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate_minutes INT CHECK (estimate_minutes > 0);
+
+CREATE TABLE IF NOT EXISTS work_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    minutes INT CHECK (minutes > 0),
+    log_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    note TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT work_logs_timer_check CHECK (minutes IS NOT NULL OR started_at IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_logs_task_id ON work_logs(task_id);
+CREATE INDEX IF NOT EXISTS idx_work_logs_user_date ON work_logs(user_id, log_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_work_logs_one_timer ON work_logs(user_id) WHERE minutes IS NULL;
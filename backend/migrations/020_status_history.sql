@@ -0,0 +1,40 @@
+-- Migration 020: Task status history for analytics
+-- Every status a task enters is recorded with the time it happened, which
+-- burndown, cumulative flow and cycle time are computed from. Like versions,
+-- a trigger records it so every code path that changes a status is covered.
+-- Existing tasks get an approximate history: todo when they were created and
+-- their current status at their last update.
+
+CREATE TABLE IF NOT EXISTS task_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_status_history_task ON task_status_history(task_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_task_status_history_status ON task_status_history(status, changed_at);
+
+CREATE OR REPLACE FUNCTION record_task_status() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO task_status_history (task_id, status, changed_at) VALUES (NEW.id, NEW.status, NOW());
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tasks_record_status ON tasks;
+CREATE TRIGGER tasks_record_status AFTER INSERT OR UPDATE OF status ON tasks
+    FOR EACH ROW EXECUTE FUNCTION record_task_status();
+
+INSERT INTO task_status_history (task_id, status, changed_at)
+SELECT t.id, 'todo', t.created_at
+FROM tasks t
+WHERE NOT EXISTS (SELECT 1 FROM task_status_history h WHERE h.task_id = t.id);
+
+INSERT INTO task_status_history (task_id, status, changed_at)
+SELECT t.id, t.status, GREATEST(COALESCE(t.updated_at, t.created_at), t.created_at + INTERVAL '1 second')
+FROM tasks t
+WHERE t.status <> 'todo'
+  AND NOT EXISTS (SELECT 1 FROM task_status_history h WHERE h.task_id = t.id AND h.status <> 'todo');
@@ -0,0 +1,39 @@
+-- Migration 021: Task watchers
+-- Watchers get the notifications about a task. A muted watcher stays
+-- subscribed but is not notified, so being assigned again doesn't undo it.
+-- Creators and assignees are subscribed by a trigger, which covers every
+-- way a task is created or reassigned (bulk edits, imports, clones).
+
+CREATE TABLE IF NOT EXISTS task_watchers (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_watchers_user ON task_watchers(user_id);
+
+CREATE OR REPLACE FUNCTION watch_task() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO task_watchers (task_id, user_id) VALUES (NEW.id, NEW.user_id)
+        ON CONFLICT DO NOTHING;
+    END IF;
+    IF NEW.assigned_to IS NOT NULL AND (TG_OP = 'INSERT' OR NEW.assigned_to IS DISTINCT FROM OLD.assigned_to) THEN
+        INSERT INTO task_watchers (task_id, user_id) VALUES (NEW.id, NEW.assigned_to)
+        ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tasks_watch ON tasks;
+CREATE TRIGGER tasks_watch AFTER INSERT OR UPDATE OF assigned_to ON tasks
+    FOR EACH ROW EXECUTE FUNCTION watch_task();
+
+INSERT INTO task_watchers (task_id, user_id)
+SELECT id, user_id FROM tasks
+UNION
+SELECT id, assigned_to FROM tasks WHERE assigned_to IS NOT NULL
+ON CONFLICT DO NOTHING;
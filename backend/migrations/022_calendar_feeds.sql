@@ -0,0 +1,18 @@
+-- Migration 022: iCalendar feeds
+-- A feed is reached with a secret token in its URL, since calendar apps
+-- can't send a JWT. Only a SHA-256 hash of the token is stored; deleting the
+-- row revokes the URL. A feed without a project lists the tasks assigned to
+-- its user; a project feed lists the project's tasks. Each user has at most
+-- one feed of each kind, and creating it again rotates the token.
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_user_project
+    ON calendar_feeds(user_id, COALESCE(project_id, '00000000-0000-0000-0000-000000000000'));
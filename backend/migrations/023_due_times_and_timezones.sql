@@ -0,0 +1,29 @@
+-- Migration 023: Due dates with a time of day, and user timezones
+-- Task due dates become TIMESTAMPTZ so a deadline can be a moment. A task
+-- that is due all day keeps a calendar day instead, stored as midnight UTC
+-- of that day. Due dates so far were all days and were written as midnight
+-- UTC, so existing values convert as UTC and are marked all-day. Recurring
+-- series get the same treatment and remember the timezone of their creator,
+-- whose wall clock timed occurrences follow. Users get a timezone that
+-- "overdue", "due today" and reminders are worked out in.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Existing rows are all-day; new ones say what they are
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS due_all_day BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE tasks ALTER COLUMN due_all_day SET DEFAULT FALSE;
+ALTER TABLE task_recurrences ADD COLUMN IF NOT EXISTS due_all_day BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE task_recurrences ALTER COLUMN due_all_day SET DEFAULT FALSE;
+ALTER TABLE task_recurrences ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'tasks' AND column_name = 'due_date') = 'timestamp without time zone' THEN
+        ALTER TABLE tasks ALTER COLUMN due_date TYPE TIMESTAMPTZ USING due_date AT TIME ZONE 'UTC';
+    END IF;
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'task_recurrences' AND column_name = 'starts_at') = 'timestamp without time zone' THEN
+        ALTER TABLE task_recurrences ALTER COLUMN starts_at TYPE TIMESTAMPTZ USING starts_at AT TIME ZONE 'UTC';
+    END IF;
+END $$;
@@ -0,0 +1,5 @@
+-- Migration 024: Stop keeping webhook response bodies
+-- The delivery log showed what endpoints answered, which let a webhook read
+-- pages it was pointed at. Only the status code is kept from now on.
+
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body;
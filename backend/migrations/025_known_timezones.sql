@@ -0,0 +1,11 @@
+-- Migration 025: Reset timezones the database doesn't know
+-- Reminder and overdue queries convert due dates with AT TIME ZONE for all
+-- users at once, so one unknown name fails them for everyone. Timezones are
+-- now checked against pg_timezone_names when saved; earlier ones fall back
+-- to UTC.
+
+UPDATE users SET timezone = 'UTC'
+WHERE timezone NOT IN (SELECT name FROM pg_timezone_names);
+
+UPDATE task_recurrences SET timezone = 'UTC'
+WHERE timezone NOT IN (SELECT name FROM pg_timezone_names);
@@ -2,4 +2,27 @@
 psql -U postgres -d taskmanagement -f /tmp/migrations/001_create_tables.sql
 psql -U postgres -d taskmanagement -f /tmp/migrations/002_add_collaboration.sql
 psql -U postgres -d taskmanagement -f /tmp/migrations/add_assigned_to.sql
-echo "✓ All migrations completed!"
\ No newline at end of file
+psql -U postgres -d taskmanagement -f /tmp/migrations/003_soft_delete.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/004_assigned_to_fk.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/005_normalize_roles.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/006_custom_fields.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/007_recurring_tasks.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/008_jobs_and_notifications.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/009_webhooks.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/010_git_integration.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/011_task_keys.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/012_task_imports.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/013_project_templates.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/014_project_archiving.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/015_sprints.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/016_milestones.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/017_task_rank.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/018_versions.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/019_time_tracking.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/020_status_history.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/021_task_watchers.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/022_calendar_feeds.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/023_due_times_and_timezones.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/024_drop_webhook_response_body.sql
+psql -U postgres -d taskmanagement -f /tmp/migrations/025_known_timezones.sql
+echo "✓ All migrations completed!"
@@ -242,7 +242,9 @@ export default function AdminPage() {
                 onClose={() => setIsDeleteDialogOpen(false)}
                 onConfirm={confirmDelete}
                 title={`Delete ${itemToDelete?.type === 'project' ? 'Project' : 'User'}`}
-                message={`Are you sure you want to delete this ${itemToDelete?.type}? This action cannot be undone.`}
+                message={itemToDelete?.type === 'user'
+                    ? 'Are you sure you want to delete this user? They are removed from all projects and their tasks are handed over. The account is moved to the trash and permanently removed after the retention period; restoring it does not bring back memberships or tasks.'
+                    : 'Are you sure you want to delete this project? It will be moved to the trash and permanently removed after the retention period.'}
                 confirmText="Delete"
                 cancelText="Cancel"
                 type="danger"
@@ -99,7 +99,17 @@ export default function DashboardPage() {
                 priority: taskData.priority,
                 due_date: taskData.due_date,
             };
-            await api.put(`/tasks/${selectedTask.id}`, updateData);
+            try {
+                await api.put(`/tasks/${selectedTask.id}`, updateData, {
+                    headers: { 'If-Match': `"${selectedTask.version}"` },
+                });
+            } catch (err: any) {
+                if (err.response?.status === 412) {
+                    await fetchTasks();
+                    throw new Error('This task was changed by someone else. Reopen it and try again.');
+                }
+                throw err;
+            }
             await fetchTasks();
         }
     };
@@ -131,14 +141,21 @@ export default function DashboardPage() {
         try {
             const task = tasks.find((t) => t.id === taskId);
             if (task) {
-                await api.put(`/tasks/${taskId}`, {
-                    ...task,
-                    status,
+                await api.patch(`/tasks/${taskId}`, { status }, {
+                    headers: {
+                        'Content-Type': 'application/merge-patch+json',
+                        'If-Match': `"${task.version}"`,
+                    },
                 });
                 toast.success('Task status updated!');
                 await fetchTasks();
             }
-        } catch (err) {
+        } catch (err: any) {
+            if (err.response?.status === 412) {
+                toast.error('This task was changed by someone else');
+                await fetchTasks();
+                return;
+            }
             toast.error('Failed to update task status');
             console.error('Error updating task status:', err);
         }
@@ -12,6 +12,18 @@ interface TaskCardProps {
     onStatusChange: (taskId: string, status: TaskStatus) => void;
 }
 
+// formatDueDate shows an all-day due date as its day and a timed one in the
+// browser's timezone
+const formatDueDate = (task: Task): string => {
+    if (!task.due_date) {
+        return 'No due date';
+    }
+    if (task.due_all_day) {
+        return format(new Date(`${task.due_date.split('T')[0]}T00:00:00`), 'MMM dd, yyyy');
+    }
+    return format(new Date(task.due_date), 'MMM dd, yyyy HH:mm');
+};
+
 const TaskCard: React.FC<TaskCardProps> = ({ task, onEdit, onDelete, onStatusChange }) => {
     const priorityColors = {
         low: 'bg-blue-500/20 text-blue-300 border-blue-500/50',
@@ -96,7 +108,7 @@ const TaskCard: React.FC<TaskCardProps> = ({ task, onEdit, onDelete, onStatusCha
                     <svg className="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
                         <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M8 7V3m8 4V3m-9 8h10M5 21h14a2 2 0 002-2V7a2 2 0 00-2-2H5a2 2 0 00-2 2v12a2 2 0 002 2z" />
                     </svg>
-                    <span className="font-medium">{task.due_date ? format(new Date(task.due_date), 'MMM dd, yyyy') : 'No due date'}</span>
+                    <span className="font-medium">{formatDueDate(task)}</span>
                 </div>
                 <div className="text-xs text-gray-500">
                     Created {format(new Date(task.created_at), 'MMM dd')}
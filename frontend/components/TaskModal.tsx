@@ -9,6 +9,7 @@ import { Task, CreateTaskRequest, UpdateTaskRequest, TaskStatus, TaskPriority, P
 import { useToast } from '@/contexts/ToastContext';
 import { useProject } from '@/contexts/ProjectContext';
 import { projectApi } from '@/lib/projectApi';
+import { format } from 'date-fns';
 
 interface TaskModalProps {
     isOpen: boolean;
@@ -24,6 +25,7 @@ const TaskModal: React.FC<TaskModalProps> = ({ isOpen, onClose, onSave, task, mo
     const [status, setStatus] = useState<TaskStatus>('todo');
     const [priority, setPriority] = useState<TaskPriority>('medium');
     const [dueDate, setDueDate] = useState('');
+    const [dueTime, setDueTime] = useState('');
     const [assignedTo, setAssignedTo] = useState<string>('');
     const [members, setMembers] = useState<ProjectMember[]>([]);
     const [isLoading, setIsLoading] = useState(false);
@@ -51,7 +53,18 @@ const TaskModal: React.FC<TaskModalProps> = ({ isOpen, onClose, onSave, task, mo
             setDescription(task.description);
             setStatus(task.status);
             setPriority(task.priority);
-            setDueDate(task.due_date ? task.due_date.split('T')[0] : '');
+            if (!task.due_date) {
+                setDueDate('');
+                setDueTime('');
+            } else if (task.due_all_day) {
+                setDueDate(task.due_date.split('T')[0]);
+                setDueTime('');
+            } else {
+                // Timed due dates are shown in the browser's timezone
+                const due = new Date(task.due_date);
+                setDueDate(format(due, 'yyyy-MM-dd'));
+                setDueTime(format(due, 'HH:mm'));
+            }
             setAssignedTo(task.assigned_to || '');
         } else {
             // Reset form for create mode
@@ -60,6 +73,7 @@ const TaskModal: React.FC<TaskModalProps> = ({ isOpen, onClose, onSave, task, mo
             setStatus('todo');
             setPriority('medium');
             setDueDate('');
+            setDueTime('');
             setAssignedTo('');
         }
     }, [task, mode, isOpen]);
@@ -87,6 +101,9 @@ const TaskModal: React.FC<TaskModalProps> = ({ isOpen, onClose, onSave, task, mo
 
         setIsLoading(true);
 
+        // Without a time the task is due all day
+        const due = dueDate ? (dueTime ? new Date(`${dueDate}T${dueTime}`).toISOString() : dueDate) : null;
+
         try {
             if (mode === 'create') {
                 const taskData: CreateTaskRequest = {
@@ -95,7 +112,7 @@ const TaskModal: React.FC<TaskModalProps> = ({ isOpen, onClose, onSave, task, mo
                     description: description.trim(),
                     status,
                     priority,
-                    due_date: dueDate || null,
+                    due_date: due,
                     assigned_to: assignedTo || null,
                 };
                 console.log('[TaskModal] Creating task with data:', taskData);
@@ -106,7 +123,7 @@ const TaskModal: React.FC<TaskModalProps> = ({ isOpen, onClose, onSave, task, mo
                     description: description.trim(),
                     status,
                     priority,
-                    due_date: dueDate || null,
+                    due_date: due,
                     assigned_to: assignedTo || null,
                 };
                 console.log('[TaskModal] Updating task with data:', taskData);
@@ -227,6 +244,14 @@ const TaskModal: React.FC<TaskModalProps> = ({ isOpen, onClose, onSave, task, mo
                             </svg>
                         </div>
                     </div>
+                    <input
+                        type="time"
+                        className="mt-2 w-full px-4 py-3 glass-dark rounded-lg text-white placeholder-gray-400 focus:outline-none focus:ring-2 focus:ring-primary-500 transition-all duration-300 [color-scheme:dark] disabled:opacity-50"
+                        value={dueTime}
+                        onChange={(e) => setDueTime(e.target.value)}
+                        disabled={!dueDate}
+                        title="Leave empty for a task due all day"
+                    />
                 </div>
 
                 <div className="flex gap-3 pt-4">
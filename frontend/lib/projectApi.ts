@@ -19,9 +19,9 @@ export const projectApi = {
     // Create project
     createProject: (data: CreateProjectRequest) => api.post<Project>('/projects', data),
 
-    // Update project
-    updateProject: (id: string, data: UpdateProjectRequest) =>
-        api.put<Project>(`/projects/${id}`, data),
+    // Update project; version is the project's version when it was loaded
+    updateProject: (id: string, data: UpdateProjectRequest, version: number) =>
+        api.put<Project>(`/projects/${id}`, data, { headers: { 'If-Match': `"${version}"` } }),
 
     // Delete project
     deleteProject: (id: string) => api.delete(`/projects/${id}`),
@@ -6,6 +6,8 @@ export interface User {
     email: string;
     name: string;
     system_role: SystemRole;
+    // IANA timezone that due dates and reminders use, e.g. "Asia/Bangkok"
+    timezone: string;
     created_at: string;
 }
 
@@ -18,6 +20,7 @@ export interface Project {
     description: string;
     created_at: string;
     updated_at: string;
+    version: number;
 }
 
 export interface ProjectMember {
@@ -43,11 +46,14 @@ export interface Task {
     status: TaskStatus;
     priority: TaskPriority;
     due_date: string | null;
+    // All-day due dates are midnight UTC of their day; others are a moment
+    due_all_day: boolean;
     assigned_to?: string | null;
     assignee_name?: string | null;
     assignee_email?: string | null;
     created_at: string;
     updated_at: string | null;
+    version: number;
 }
 
 // Auth types
@@ -79,6 +85,7 @@ export interface CreateTaskRequest {
     description: string;
     status: TaskStatus;
     priority: TaskPriority;
+    // YYYY-MM-DD for a date due all day, or an RFC 3339 moment
     due_date: string | null;
     assigned_to?: string | null;
 }
@@ -88,6 +95,7 @@ export interface UpdateTaskRequest {
     description: string;
     status: TaskStatus;
     priority: TaskPriority;
+    // YYYY-MM-DD for a date due all day, or an RFC 3339 moment
     due_date: string | null;
     assigned_to?: string | null;
 }